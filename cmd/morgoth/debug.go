@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/joeabbey/morgoth/internal/eval"
+	"github.com/joeabbey/morgoth/internal/lexer"
+	"github.com/joeabbey/morgoth/internal/parser"
+)
+
+// errDebugQuit stops a debug session early at the user's request.
+var errDebugQuit = errors.New("debugger quit")
+
+func runDebug(filename string) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if !debugSource(string(source), os.Stdin, os.Stdout, os.Stderr) {
+		os.Exit(1)
+	}
+}
+
+// debugSource runs source under the stepping debugger, reading commands from
+// in. It reports whether the program finished (or was quit) without dooming.
+func debugSource(source string, in io.Reader, out, errOut io.Writer) bool {
+	p := parser.New(lexer.New(source))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintf(errOut, "parse error: %s\n", e)
+		}
+		return false
+	}
+
+	ev := eval.New()
+	ev.SetOutput(out)
+	ev.SetErrOutput(errOut)
+	ev.SetDebugger(&stepper{
+		lines: strings.Split(source, "\n"),
+		in:    bufio.NewScanner(in),
+		out:   out,
+	})
+	if _, err := ev.Eval(program); err != nil {
+		if errors.Is(err, errDebugQuit) {
+			return true
+		}
+		reportReplError(err, errOut)
+		return false
+	}
+	return true
+}
+
+// stepper is an interactive Debugger. Before each statement it prints the
+// statement's source line and reads commands until told to move on:
+//
+//	step, s or an empty line   run this statement and stop at the next one
+//	continue, c                run to the end without stopping
+//	print <name>, p <name>     show a variable in the current scope
+//	quit, q                    abandon the program
+//
+// End of input behaves like continue.
+type stepper struct {
+	lines   []string
+	in      *bufio.Scanner
+	out     io.Writer
+	running bool // set by continue; stop pausing
+}
+
+func (s *stepper) Before(ev *eval.Evaluator, node parser.Node) error {
+	if s.running {
+		return nil
+	}
+	tok, ok := parser.StmtToken(node)
+	if !ok {
+		return nil
+	}
+	text := ""
+	if tok.Line >= 1 && tok.Line <= len(s.lines) {
+		text = strings.TrimSpace(s.lines[tok.Line-1])
+	}
+	fmt.Fprintf(s.out, "%d: %s\n", tok.Line, text)
+
+	for {
+		fmt.Fprint(s.out, "(debug) ")
+		if !s.in.Scan() {
+			fmt.Fprintln(s.out)
+			s.running = true
+			return nil
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(s.in.Text()), " ")
+		switch cmd {
+		case "", "step", "s":
+			return nil
+		case "continue", "c":
+			s.running = true
+			return nil
+		case "print", "p":
+			name := strings.TrimSpace(arg)
+			if val, ok := ev.Lookup(name); ok {
+				fmt.Fprintf(s.out, "%s = %s\n", name, val.String())
+			} else {
+				fmt.Fprintf(s.out, "%s is not defined here\n", name)
+			}
+		case "quit", "q":
+			return errDebugQuit
+		default:
+			fmt.Fprintf(s.out, "unknown command %q (step, continue, print <name>, quit)\n", cmd)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const debugProgram = `let x = 1
+fn double(n) {
+  let d = n * 2
+  d
+}
+let y = double(x)
+speak y
+speak "done"`
+
+func TestDebugStepPrintContinue(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		"",         // let x
+		"s",        // fn double
+		"p x",      // still at let y
+		"step",     // let y -> enters double
+		"print n",  // inside double
+		"print zz", // undefined
+		"bogus",
+		"step", // let d; the final expression d is not a statement
+		"print y",
+		"c",
+	}, "\n"))
+	var out, errOut bytes.Buffer
+	if !debugSource(debugProgram, in, &out, &errOut) {
+		t.Fatalf("debug session failed: %s", errOut.String())
+	}
+	got := out.String()
+	for _, frag := range []string{
+		"1: let x = 1\n",
+		"2: fn double(n) {\n",
+		"x = 1\n",
+		"6: let y = double(x)\n",
+		"3: let d = n * 2\n",
+		"n = 1\n",
+		"zz is not defined here\n",
+		`unknown command "bogus"`,
+		"7: speak y\n",
+		"y = 2\n",
+		"2\ndone\n",
+	} {
+		if !strings.Contains(got, frag) {
+			t.Errorf("output missing %q:\n%s", frag, got)
+		}
+	}
+	if strings.Contains(got, "8: speak") {
+		t.Errorf("continue should stop pausing:\n%s", got)
+	}
+}
+
+func TestDebugQuit(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if !debugSource(debugProgram, strings.NewReader("s\nq\n"), &out, &errOut) {
+		t.Fatalf("quit should end the session cleanly: %s", errOut.String())
+	}
+	if strings.Contains(out.String(), "done") {
+		t.Errorf("program kept running after quit:\n%s", out.String())
+	}
+}
+
+func TestDebugReportsDoom(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if debugSource(`doom("bad")`, strings.NewReader(""), &out, &errOut) {
+		t.Fatal("expected failure for a dooming program")
+	}
+	if !strings.Contains(errOut.String(), "doom: bad") {
+		t.Errorf("stderr: got %q", errOut.String())
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -13,7 +14,7 @@ import (
 
 func main() {
 	if len(os.Args) < 2 {
-		fmt.Fprintf(os.Stderr, "usage: morgoth <command> [args]\ncommands: run <file.mor>, repl\n")
+		fmt.Fprintf(os.Stderr, "usage: morgoth <command> [args]\ncommands: run <file.mor>, check <file.mor>, debug <file.mor>, symbols <file.mor>, repl\n")
 		os.Exit(1)
 	}
 
@@ -24,10 +25,28 @@ func main() {
 			os.Exit(1)
 		}
 		runFile(os.Args[2])
+	case "check":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "usage: morgoth check <file.mor>\n")
+			os.Exit(1)
+		}
+		checkFile(os.Args[2])
+	case "debug":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "usage: morgoth debug <file.mor>\n")
+			os.Exit(1)
+		}
+		runDebug(os.Args[2])
+	case "symbols":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "usage: morgoth symbols <file.mor>\n")
+			os.Exit(1)
+		}
+		printSymbols(os.Args[2])
 	case "repl":
 		runRepl()
 	default:
-		fmt.Fprintf(os.Stderr, "unknown command: %s\nusage: morgoth <command> [args]\ncommands: run <file.mor>, repl\n", os.Args[1])
+		fmt.Fprintf(os.Stderr, "unknown command: %s\nusage: morgoth <command> [args]\ncommands: run <file.mor>, check <file.mor>, debug <file.mor>, symbols <file.mor>, repl\n", os.Args[1])
 		os.Exit(1)
 	}
 }
@@ -62,15 +81,79 @@ func runFile(filename string) {
 	}
 }
 
+func checkFile(filename string) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if !check(string(source), os.Stderr) {
+		os.Exit(1)
+	}
+}
+
+// check parses src without running it and reports warnings and errors to
+// errOut. Only errors fail the check; warnings alone still return true.
+func check(src string, errOut io.Writer) bool {
+	p := parser.New(lexer.New(src))
+	p.Parse()
+	for _, w := range p.Warnings() {
+		fmt.Fprintf(errOut, "warning: %s\n", w)
+	}
+	errs := p.Errors()
+	for _, e := range errs {
+		fmt.Fprintf(errOut, "parse error: %s\n", e)
+	}
+	return len(errs) == 0
+}
+
+func printSymbols(filename string) {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "parse error: %s\n", e)
+		}
+		os.Exit(1)
+	}
+
+	for _, sym := range parser.Outline(program) {
+		if sym.Kind == parser.SymbolConst {
+			fmt.Printf("%d:%d\t%s\t%s\n", sym.Line, sym.Col, sym.Kind, sym.Name)
+			continue
+		}
+		params := make([]string, len(sym.Params))
+		for i, prm := range sym.Params {
+			params[i] = prm.Name
+		}
+		fmt.Printf("%d:%d\t%s\t%s(%s)\n", sym.Line, sym.Col, sym.Kind, sym.Name, strings.Join(params, ", "))
+	}
+}
+
 func runRepl() {
-	scanner := bufio.NewScanner(os.Stdin)
+	repl(os.Stdin, os.Stdout, os.Stderr)
+}
+
+// repl reads lines from in and evaluates them in one persistent evaluator,
+// writing results and speak output to out and diagnostics to errOut. Errors
+// are reported and the session continues.
+func repl(in io.Reader, out, errOut io.Writer) {
+	scanner := bufio.NewScanner(in)
 	ev := eval.New()
+	ev.SetOutput(out)
+	ev.SetErrOutput(errOut)
 
-	fmt.Println("Morgoth REPL (type 'exit' or Ctrl+D to quit)")
+	fmt.Fprintln(out, "Morgoth REPL (type 'exit' or Ctrl+D to quit)")
 	for {
-		fmt.Print("morgoth> ")
+		fmt.Fprint(out, "morgoth> ")
 		if !scanner.Scan() {
-			fmt.Println()
+			fmt.Fprintln(out)
 			break
 		}
 		line := strings.TrimSpace(scanner.Text())
@@ -80,6 +163,10 @@ func runRepl() {
 		if line == "exit" || line == "quit" {
 			break
 		}
+		if line == ":type" || strings.HasPrefix(line, ":type ") {
+			replType(ev, strings.TrimSpace(strings.TrimPrefix(line, ":type")), out, errOut)
+			continue
+		}
 
 		l := lexer.New(line)
 		p := parser.New(l)
@@ -87,24 +174,49 @@ func runRepl() {
 
 		if errs := p.Errors(); len(errs) > 0 {
 			for _, e := range errs {
-				fmt.Fprintf(os.Stderr, "parse error: %s\n", e)
+				fmt.Fprintf(errOut, "parse error: %s\n", e)
 			}
 			continue
 		}
 
 		result, err := ev.Eval(program)
 		if err != nil {
-			if doomErr, ok := err.(*eval.DoomError); ok {
-				fmt.Fprintf(os.Stderr, "doom: %s\n", doomErr.Message)
-			} else {
-				fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			}
+			reportReplError(err, errOut)
 			continue
 		}
 
 		// Print non-nil results for expression evaluation feedback
 		if result != nil && result.Kind != eval.ValNil {
-			fmt.Println(result.String())
+			fmt.Fprintln(out, result.String())
+		}
+	}
+}
+
+// replType handles ":type expr": it evaluates expr and prints the kind of the
+// result, as type() would name it, instead of the value.
+func replType(ev *eval.Evaluator, src string, out, errOut io.Writer) {
+	if src == "" {
+		fmt.Fprintln(errOut, "usage: :type <expr>")
+		return
+	}
+	result, err := ev.EvalString(src)
+	if err != nil {
+		if pe, ok := err.(*eval.ParseError); ok {
+			for _, e := range pe.Errors {
+				fmt.Fprintf(errOut, "parse error: %s\n", e)
+			}
+			return
 		}
+		reportReplError(err, errOut)
+		return
+	}
+	fmt.Fprintln(out, result.Kind)
+}
+
+func reportReplError(err error, errOut io.Writer) {
+	if doomErr, ok := err.(*eval.DoomError); ok {
+		fmt.Fprintf(errOut, "doom: %s\n", doomErr.Message)
+	} else {
+		fmt.Fprintf(errOut, "error: %v\n", err)
 	}
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReplTypeCommand(t *testing.T) {
+	in := strings.NewReader(strings.Join([]string{
+		":type 1 + 2",
+		`let m = { "a": 1 }`,
+		":type m",
+		":type [1, 2][",
+		":type nope",
+		":type",
+		`:type "still" + " here"`,
+	}, "\n"))
+	var out, errOut bytes.Buffer
+	repl(in, &out, &errOut)
+
+	got := strings.ReplaceAll(out.String(), "morgoth> ", "")
+	want := "Morgoth REPL (type 'exit' or Ctrl+D to quit)\nint\n{a: 1}\nmap\nstr\n\n"
+	if got != want {
+		t.Errorf("stdout: got %q, want %q", got, want)
+	}
+	diag := errOut.String()
+	for _, frag := range []string{"parse error:", "doom: undefined variable: nope", "usage: :type <expr>"} {
+		if !strings.Contains(diag, frag) {
+			t.Errorf("stderr %q: missing %q", diag, frag)
+		}
+	}
+}
+
+func TestCheckReportsWarningsWithoutFailing(t *testing.T) {
+	var errOut bytes.Buffer
+	if !check("let xs = [1, 2,]\nspeak len(xs)\n", &errOut) {
+		t.Errorf("check failed on warnings alone: %q", errOut.String())
+	}
+	if want := "warning: line 1 col 15: trailing comma before ] on the same line\n"; errOut.String() != want {
+		t.Errorf("got %q, want %q", errOut.String(), want)
+	}
+
+	errOut.Reset()
+	if check("speak f(1,)\nlet = 5\n", &errOut) {
+		t.Error("check passed despite a parse error")
+	}
+	diag := errOut.String()
+	if !strings.Contains(diag, "warning: ") || !strings.Contains(diag, "parse error: ") {
+		t.Errorf("expected both a warning and a parse error, got %q", diag)
+	}
+}
@@ -1,15 +1,12 @@
 package morgoth_test
 
 import (
-	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/joeabbey/morgoth/internal/eval"
-	"github.com/joeabbey/morgoth/internal/lexer"
-	"github.com/joeabbey/morgoth/internal/parser"
 )
 
 func TestGoldenExamples(t *testing.T) {
@@ -35,22 +32,11 @@ func TestGoldenExamples(t *testing.T) {
 				t.Fatalf("failed to read golden file %s: %v", goldenFile, err)
 			}
 
-			l := lexer.New(string(source))
-			p := parser.New(l)
-			program := p.Parse()
-			if errs := p.Errors(); len(errs) > 0 {
-				t.Fatalf("parse errors: %v", errs)
-			}
-
-			var buf bytes.Buffer
-			e := eval.New()
-			e.SetOutput(&buf)
-			_, evalErr := e.Eval(program)
-			if evalErr != nil {
-				t.Fatalf("eval error: %v", evalErr)
+			got, _, err := eval.New().RunString(string(source))
+			if err != nil {
+				t.Fatalf("run error: %v", err)
 			}
 
-			got := buf.String()
 			want := string(expected)
 			if got != want {
 				t.Errorf("output mismatch for %s:\ngot:  %q\nwant: %q", exFile, got, want)
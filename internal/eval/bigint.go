@@ -0,0 +1,79 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Arbitrary-precision integers, enabled by decree "arbitrary_precision".
+// Under the decree every int/int arithmetic operation is carried out in
+// math/big and the result is narrowed back to a plain int whenever it fits,
+// so ValBigInt only ever holds values outside the int64 range.
+
+func BigIntVal(b *big.Int) *Value { return &Value{Kind: ValBigInt, Big: b} }
+
+// normalizeBig returns an int when b fits in int64, a bigint otherwise.
+func normalizeBig(b *big.Int) *Value {
+	if b.IsInt64() {
+		return IntVal(b.Int64())
+	}
+	return BigIntVal(b)
+}
+
+func isIntegral(v *Value) bool {
+	return v.Kind == ValInt || v.Kind == ValBigInt
+}
+
+func toBig(v *Value) *big.Int {
+	if v.Kind == ValBigInt {
+		return v.Big
+	}
+	return big.NewInt(v.Int)
+}
+
+// useBig reports whether an int/int operation should go through math/big.
+func (ev *Evaluator) useBig(left, right *Value) bool {
+	if !isIntegral(left) || !isIntegral(right) {
+		return false
+	}
+	return ev.decrees.ArbitraryPrecision || left.Kind == ValBigInt || right.Kind == ValBigInt
+}
+
+// evalBigArith applies op to two integral values with arbitrary precision.
+// / and % truncate toward zero while // and %% floor, matching plain ints.
+func (ev *Evaluator) evalBigArith(left, right *Value, op string) (*Value, error) {
+	l, r := toBig(left), toBig(right)
+	res := new(big.Int)
+	switch op {
+	case "+":
+		res.Add(l, r)
+	case "-":
+		res.Sub(l, r)
+	case "*":
+		res.Mul(l, r)
+	case "/", "//", "%", "%%":
+		if r.Sign() == 0 {
+			return nil, &DoomError{Message: "division by zero"}
+		}
+		switch op {
+		case "/":
+			res.Quo(l, r)
+		case "//":
+			m := new(big.Int)
+			res.QuoRem(l, r, m)
+			if m.Sign() != 0 && m.Sign() != r.Sign() {
+				res.Sub(res, big.NewInt(1))
+			}
+		case "%%":
+			res.Rem(l, r)
+			if res.Sign() != 0 && res.Sign() != r.Sign() {
+				res.Add(res, r)
+			}
+		default:
+			res.Rem(l, r)
+		}
+	default:
+		return nil, &DoomError{Message: fmt.Sprintf("cannot perform %s on big integers", op)}
+	}
+	return normalizeBig(res), nil
+}
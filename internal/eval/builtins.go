@@ -1,7 +1,16 @@
 package eval
 
 import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 	"unicode/utf8"
 )
 
@@ -12,6 +21,8 @@ func (ev *Evaluator) callBuiltin(name string, args []*Value) (*Value, bool, erro
 	switch name {
 	case "len":
 		return ev.builtinLen(args)
+	case "byte_len":
+		return ev.builtinByteLen(args)
 	case "malloc":
 		return PtrVal(0), true, nil
 	case "free":
@@ -22,10 +33,164 @@ func (ev *Evaluator) callBuiltin(name string, args []*Value) (*Value, bool, erro
 		return OkVal(NilVal()), true, nil
 	case "read_file":
 		return ev.builtinReadFile(args)
+	case "read_lines":
+		return ev.builtinReadLines(args)
+	case "iter_lines":
+		return ev.builtinIterLines(args)
+	case "glob":
+		return ev.builtinGlob(args)
+	case "join_path":
+		return ev.builtinJoinPath(args)
+	case "base_name", "dir_name", "ext":
+		return ev.builtinPathPart(name, args)
 	case "parse_toml":
 		return ErrVal(StrVal("not implemented")), true, nil
+	case "is_empty", "non_empty":
+		return ev.builtinEmpty(name, args)
+	case "is_nil":
+		return ev.builtinIsNil(args)
+	case "exists":
+		return ev.builtinExists(args)
 	case "coward":
 		return ev.builtinCoward(args)
+	case "tag":
+		return ev.builtinTag(args)
+	case "type":
+		return ev.builtinType(args)
+	case "dump":
+		return ev.builtinDump(args)
+	case "debug_env":
+		return ev.builtinDebugEnv(args)
+	case "memoize":
+		return ev.builtinMemoize(args)
+	case "once":
+		return ev.builtinOnce(args)
+	case "compose", "pipe_fns":
+		return ev.builtinCompose(name, args)
+	case "partial":
+		return ev.builtinPartial(args)
+	case "new_array":
+		return ev.builtinNewArray(args)
+	case "keys":
+		return ev.builtinKeys(args)
+	case "values":
+		return ev.builtinValues(args)
+	case "entries":
+		return ev.builtinEntries(args)
+	case "zip_map":
+		return ev.builtinZipMap(args)
+	case "from_entries":
+		return ev.builtinFromEntries(args)
+	case "map_values":
+		return ev.builtinMapValues(args)
+	case "map_keys":
+		return ev.builtinMapKeys(args)
+	case "move_to_end":
+		return ev.builtinMoveToEnd(args)
+	case "sort_keys":
+		return ev.builtinSortKeys(args)
+	case "to_json":
+		return ev.builtinToJSON(args)
+	case "to_json_pretty":
+		return ev.builtinToJSONPretty(args)
+	case "escape_html", "escape_shell", "escape_json":
+		return ev.builtinEscape(name, args)
+	case "parse_csv":
+		return ev.builtinParseCSV(args)
+	case "to_csv":
+		return ev.builtinToCSV(args)
+	case "get_path":
+		return ev.builtinGetPath(args)
+	case "set_path":
+		return ev.builtinSetPath(args)
+	case "take":
+		return ev.builtinTake(args)
+	case "drop":
+		return ev.builtinDrop(args)
+	case "chunk":
+		return ev.builtinChunk(args)
+	case "windows":
+		return ev.builtinWindows(args)
+	case "pairwise":
+		return ev.builtinPairwise(args)
+	case "group_by":
+		return ev.builtinGroupBy(args)
+	case "flat_map":
+		return ev.builtinFlatMap(args)
+	case "flatten":
+		return ev.builtinFlatten(args)
+	case "unique":
+		return ev.builtinUnique(args)
+	case "partition":
+		return ev.builtinPartition(args)
+	case "sort":
+		return ev.builtinSort(args)
+	case "sort_by":
+		return ev.builtinSortBy(args)
+	case "min_by", "max_by":
+		return ev.builtinExtremeBy(name, args)
+	case "popcount", "leading_zeros", "trailing_zeros", "rotate_left":
+		return ev.builtinBits(name, args)
+	case "mutex":
+		return ev.builtinMutex(args)
+	case "lock", "unlock":
+		return ev.builtinLockOp(name, args)
+	case "channel":
+		return ev.builtinChannel(args)
+	case "send":
+		return ev.builtinSend(args)
+	case "recv":
+		return ev.builtinRecv(args)
+	case "iter":
+		return ev.builtinIter(args)
+	case "next":
+		return ev.builtinNext(args)
+	case "range":
+		return ev.builtinRange(args)
+	case "force":
+		return ev.builtinForce(args)
+	case "await":
+		return ev.builtinAwait(args)
+	case "spawn_pool":
+		return ev.builtinSpawnPool(args)
+	case "submit":
+		return ev.builtinSubmit(args)
+	case "await_pool":
+		return ev.builtinAwaitPool(args)
+	case "catch":
+		return ev.builtinCatch(args)
+	case "unwrap", "expect":
+		return ev.builtinUnwrap(name, args)
+	case "sleep":
+		return ev.builtinSleep(args)
+	case "retry":
+		return ev.builtinRetry(args)
+	case "times":
+		return ev.builtinTimes(args)
+	case "uuid":
+		return ev.builtinUUID(args)
+	case "ulid":
+		return ev.builtinULID(args)
+	case "floor_div", "floor_mod":
+		return ev.builtinFloorOp(name, args)
+	case "gcd", "lcm":
+		return ev.builtinGcdLcm(name, args)
+	case "is_even", "is_odd":
+		return ev.builtinParity(name, args)
+	case "hex", "oct", "bin":
+		return ev.builtinRadix(name, args)
+	case "commas":
+		return ev.builtinCommas(args)
+	case "rat":
+		return ev.builtinRat(args)
+	case "decimal":
+		return ev.builtinDecimal(args)
+	case "complex":
+		return ev.builtinComplex(args)
+	case "real", "imag":
+		return ev.builtinComplexPart(name, args)
+	case "abs":
+		return ev.builtinAbs(args)
 	default:
 		return nil, false, nil
 	}
@@ -47,6 +212,76 @@ func (ev *Evaluator) builtinLen(args []*Value) (*Value, bool, error) {
 	}
 }
 
+// builtinDebugEnv returns the names of the variables and functions visible
+// at the call site, sorted. Builtins live outside the environment and are
+// not listed.
+func (ev *Evaluator) builtinDebugEnv(args []*Value) (*Value, bool, error) {
+	if len(args) != 0 {
+		return nil, true, &DoomError{Message: "debug_env() takes no arguments"}
+	}
+	names := ev.env.Names()
+	vals := make([]*Value, len(names))
+	for i, name := range names {
+		vals[i] = StrVal(name)
+	}
+	return ArrayVal(vals), true, nil
+}
+
+// builtinByteLen returns the UTF-8 encoded size of a string, where len()
+// counts runes.
+func (ev *Evaluator) builtinByteLen(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValStr {
+		return nil, true, &DoomError{Message: "byte_len() takes exactly 1 string argument"}
+	}
+	return IntVal(int64(len(args[0].Str))), true, nil
+}
+
+// builtinEmpty implements is_empty and non_empty. Arrays, maps and strings
+// are empty when they have no elements, and nil always counts as empty.
+func (ev *Evaluator) builtinEmpty(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: name + "() takes exactly 1 argument"}
+	}
+	var empty bool
+	switch args[0].Kind {
+	case ValArray:
+		empty = len(args[0].Array) == 0
+	case ValStr:
+		empty = args[0].Str == ""
+	case ValMap:
+		empty = args[0].Map.Len() == 0
+	case ValNil:
+		empty = true
+	default:
+		return nil, true, &DoomError{Message: name + "() argument must be array, string, map, or nil"}
+	}
+	if name == "non_empty" {
+		empty = !empty
+	}
+	return BoolVal(empty), true, nil
+}
+
+func (ev *Evaluator) builtinIsNil(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: "is_nil() takes exactly 1 argument"}
+	}
+	return BoolVal(args[0].Kind == ValNil), true, nil
+}
+
+// builtinExists reports whether a map has the key. Unlike m[k], which yields
+// nil both for a missing key and for a key bound to nil, it tells them apart.
+func (ev *Evaluator) builtinExists(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValMap {
+		return nil, true, &DoomError{Message: "exists() takes a map and a key"}
+	}
+	key, err := MapKey(args[1])
+	if err != nil {
+		return nil, true, &DoomError{Message: err.Error()}
+	}
+	_, ok := args[0].Map.Get(key)
+	return BoolVal(ok), true, nil
+}
+
 func (ev *Evaluator) builtinCoward(args []*Value) (*Value, bool, error) {
 	if len(args) != 1 {
 		return nil, true, &DoomError{Message: "coward() takes exactly 1 argument"}
@@ -56,6 +291,778 @@ func (ev *Evaluator) builtinCoward(args []*Value) (*Value, bool, error) {
 	return &v, true, nil
 }
 
+// builtinTag returns a copy of map m tagged as a value of the struct type
+// name, which typed patterns such as `p: Point` then match. Struct type names
+// start with an uppercase letter so they never shadow the built-in ones.
+func (ev *Evaluator) builtinTag(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValMap || args[1].Kind != ValStr {
+		return nil, true, &DoomError{Message: "tag() takes a map and a type name"}
+	}
+	name := args[1].Str
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsUpper(r) {
+		return nil, true, &DoomError{Message: fmt.Sprintf("tag(): struct type name %q must start with an uppercase letter", name)}
+	}
+	m := NewOrderedMap()
+	for _, k := range args[0].Map.Keys() {
+		v, _ := args[0].Map.Get(k)
+		m.Set(k, v)
+	}
+	m.SetTag(name)
+	return MapVal(m), true, nil
+}
+
+// builtinType returns the name of a value's kind, e.g. "int" or "map".
+func (ev *Evaluator) builtinType(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: "type() takes exactly 1 argument"}
+	}
+	return StrVal(args[0].Kind.String()), true, nil
+}
+
+// builtinMemoize wraps a function in a per-wrapper result cache keyed by the
+// string form of its arguments. Only pure functions should be memoized: side
+// effects in the body run once per distinct argument list, not once per call.
+func (ev *Evaluator) builtinMemoize(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValFn {
+		return nil, true, &DoomError{Message: "memoize() takes exactly 1 function argument"}
+	}
+	fn := *args[0].Fn
+	fn.Memo = make(map[string]*Value)
+	return FnVal(&fn), true, nil
+}
+
+// builtinOnce wraps a zero-parameter function so its body runs at most once;
+// every later call returns the first result.
+func (ev *Evaluator) builtinOnce(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValFn {
+		return nil, true, &DoomError{Message: "once() takes exactly 1 function argument"}
+	}
+	if len(args[0].Fn.Params) != 0 {
+		return nil, true, &DoomError{Message: "once() needs a function with no parameters"}
+	}
+	fn := *args[0].Fn
+	fn.Memo = nil
+	fn.Once = &OnceCell{}
+	return FnVal(&fn), true, nil
+}
+
+// builtinCompose chains functions into one: pipe_fns(f, g) calls f first and
+// passes its result to g, while compose(f, g) applies them right to left.
+func (ev *Evaluator) builtinCompose(name string, args []*Value) (*Value, bool, error) {
+	if len(args) == 0 {
+		return nil, true, &DoomError{Message: name + "() needs at least 1 function"}
+	}
+	chain := make([]*FnValue, len(args))
+	for i, arg := range args {
+		if arg.Kind != ValFn {
+			return nil, true, &DoomError{Message: fmt.Sprintf("%s() argument %d is %s, not a function", name, i+1, arg.Kind)}
+		}
+		if name == "compose" {
+			chain[len(args)-1-i] = arg.Fn
+		} else {
+			chain[i] = arg.Fn
+		}
+	}
+	return FnVal(&FnValue{Name: name, Params: chain[0].Params, Chain: chain}), true, nil
+}
+
+// builtinPartial fixes a function's leading arguments: partial(f, a)(b) calls
+// f(a, b). The result takes the parameters f has left.
+func (ev *Evaluator) builtinPartial(args []*Value) (*Value, bool, error) {
+	if len(args) == 0 || args[0].Kind != ValFn {
+		return nil, true, &DoomError{Message: "partial() takes a function and the arguments to fix"}
+	}
+	fn := args[0].Fn
+	params := fn.Params
+	if len(args)-1 < len(params) {
+		params = params[len(args)-1:]
+	} else {
+		params = nil
+	}
+	bound := append([]*Value(nil), args[1:]...)
+	return FnVal(&FnValue{Name: "partial", Params: params, Chain: []*FnValue{fn}, Bound: bound}), true, nil
+}
+
+// maxNewArrayLen bounds new_array()'s length.
+const maxNewArrayLen = 1 << 24
+
+// builtinNewArray preallocates an array of n elements, each an independent
+// clone of the fill value (nil when omitted).
+func (ev *Evaluator) builtinNewArray(args []*Value) (*Value, bool, error) {
+	if len(args) < 1 || len(args) > 2 || args[0].Kind != ValInt {
+		return nil, true, &DoomError{Message: "new_array() takes an int length and an optional fill value"}
+	}
+	n := args[0].Int
+	if n < 0 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("new_array() length must not be negative: %d", n)}
+	}
+	if n > maxNewArrayLen {
+		return nil, true, &DoomError{Message: fmt.Sprintf("new_array() length must be at most %d, got %d", maxNewArrayLen, n)}
+	}
+	fill := NilVal()
+	if len(args) == 2 {
+		fill = args[1]
+	}
+	elems := make([]*Value, n)
+	for i := range elems {
+		elems[i] = fill.Clone()
+	}
+	return ArrayVal(elems), true, nil
+}
+
+func (ev *Evaluator) builtinKeys(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValMap {
+		return nil, true, &DoomError{Message: "keys() takes exactly 1 map argument"}
+	}
+	keys := make([]*Value, 0, args[0].Map.Len())
+	for _, k := range args[0].Map.Keys() {
+		keys = append(keys, StrVal(keyText(k)))
+	}
+	return ArrayVal(keys), true, nil
+}
+
+func (ev *Evaluator) builtinValues(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValMap {
+		return nil, true, &DoomError{Message: "values() takes exactly 1 map argument"}
+	}
+	vals := make([]*Value, 0, args[0].Map.Len())
+	for _, k := range args[0].Map.Keys() {
+		v, _ := args[0].Map.Get(k)
+		vals = append(vals, v)
+	}
+	return ArrayVal(vals), true, nil
+}
+
+// builtinEntries returns a map's [key, value] pairs in insertion order.
+func (ev *Evaluator) builtinEntries(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValMap {
+		return nil, true, &DoomError{Message: "entries() takes exactly 1 map argument"}
+	}
+	pairs := make([]*Value, 0, args[0].Map.Len())
+	for _, k := range args[0].Map.Keys() {
+		v, _ := args[0].Map.Get(k)
+		pairs = append(pairs, ArrayVal([]*Value{StrVal(keyText(k)), v}))
+	}
+	return ArrayVal(pairs), true, nil
+}
+
+func (ev *Evaluator) builtinZipMap(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValArray {
+		return nil, true, &DoomError{Message: "zip_map() takes 2 array arguments"}
+	}
+	keys, vals := args[0].Array, args[1].Array
+	if len(keys) != len(vals) {
+		return nil, true, &DoomError{Message: fmt.Sprintf("zip_map() length mismatch: %d keys, %d values", len(keys), len(vals))}
+	}
+	m := NewOrderedMap()
+	for i, k := range keys {
+		key, err := MapKey(k)
+		if err != nil {
+			return nil, true, &DoomError{Message: err.Error()}
+		}
+		m.Set(key, vals[i])
+	}
+	return MapVal(m), true, nil
+}
+
+// builtinMapValues returns a new map with each value replaced by fn(value,
+// key). Keys and their order are unchanged.
+func (ev *Evaluator) builtinMapValues(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValMap || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "map_values() takes a map and a function"}
+	}
+	m := NewOrderedMap()
+	for _, k := range args[0].Map.Keys() {
+		v, _ := args[0].Map.Get(k)
+		nv, err := ev.callFunction(args[1].Fn, []*Value{v, StrVal(keyText(k))})
+		if err != nil {
+			return nil, true, err
+		}
+		m.Set(k, nv)
+	}
+	return MapVal(m), true, nil
+}
+
+// builtinMapKeys returns a new map with each key replaced by fn(key, value),
+// keeping the original order. Two keys mapping to the same new key doom
+// rather than silently dropping a value.
+func (ev *Evaluator) builtinMapKeys(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValMap || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "map_keys() takes a map and a function"}
+	}
+	m := NewOrderedMap()
+	for _, k := range args[0].Map.Keys() {
+		v, _ := args[0].Map.Get(k)
+		nk, err := ev.callFunction(args[1].Fn, []*Value{StrVal(keyText(k)), v})
+		if err != nil {
+			return nil, true, err
+		}
+		key, err := MapKey(nk)
+		if err != nil {
+			return nil, true, &DoomError{Message: err.Error()}
+		}
+		if _, dup := m.Get(key); dup {
+			return nil, true, &DoomError{Message: fmt.Sprintf("map_keys() maps more than one key to %s", key)}
+		}
+		m.Set(key, v)
+	}
+	return MapVal(m), true, nil
+}
+
+// builtinFromEntries builds a map from an array of [key, value] pairs,
+// the inverse of entries().
+func (ev *Evaluator) builtinFromEntries(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValArray {
+		return nil, true, &DoomError{Message: "from_entries() takes exactly 1 array argument"}
+	}
+	m := NewOrderedMap()
+	for i, pair := range args[0].Array {
+		if pair.Kind != ValArray || len(pair.Array) != 2 {
+			return nil, true, &DoomError{Message: fmt.Sprintf("from_entries() element %d is not a [key, value] pair", i)}
+		}
+		key, err := MapKey(pair.Array[0])
+		if err != nil {
+			return nil, true, &DoomError{Message: err.Error()}
+		}
+		m.Set(key, pair.Array[1])
+	}
+	return MapVal(m), true, nil
+}
+
+// builtinMoveToEnd moves key to the end of m's insertion order in place,
+// which is enough to build LRU-style structures. It returns whether the key
+// was present; a missing key leaves the map untouched.
+func (ev *Evaluator) builtinMoveToEnd(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValMap {
+		return nil, true, &DoomError{Message: "move_to_end() takes a map and a key"}
+	}
+	key, err := MapKey(args[1])
+	if err != nil {
+		return nil, true, &DoomError{Message: err.Error()}
+	}
+	return BoolVal(args[0].Map.MoveToEnd(key)), true, nil
+}
+
+// builtinSortKeys returns a copy of m with its keys in sorted order. Keys
+// written from numbers sort numerically and come before all other keys, which
+// sort as strings, so {10: .., 9: .., "b": .., "a": ..} yields 9, 10, a, b.
+func (ev *Evaluator) builtinSortKeys(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValMap {
+		return nil, true, &DoomError{Message: "sort_keys() takes exactly 1 map argument"}
+	}
+	keys := append([]string(nil), args[0].Map.Keys()...)
+	sort.SliceStable(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+	m := NewOrderedMap()
+	for _, k := range keys {
+		v, _ := args[0].Map.Get(k)
+		m.Set(k, v)
+	}
+	return MapVal(m), true, nil
+}
+
+// keyLess orders canonical map keys: numeric keys first by value, then the
+// rest lexically.
+func keyLess(a, b string) bool {
+	af, aNum := numericKey(a)
+	bf, bNum := numericKey(b)
+	switch {
+	case aNum && bNum:
+		return af < bf
+	case aNum:
+		return true
+	case bNum:
+		return false
+	default:
+		return keyText(a) < keyText(b)
+	}
+}
+
+// numericKey parses a key written from an int or float.
+func numericKey(k string) (float64, bool) {
+	kind, text := splitKey(k)
+	if kind != "int" && kind != "float" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	return f, err == nil
+}
+
+// clampCount validates the (array, int) arguments shared by take and drop and
+// clamps the count into [0, len(array)].
+func clampCount(name string, args []*Value) ([]*Value, int, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValInt {
+		return nil, 0, &DoomError{Message: name + "() takes an array and an int"}
+	}
+	n := args[1].Int
+	if n < 0 {
+		n = 0
+	}
+	if n > int64(len(args[0].Array)) {
+		n = int64(len(args[0].Array))
+	}
+	return args[0].Array, int(n), nil
+}
+
+func (ev *Evaluator) builtinTake(args []*Value) (*Value, bool, error) {
+	arr, n, err := clampCount("take", args)
+	if err != nil {
+		return nil, true, err
+	}
+	return ArrayVal(append([]*Value(nil), arr[:n]...)), true, nil
+}
+
+func (ev *Evaluator) builtinDrop(args []*Value) (*Value, bool, error) {
+	arr, n, err := clampCount("drop", args)
+	if err != nil {
+		return nil, true, err
+	}
+	return ArrayVal(append([]*Value(nil), arr[n:]...)), true, nil
+}
+
+// builtinChunk splits an array into sub-arrays of the given size; the last
+// chunk holds whatever is left over.
+func (ev *Evaluator) builtinChunk(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValInt {
+		return nil, true, &DoomError{Message: "chunk() takes an array and an int size"}
+	}
+	size := args[1].Int
+	if size <= 0 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("chunk() size must be positive: %d", size)}
+	}
+	arr := args[0].Array
+	// A size past the end yields one chunk; clamping keeps the capacity
+	// computation from overflowing.
+	size = max(min(size, int64(len(arr))), 1)
+	chunks := make([]*Value, 0, (int64(len(arr))+size-1)/size)
+	for start := int64(0); start < int64(len(arr)); start += size {
+		end := min(start+size, int64(len(arr)))
+		chunks = append(chunks, ArrayVal(append([]*Value(nil), arr[start:end]...)))
+	}
+	return ArrayVal(chunks), true, nil
+}
+
+// builtinWindows returns every run of size consecutive elements, overlapping
+// and in order; an array shorter than size has none.
+func (ev *Evaluator) builtinWindows(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValInt {
+		return nil, true, &DoomError{Message: "windows() takes an array and an int size"}
+	}
+	size := args[1].Int
+	if size <= 0 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("windows() size must be positive: %d", size)}
+	}
+	return windowsOf(args[0].Array, size), true, nil
+}
+
+// builtinPairwise returns the consecutive pairs of an array: windows(xs, 2).
+func (ev *Evaluator) builtinPairwise(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValArray {
+		return nil, true, &DoomError{Message: "pairwise() takes exactly 1 array argument"}
+	}
+	return windowsOf(args[0].Array, 2), true, nil
+}
+
+func windowsOf(arr []*Value, size int64) *Value {
+	n := int64(len(arr)) - size + 1
+	if n <= 0 {
+		return ArrayVal([]*Value{})
+	}
+	out := make([]*Value, n)
+	for i := range out {
+		out[i] = ArrayVal(append([]*Value(nil), arr[i:int64(i)+size]...))
+	}
+	return ArrayVal(out)
+}
+
+// builtinGroupBy buckets array elements by the (map-key form of the)
+// callback's result, preserving element order within each bucket.
+func (ev *Evaluator) builtinGroupBy(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "group_by() takes an array and a function"}
+	}
+	groups := NewOrderedMap()
+	for _, elem := range args[0].Array {
+		k, err := ev.callFunction(args[1].Fn, []*Value{elem})
+		if err != nil {
+			return nil, true, err
+		}
+		key, err := MapKey(k)
+		if err != nil {
+			return nil, true, &DoomError{Message: err.Error()}
+		}
+		bucket, ok := groups.Get(key)
+		if !ok {
+			bucket = ArrayVal(nil)
+			groups.Set(key, bucket)
+		}
+		bucket.Array = append(bucket.Array, elem)
+	}
+	return MapVal(groups), true, nil
+}
+
+// builtinPartition splits an array by the predicate's truthiness into
+// [matching, non_matching] in a single pass, keeping element order.
+func (ev *Evaluator) builtinPartition(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "partition() takes an array and a function"}
+	}
+	yes, no := []*Value{}, []*Value{}
+	for _, elem := range args[0].Array {
+		keep, err := ev.callFunction(args[1].Fn, []*Value{elem})
+		if err != nil {
+			return nil, true, err
+		}
+		if ev.truthy(keep) {
+			yes = append(yes, elem)
+		} else {
+			no = append(no, elem)
+		}
+	}
+	return ArrayVal([]*Value{ArrayVal(yes), ArrayVal(no)}), true, nil
+}
+
+// builtinCatch calls fn with no arguments and yields ok(result), or, if the
+// call dooms, err({ "message": msg, "payload": payload }) with payload nil
+// for a plain doom(msg). Other failures propagate unchanged.
+func (ev *Evaluator) builtinCatch(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValFn {
+		return nil, true, &DoomError{Message: "catch() takes exactly 1 function"}
+	}
+	val, err := ev.callFunction(args[0].Fn, nil)
+	if doom, ok := err.(*DoomError); ok {
+		payload := doom.Payload
+		if payload == nil {
+			payload = NilVal()
+		}
+		info := NewOrderedMap()
+		info.Set("message", StrVal(doom.Message))
+		info.Set("payload", payload)
+		return ErrVal(MapVal(info)), true, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	return OkVal(val), true, nil
+}
+
+// builtinUnwrap implements unwrap(r) and expect(r, msg): both yield the
+// inner value of an ok and doom on an err, carrying the error value as the
+// doom payload. expect puts msg in front of the error in the message.
+func (ev *Evaluator) builtinUnwrap(name string, args []*Value) (*Value, bool, error) {
+	want := 1
+	if name == "expect" {
+		want = 2
+	}
+	if len(args) != want || !isResult(args[0]) {
+		if name == "expect" {
+			return nil, true, &DoomError{Message: "expect() takes a result and a message"}
+		}
+		return nil, true, &DoomError{Message: "unwrap() takes exactly 1 result argument"}
+	}
+	res := args[0]
+	if res.Kind == ValOk {
+		return res.Inner, true, nil
+	}
+	msg := "unwrap() on err: " + res.Inner.String()
+	if name == "expect" {
+		msg = args[1].String() + ": " + res.Inner.String()
+	}
+	return nil, true, &DoomError{Message: msg, Payload: res.Inner}
+}
+
+// builtinUnique returns the array with later duplicates removed, comparing
+// elements deeply with ==. Candidates are bucketed by canonicalKey so large
+// arrays avoid pairwise comparison; Equal settles each bucket.
+func (ev *Evaluator) builtinUnique(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValArray {
+		return nil, true, &DoomError{Message: "unique() takes exactly 1 array argument"}
+	}
+	seen := make(map[string][]*Value)
+	out := make([]*Value, 0, len(args[0].Array))
+	for _, elem := range args[0].Array {
+		key := canonicalKey(elem)
+		dup := false
+		for _, prev := range seen[key] {
+			if prev.Equal(elem) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			seen[key] = append(seen[key], elem)
+			out = append(out, elem)
+		}
+	}
+	return ArrayVal(out), true, nil
+}
+
+// canonicalKey renders v so that values equal under == share a key: kinds
+// are spelled out, so 1 and "1" differ, and map keys are sorted, so insertion
+// order does not matter.
+func canonicalKey(v *Value) string {
+	switch v.Kind {
+	case ValArray:
+		parts := make([]string, len(v.Array))
+		for i, elem := range v.Array {
+			parts[i] = canonicalKey(elem)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	case ValMap:
+		keys := append([]string(nil), v.Map.Keys()...)
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			val, _ := v.Map.Get(k)
+			parts[i] = strconv.Quote(k) + ":" + canonicalKey(val)
+		}
+		return "{" + strings.Join(parts, ",") + "}"
+	case ValOk, ValErr:
+		return v.Kind.String() + "(" + canonicalKey(v.Inner) + ")"
+	case ValFloat:
+		if v.Float == 0 {
+			return "float(0)" // -0.0 == 0.0
+		}
+		return annotate(v)
+	default:
+		return annotate(v)
+	}
+}
+
+// builtinSort returns a sorted copy of the array, ordering elements with <.
+// The sort is stable, and elements that cannot be compared doom.
+func (ev *Evaluator) builtinSort(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValArray {
+		return nil, true, &DoomError{Message: "sort() takes exactly 1 array argument"}
+	}
+	out := append([]*Value(nil), args[0].Array...)
+	var cmpErr error
+	sort.SliceStable(out, func(i, j int) bool {
+		if cmpErr != nil {
+			return false
+		}
+		less, err := ev.evalCompare(out[i], out[j], "<")
+		if err != nil {
+			cmpErr = err
+			return false
+		}
+		return less.Bool
+	})
+	if cmpErr != nil {
+		return nil, true, cmpErr
+	}
+	return ArrayVal(out), true, nil
+}
+
+// builtinSortBy returns a copy of the array stably sorted by the key each
+// callback derives from an element. With an array of callbacks, later keys
+// break ties in earlier ones. Each key is computed once per element, and
+// keys that cannot be compared with < doom.
+func (ev *Evaluator) builtinSortBy(args []*Value) (*Value, bool, error) {
+	usage := &DoomError{Message: "sort_by() takes an array and a function or an array of functions"}
+	if len(args) != 2 || args[0].Kind != ValArray {
+		return nil, true, usage
+	}
+	var fns []*FnValue
+	switch args[1].Kind {
+	case ValFn:
+		fns = []*FnValue{args[1].Fn}
+	case ValArray:
+		for _, f := range args[1].Array {
+			if f.Kind != ValFn {
+				return nil, true, usage
+			}
+			fns = append(fns, f.Fn)
+		}
+	}
+	if len(fns) == 0 {
+		return nil, true, usage
+	}
+
+	type keyed struct {
+		elem *Value
+		keys []*Value
+	}
+	items := make([]keyed, len(args[0].Array))
+	for i, elem := range args[0].Array {
+		keys := make([]*Value, len(fns))
+		for k, fn := range fns {
+			key, err := ev.callFunction(fn, []*Value{elem})
+			if err != nil {
+				return nil, true, err
+			}
+			keys[k] = key
+		}
+		items[i] = keyed{elem: elem, keys: keys}
+	}
+
+	var cmpErr error
+	before := func(a, b *Value) bool {
+		if cmpErr != nil {
+			return false
+		}
+		less, err := ev.evalCompare(a, b, "<")
+		if err != nil {
+			cmpErr = err
+			return false
+		}
+		return less.Bool
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		for k := range fns {
+			a, b := items[i].keys[k], items[j].keys[k]
+			if before(a, b) {
+				return true
+			}
+			if before(b, a) {
+				return false
+			}
+		}
+		return false
+	})
+	if cmpErr != nil {
+		return nil, true, cmpErr
+	}
+	out := make([]*Value, len(items))
+	for i, it := range items {
+		out[i] = it.elem
+	}
+	return ArrayVal(out), true, nil
+}
+
+// builtinExtremeBy implements min_by and max_by: it returns the element whose
+// callback key is smallest (or largest). Keys must be ints, floats or strings,
+// and on ties the earliest element wins.
+func (ev *Evaluator) builtinExtremeBy(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: name + "() takes an array and a function"}
+	}
+	if len(args[0].Array) == 0 {
+		return nil, true, &DoomError{Message: name + "() of an empty array"}
+	}
+	op := "<"
+	if name == "max_by" {
+		op = ">"
+	}
+	var best, bestKey *Value
+	for _, elem := range args[0].Array {
+		key, err := ev.callFunction(args[1].Fn, []*Value{elem})
+		if err != nil {
+			return nil, true, err
+		}
+		switch key.Kind {
+		case ValInt, ValFloat, ValStr:
+		default:
+			return nil, true, &DoomError{Message: fmt.Sprintf("%s() key must be int, float, or str, got %s", name, key.Kind)}
+		}
+		if best == nil {
+			best, bestKey = elem, key
+			continue
+		}
+		better, err := ev.evalCompare(key, bestKey, op)
+		if err != nil {
+			return nil, true, err
+		}
+		if better.Bool {
+			best, bestKey = elem, key
+		}
+	}
+	return best, true, nil
+}
+
+// builtinFlatMap maps each element through the callback and flattens array
+// results one level; non-array results are kept as single elements.
+func (ev *Evaluator) builtinFlatMap(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValArray || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "flat_map() takes an array and a function"}
+	}
+	var out []*Value
+	for _, elem := range args[0].Array {
+		res, err := ev.callFunction(args[1].Fn, []*Value{elem})
+		if err != nil {
+			return nil, true, err
+		}
+		if res.Kind == ValArray {
+			out = append(out, res.Array...)
+		} else {
+			out = append(out, res)
+		}
+	}
+	return ArrayVal(out), true, nil
+}
+
+// builtinFlatten splices nested arrays into one array, descending at most
+// depth levels when a depth is given and without limit otherwise. An array
+// that contains itself dooms instead of recursing forever.
+func (ev *Evaluator) builtinFlatten(args []*Value) (*Value, bool, error) {
+	if len(args) < 1 || len(args) > 2 || args[0].Kind != ValArray {
+		return nil, true, &DoomError{Message: "flatten() takes an array and an optional depth"}
+	}
+	depth := int64(-1)
+	if len(args) == 2 {
+		if args[1].Kind != ValInt || args[1].Int < 0 {
+			return nil, true, &DoomError{Message: "flatten() depth must be a non-negative int"}
+		}
+		depth = args[1].Int
+	}
+	out := []*Value{}
+	if err := flattenInto(&out, args[0].Array, depth, map[**Value]bool{}); err != nil {
+		return nil, true, err
+	}
+	return ArrayVal(out), true, nil
+}
+
+// flattenInto appends elems to out, recursing into arrays while depth is
+// non-zero. onPath holds the backing arrays currently being flattened,
+// identified by their first slot, so a cycle is caught without rejecting an
+// array that merely appears twice.
+func flattenInto(out *[]*Value, elems []*Value, depth int64, onPath map[**Value]bool) error {
+	if len(elems) > 0 {
+		if onPath[&elems[0]] {
+			return &DoomError{Message: "flatten() of an array that contains itself"}
+		}
+		onPath[&elems[0]] = true
+		defer delete(onPath, &elems[0])
+	}
+	for _, elem := range elems {
+		if elem.Kind != ValArray || depth == 0 {
+			*out = append(*out, elem)
+			continue
+		}
+		if err := flattenInto(out, elem.Array, depth-1, onPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builtinBits implements the integer bit builtins over the two's-complement
+// 64-bit representation of their int arguments.
+func (ev *Evaluator) builtinBits(name string, args []*Value) (*Value, bool, error) {
+	arity := 1
+	if name == "rotate_left" {
+		arity = 2
+	}
+	if len(args) != arity {
+		return nil, true, &DoomError{Message: fmt.Sprintf("%s() takes exactly %d int argument(s)", name, arity)}
+	}
+	for _, a := range args {
+		if a.Kind != ValInt {
+			return nil, true, &DoomError{Message: fmt.Sprintf("%s() arguments must be int, got %s", name, a.String())}
+		}
+	}
+	n := uint64(args[0].Int)
+	switch name {
+	case "popcount":
+		return IntVal(int64(bits.OnesCount64(n))), true, nil
+	case "leading_zeros":
+		return IntVal(int64(bits.LeadingZeros64(n))), true, nil
+	case "trailing_zeros":
+		return IntVal(int64(bits.TrailingZeros64(n))), true, nil
+	default: // rotate_left; negative k rotates right
+		return IntVal(int64(bits.RotateLeft64(n, int(args[1].Int)))), true, nil
+	}
+}
+
 func (ev *Evaluator) builtinReadFile(args []*Value) (*Value, bool, error) {
 	if len(args) != 1 || args[0].Kind != ValStr {
 		return ErrVal(StrVal("read_file() takes exactly 1 string argument")), true, nil
@@ -66,3 +1073,179 @@ func (ev *Evaluator) builtinReadFile(args []*Value) (*Value, bool, error) {
 	}
 	return OkVal(StrVal(string(data))), true, nil
 }
+
+// maxMillis is the longest wait, in milliseconds, that a time.Duration can
+// hold; sleep(), retry() and await_all doom above it rather than overflow.
+const maxMillis = math.MaxInt64 / int64(time.Millisecond)
+
+func (ev *Evaluator) builtinSleep(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValInt {
+		return nil, true, &DoomError{Message: "sleep() takes exactly 1 int argument (milliseconds)"}
+	}
+	if args[0].Int < 0 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("sleep() duration cannot be negative: %d", args[0].Int)}
+	}
+	if args[0].Int > maxMillis {
+		return nil, true, &DoomError{Message: fmt.Sprintf("sleep() duration must be at most %d ms, got %d", maxMillis, args[0].Int)}
+	}
+	ev.blocking(func() { ev.sleep(time.Duration(args[0].Int) * time.Millisecond) })
+	return NilVal(), true, nil
+}
+
+// builtinRetry calls fn() up to n times, stopping at the first ok(...) and
+// otherwise returning the last err(...). An optional third argument sleeps
+// that many milliseconds between attempts.
+func (ev *Evaluator) builtinRetry(args []*Value) (*Value, bool, error) {
+	if len(args) < 2 || len(args) > 3 || args[0].Kind != ValInt || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "retry() takes an attempt count, a function and an optional backoff in milliseconds"}
+	}
+	if args[0].Int < 1 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("retry() needs at least 1 attempt, got %d", args[0].Int)}
+	}
+	var backoff time.Duration
+	if len(args) == 3 {
+		if args[2].Kind != ValInt || args[2].Int < 0 {
+			return nil, true, &DoomError{Message: "retry() backoff must be a non-negative int (milliseconds)"}
+		}
+		if args[2].Int > maxMillis {
+			return nil, true, &DoomError{Message: fmt.Sprintf("retry() backoff must be at most %d ms, got %d", maxMillis, args[2].Int)}
+		}
+		backoff = time.Duration(args[2].Int) * time.Millisecond
+	}
+	var last *Value
+	for attempt := int64(0); attempt < args[0].Int; attempt++ {
+		if attempt > 0 && backoff > 0 {
+			ev.blocking(func() { ev.sleep(backoff) })
+		}
+		res, err := ev.callFunction(args[1].Fn, nil)
+		if err != nil {
+			return nil, true, err
+		}
+		switch res.Kind {
+		case ValOk:
+			return res, true, nil
+		case ValErr:
+			last = res
+		default:
+			return nil, true, &DoomError{Message: fmt.Sprintf("retry() callback must return ok or err, got %s", res.Kind)}
+		}
+	}
+	return last, true, nil
+}
+
+// builtinTimes calls fn(i) n times and returns nil. The index starts where
+// array indexing does under the current decrees, so 0 under "zero_indexed"
+// and 1 under "one_indexed" or on a weekday.
+func (ev *Evaluator) builtinTimes(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValInt || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "times() takes an int count and a function"}
+	}
+	if args[0].Int < 0 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("times() count cannot be negative: %d", args[0].Int)}
+	}
+	base := -ev.adjustIndex(0)
+	for i := int64(0); i < args[0].Int; i++ {
+		if _, err := ev.callFunction(args[1].Fn, []*Value{IntVal(base + i)}); err != nil {
+			return nil, true, err
+		}
+	}
+	return NilVal(), true, nil
+}
+
+// builtinGcdLcm implements gcd() and lcm(). Both results are non-negative.
+// gcd(0, 0) and an lcm() with a zero argument are undefined and doom, as does
+// a result outside int64 unless decree "arbitrary_precision" is in force.
+func (ev *Evaluator) builtinGcdLcm(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || !isIntegral(args[0]) || !isIntegral(args[1]) {
+		return nil, true, &DoomError{Message: fmt.Sprintf("%s() takes exactly 2 int arguments", name)}
+	}
+	a := new(big.Int).Abs(toBig(args[0]))
+	b := new(big.Int).Abs(toBig(args[1]))
+	if name == "gcd" && a.Sign() == 0 && b.Sign() == 0 {
+		return nil, true, &DoomError{Message: "gcd(0, 0) is undefined"}
+	}
+	if name == "lcm" && (a.Sign() == 0 || b.Sign() == 0) {
+		return nil, true, &DoomError{Message: fmt.Sprintf("lcm(%s, %s) is undefined", args[0], args[1])}
+	}
+	g := new(big.Int).GCD(nil, nil, a, b)
+	result := g
+	if name == "lcm" {
+		result = a.Mul(a.Quo(a, g), b)
+	}
+	if !result.IsInt64() && !ev.decrees.ArbitraryPrecision {
+		return nil, true, &DoomError{Message: fmt.Sprintf("integer overflow: %s(%s, %s) does not fit in int", name, args[0], args[1])}
+	}
+	return normalizeBig(result), true, nil
+}
+
+// builtinParity implements is_even() and is_odd() for ints and bigints.
+func (ev *Evaluator) builtinParity(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || !isIntegral(args[0]) {
+		return nil, true, &DoomError{Message: name + "() takes exactly 1 int argument"}
+	}
+	even := toBig(args[0]).Bit(0) == 0
+	if name == "is_odd" {
+		even = !even
+	}
+	return BoolVal(even), true, nil
+}
+
+var radixPrefixes = map[string]struct {
+	base   int
+	prefix string
+}{
+	"hex": {16, "0x"},
+	"oct": {8, "0o"},
+	"bin": {2, "0b"},
+}
+
+// builtinRadix implements hex(), oct() and bin(). Negative numbers keep their
+// sign in front of the prefix: hex(-255) is "-0xff".
+func (ev *Evaluator) builtinRadix(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || !isIntegral(args[0]) {
+		return nil, true, &DoomError{Message: fmt.Sprintf("%s() takes exactly 1 int argument", name)}
+	}
+	r := radixPrefixes[name]
+	n := toBig(args[0])
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+	}
+	return StrVal(sign + r.prefix + new(big.Int).Abs(n).Text(r.base)), true, nil
+}
+
+// builtinCommas renders an int with thousands separators: 1234567 becomes
+// "1,234,567".
+func (ev *Evaluator) builtinCommas(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || !isIntegral(args[0]) {
+		return nil, true, &DoomError{Message: "commas() takes exactly 1 int argument"}
+	}
+	digits := toBig(args[0]).String()
+	sign := ""
+	if digits[0] == '-' {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return StrVal(sign + b.String()), true, nil
+}
+
+// builtinFloorOp implements floor_div() and floor_mod(): division rounds
+// toward negative infinity and the modulo takes the divisor's sign, so
+// floor_mod(-7, 3) is 2 where -7 % 3 is -1.
+func (ev *Evaluator) builtinFloorOp(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 2 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("%s() takes exactly 2 arguments", name)}
+	}
+	op := "//"
+	if name == "floor_mod" {
+		op = "%%"
+	}
+	val, err := ev.evalArith(args[0], args[1], op)
+	return val, true, err
+}
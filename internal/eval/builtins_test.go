@@ -0,0 +1,1869 @@
+package eval
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joeabbey/morgoth/internal/lexer"
+	"github.com/joeabbey/morgoth/internal/parser"
+)
+
+// --- memoize ---
+
+func TestMemoizeCachesCalls(t *testing.T) {
+	out, _, err := evalSource(t, `
+let calls = 0
+fn slow(n) {
+  calls = calls + 1
+  n * 2
+}
+let fast = memoize(slow)
+fast(2)
+fast(2)
+fast(3)
+speak fast(2)
+speak calls
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "4\n2\n" {
+		t.Errorf("got %q, want %q", out, "4\n2\n")
+	}
+}
+
+func TestMemoizeRecursive(t *testing.T) {
+	out, _, err := evalSource(t, `
+let calls = 0
+fn fib(n) {
+  calls = calls + 1
+  if n < 2 { n } else { fib(n - 1) + fib(n - 2) }
+}
+fib = memoize(fib)
+speak fib(60)
+speak calls
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "1548008755920\n61\n" {
+		t.Errorf("got %q, want %q", out, "1548008755920\n61\n")
+	}
+}
+
+func TestMemoizeKeysOnKindAndValue(t *testing.T) {
+	out, _, err := evalSource(t, `
+let kind = memoize(fn(x) { type(x) })
+speak kind(1)
+speak kind("1")
+speak kind(nil)
+speak kind("nil")
+speak kind(true)
+speak kind("true")
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "int\nstr\nnil\nstr\nbool\nstr\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMemoizeReturnsFreshCopies(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "zero_indexed"
+let make = memoize(fn(n) { [n, { "n": n }] })
+let a = make(1)
+a[0] = 99
+a[1].n = 99
+let b = make(1)
+speak b
+b[0] = 7
+speak make(1)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[1, {n: 1}]\n[1, {n: 1}]\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMemoizeSelfContainingResult(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "zero_indexed"
+let make = memoize(fn(n) { let r = [n, 0]; r[1] = r; r })
+let a = make(1)
+let b = make(1)
+speak a == b, b[1][1][0]
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "true 1\n" {
+		t.Errorf("got %q, want %q", out, "true 1\n")
+	}
+}
+
+func TestMemoizeNonFunction(t *testing.T) {
+	_, _, err := evalSource(t, `memoize(42)`)
+	if err == nil {
+		t.Fatal("expected doom for memoize on non-function")
+	}
+}
+
+// --- compose / pipe_fns ---
+
+func TestComposeAndPipeFns(t *testing.T) {
+	prelude := "let inc = fn(x) { x + 1 }\nlet double = fn(x) { x * 2 }\n"
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak compose(inc, double)(5)`, "11\n"},
+		{`speak pipe_fns(inc, double)(5)`, "12\n"},
+		{`speak compose(inc, double, fn(x) { x - 3 })(5)`, "5\n"},
+		{`speak pipe_fns(inc, double, fn(x) { x - 3 })(5)`, "9\n"},
+		{`speak compose(inc)(1)`, "2\n"},
+		{`speak compose(fn(x) { x * 10 }, fn(a, b) { a + b })(2, 3)`, "50\n"},
+		{`let f = compose(inc, double); speak pipe_fns(f, f)(1)`, "7\n"},
+		{`speak compose(fn(s) { len(s) }, fn(s) { s + "!" })("hey")`, "4\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, prelude+tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{
+		`compose()`,
+		`pipe_fns(fn(x) { x }, 3)`,
+		`compose(fn(x) { doom("boom") }, fn(x) { x })(1)`,
+	} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- partial ---
+
+func TestPartial(t *testing.T) {
+	prelude := "fn add(a, b) { a + b }\nfn sub(a, b) { a - b }\n"
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let add5 = partial(add, 5); speak add5(10)`, "15\n"},
+		{`speak partial(sub, 10)(3)`, "7\n"},
+		{`speak partial(sub, 10, 4)()`, "6\n"},
+		{`speak partial(sub)(10, 4)`, "6\n"},
+		{`speak partial(partial(fn(a, b, c) { a * 100 + b * 10 + c }, 1), 2)(3)`, "123\n"},
+		{`let inc = partial(add, 1); speak compose(inc, inc)(1)`, "3\n"},
+		{`let add5 = partial(add, 5); add5(1); speak add5(2)`, "7\n"},
+		{`speak once(partial(add, 1, 2))()`, "3\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, prelude+tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`partial()`, `partial(42, 1)`, `partial("add", 1)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- once ---
+
+func TestOnceRunsBodyOnce(t *testing.T) {
+	out, _, err := evalSource(t, `
+let calls = 0
+let config = once(fn() {
+  calls = calls + 1
+  { "level": calls * 3 }
+})
+config()
+config()
+speak config()["level"]
+speak calls
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "3\n1\n" {
+		t.Errorf("got %q, want %q", out, "3\n1\n")
+	}
+}
+
+func TestOnceRetriesAfterDoom(t *testing.T) {
+	out, _, err := evalSource(t, `
+let calls = 0
+let init = once(fn() {
+  calls = calls + 1
+  if calls == 1 { doom("not yet") } else { calls }
+})
+catch(init)
+speak init()
+speak init()
+speak calls
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "2\n2\n2\n" {
+		t.Errorf("got %q, want %q", out, "2\n2\n2\n")
+	}
+}
+
+func TestOnceErrors(t *testing.T) {
+	for _, src := range []string{`once(42)`, `once(fn(x) { x })`, `once()`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- is_nil / exists ---
+
+func TestIsNilAndExists(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak is_nil(nil), is_nil(0), is_nil(""), is_nil(false), is_nil([])`, "true false false false false\n"},
+		{`let m = { "a": nil, "b": 2 }; speak exists(m, "a"), exists(m, "b"), exists(m, "c")`, "true true false\n"},
+		{`let m = { "a": nil }; speak m["a"] == m["c"], exists(m, "a") == exists(m, "c")`, "true false\n"},
+		{`let m = { 1: "one", 2.5: "x" }; speak exists(m, 1), exists(m, 2.5), exists(m, 2)`, "true true false\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	for _, src := range []string{`is_nil()`, `exists([1], 0)`, `exists({ "a": 1 })`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- type ---
+
+func TestTypeBuiltin(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak type(1)`, "int\n"},
+		{`speak type(1.5)`, "float\n"},
+		{`speak type("s")`, "str\n"},
+		{`speak type([1])`, "array\n"},
+		{`speak type({})`, "map\n"},
+		{`speak type(ok(1))`, "ok\n"},
+		{`speak type(nil)`, "nil\n"},
+		{`fn f() { 1 }; speak type(f)`, "fn\n"},
+		{`speak type(rat(1, 3))`, "rat\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+// --- dump ---
+
+func TestDump(t *testing.T) {
+	tests := []struct {
+		source string
+		stdout string
+		stderr string
+	}{
+		{`dump([1, "a", { "k": [true, nil] }])`, "", "array[3] = [int(1), str(\"a\"), map{k: [bool(true), nil]}]\n"},
+		{`dump({ "x": 1.5, "y": ok("z") })`, "", "map[2] = map{x: float(1.5), y: ok(str(\"z\"))}\n"},
+		{`speak dump(2) + 3`, "5\n", "int(2)\n"},
+		{`let xs = dump([1]); speak xs`, "[1]\n", "array[1] = [int(1)]\n"},
+		{`fn f() { 1 }; dump(f)`, "", "fn(f)\n"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.source))
+		prog := p.Parse()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatalf("source %q: parse errors: %v", tt.source, errs)
+		}
+		var stdout, stderr bytes.Buffer
+		ev := New()
+		ev.SetOutput(&stdout)
+		ev.SetErrOutput(&stderr)
+		if _, err := ev.Eval(prog); err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if stdout.String() != tt.stdout {
+			t.Errorf("source %q: stdout got %q, want %q", tt.source, stdout.String(), tt.stdout)
+		}
+		if stderr.String() != tt.stderr {
+			t.Errorf("source %q: stderr got %q, want %q", tt.source, stderr.String(), tt.stderr)
+		}
+	}
+}
+
+// --- debug_env ---
+
+func TestDebugEnv(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak debug_env()`, "[]\n"},
+		{`let b = 1; let a = 2; speak debug_env()`, "[a, b]\n"},
+		{`let x = 1; { let inner = 2; { let x = 3; speak debug_env() } }; speak debug_env()`, "[inner, x]\n[x]\n"},
+		{`let top = 0
+fn make(p) {
+  let secret = p * 2
+  fn() { let local = 1; debug_env() }
+}
+let f = make(1)
+speak f()`, "[f, local, make, p, secret, top]\n"},
+		{`fn show([a, b]) { debug_env() }; speak show([1, 2])`, "[a, b, show]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	if _, _, err := evalSource(t, `debug_env(1)`); err == nil {
+		t.Error("expected doom for debug_env with an argument")
+	}
+}
+
+func TestEnvNames(t *testing.T) {
+	outer := NewEnv(nil)
+	outer.Define("b", IntVal(1), false)
+	outer.Define("shadow", IntVal(1), true)
+	inner := NewEnv(outer)
+	inner.Define("a", IntVal(2), false)
+	inner.Define("shadow", IntVal(2), false)
+	got := strings.Join(inner.Names(), ",")
+	if got != "a,b,shadow" {
+		t.Errorf("got %s, want a,b,shadow", got)
+	}
+	if got := strings.Join(outer.Names(), ","); got != "b,shadow" {
+		t.Errorf("outer: got %s, want b,shadow", got)
+	}
+}
+
+// --- is_empty / non_empty ---
+
+func TestIsEmpty(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak is_empty([]), is_empty([1])`, "true false\n"},
+		{`speak is_empty({}), is_empty({ "a": 1 })`, "true false\n"},
+		{`speak is_empty(""), is_empty("x")`, "true false\n"},
+		{`speak is_empty(nil), non_empty(nil)`, "true false\n"},
+		{`speak non_empty([1]), non_empty("")`, "true false\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	for _, src := range []string{`is_empty(0)`, `non_empty(true)`, `is_empty()`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- new_array ---
+
+func TestNewArray(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak new_array(3, 0)`, "[0, 0, 0]\n"},
+		{`speak new_array(2)`, "[nil, nil]\n"},
+		{`speak new_array(0, "x")`, "[]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestNewArrayElementsIndependent(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "zero_indexed"
+let grid = new_array(3, [0, 0])
+grid[1][0] = 7
+let rows = new_array(2, { "n": 0 })
+rows[0].n = 1
+speak grid
+speak rows
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "[[0, 0], [7, 0], [0, 0]]\n[{n: 1}, {n: 0}]\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestNewArrayNegativeLength(t *testing.T) {
+	_, _, err := evalSource(t, `new_array(-1, 0)`)
+	if err == nil {
+		t.Fatal("expected doom for negative length")
+	}
+}
+
+func TestNewArrayTooLong(t *testing.T) {
+	for _, src := range []string{`new_array(9223372036854775807)`, `new_array(16777217, 0)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- keys / values / entries / zip_map / from_entries ---
+
+func TestMapReshapingBuiltins(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak keys({ "a": 1, "b": 2 })`, "[a, b]\n"},
+		{`speak values({ "a": 1, "b": 2 })`, "[1, 2]\n"},
+		{`speak entries({ "a": 1, "b": 2 })`, "[[a, 1], [b, 2]]\n"},
+		{`speak zip_map(["x", "y"], [10, 20])`, "{x: 10, y: 20}\n"},
+		{`speak from_entries([["k", true], [1, nil]])`, "{k: true, 1: nil}\n"},
+		{`let m = { "a": [1], "b": "two" }; speak from_entries(entries(m)) == m`, "true\n"},
+		{`let m = { "a": 1 }; speak zip_map(keys(m), values(m)) == m`, "true\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestMapReshapingDooms(t *testing.T) {
+	for _, src := range []string{
+		`zip_map(["a", "b"], [1])`,
+		`from_entries([["a", 1], ["b"]])`,
+		`from_entries([5])`,
+		`keys([1, 2])`,
+	} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- map_values / map_keys ---
+
+func TestMapValuesAndKeys(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak map_values({ "a": 1, "b": 2, "c": 3 }, fn(v) { v * 2 })`, "{a: 2, b: 4, c: 6}\n"},
+		{`speak map_values({ "x": 1, "y": 2 }, fn(v, k) { k + ":" + v as str })`, "{x: x:1, y: y:2}\n"},
+		{`let up = { "id": "ID", "name": "NAME" }; speak map_keys({ "id": 7, "name": "bob" }, fn(k) { up[k] })`, "{ID: 7, NAME: bob}\n"},
+		{`speak map_keys({ "a": 1, "b": 2 }, fn(k, v) { v * 10 })`, "{10: 1, 20: 2}\n"},
+		{`let m = { "a": 1 }; let n = map_values(m, fn(v) { v + 1 }); speak m, n`, "{a: 1} {a: 2}\n"},
+		{`speak map_values({}, fn(v) { v })`, "{}\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	dooms := []struct {
+		source string
+		want   string
+	}{
+		{`map_keys({ "a": 1, "b": 2 }, fn(k) { "same" })`, "map_keys() maps more than one key to same"},
+		{`map_values({ "a": 1 }, fn(v) { doom("bad value") })`, "bad value"},
+		{`map_values([1, 2], fn(v) { v })`, "map_values() takes a map and a function"},
+		{`map_keys({ "a": 1 })`, "map_keys() takes a map and a function"},
+	}
+	for _, tt := range dooms {
+		_, _, err := evalSource(t, tt.source)
+		if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != tt.want {
+			t.Errorf("source %q: got %v, want doom %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+// --- move_to_end ---
+
+func TestMoveToEnd(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let m = { "a": 1, "b": 2, "c": 3 }; move_to_end(m, "a"); speak keys(m)`, "[b, c, a]\n"},
+		{`let m = { "a": 1, "b": 2 }; move_to_end(m, "b"); speak m`, "{a: 1, b: 2}\n"},
+		{`let m = { "a": 1, "b": 2 }; speak move_to_end(m, "z"); speak keys(m)`, "false\n[a, b]\n"},
+		{`let m = { "a": 1, "b": 2 }; m["a"] = 9; speak m`, "{a: 9, b: 2}\n"},
+		{`let m = { "a": 1, "b": 2 }; m["a"] = 9; move_to_end(m, "a"); speak m`, "{b: 2, a: 9}\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	if _, _, err := evalSource(t, `move_to_end([1, 2], 1)`); err == nil {
+		t.Error("expected doom for move_to_end on an array")
+	}
+}
+
+// --- sort_keys ---
+
+func TestSortKeys(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak sort_keys({ "b": 2, "c": 3, "a": 1 })`, "{a: 1, b: 2, c: 3}\n"},
+		{`speak keys(sort_keys({ 10: "x", 9: "y", 1.5: "z" }))`, "[1.5, 9, 10]\n"},
+		{`speak keys(sort_keys({ "b": 1, 2: 1, "a": 1, -1: 1 }))`, "[-1, 2, a, b]\n"},
+		{`speak keys(sort_keys({ "nan": 1, "inf": 1, 3: 1 }))`, "[3, inf, nan]\n"},
+		{`speak keys(sort_keys({ "10": 1, 9: 1, "9": 1 }))`, "[9, 10, 9]\n"},
+		{`let m = { "z": 1, "y": 2 }; let s = sort_keys(m); speak keys(m), keys(s)`, "[z, y] [y, z]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	if _, _, err := evalSource(t, `sort_keys([1])`); err == nil {
+		t.Error("expected doom for sort_keys on an array")
+	}
+}
+
+// --- get_path / set_path ---
+
+func TestGetSetPath(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let cfg = { "db": { "hosts": [{ "name": "a" }, { "name": "b" }] } }; speak get_path(cfg, ["db", "hosts", 1, "name"])`, "b\n"},
+		{`let cfg = { "db": { "port": 5432 } }; speak get_path(cfg, ["db", "host"]), get_path(cfg, ["db", "port", "x"])`, "nil nil\n"},
+		{`speak get_path([1, 2], [5]), get_path([1, 2], ["a"])`, "nil nil\n"},
+		{`speak get_path(7, [])`, "7\n"},
+		{`let cfg = { "a": { "b": { "c": 1 } } }; set_path(cfg, ["a", "b", "c"], 2); speak cfg`, "{a: {b: {c: 2}}}\n"},
+		{`let cfg = {}; set_path(cfg, ["x", "y", "z"], true); speak cfg`, "{x: {y: {z: true}}}\n"},
+		{`let cfg = { "xs": [{ "v": 1 }, nil] }; set_path(cfg, ["xs", 1, "v"], 9); speak cfg`, "{xs: [{v: 1}, {v: 9}]}\n"},
+		{`let m = { "k": 1 }; speak set_path(m, ["k"], 2) == m`, "true\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, "decree \"zero_indexed\"\n"+tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	out, _, err := evalSource(t, `decree "one_indexed"
+let xs = [[1, 2], [3, 4]]
+set_path(xs, [2, 1], 30)
+speak get_path(xs, [2, 1]), xs`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "30 [[1, 2], [30, 4]]\n" {
+		t.Errorf("one_indexed: got %q", out)
+	}
+
+	for _, src := range []string{
+		`set_path({}, [], 1)`,
+		`set_path([1], [5], 1)`,
+		`set_path({ "a": 1 }, ["a", "b"], 2)`,
+		`get_path({}, "a")`,
+	} {
+		if _, _, err := evalSource(t, "decree \"zero_indexed\"\n"+src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- to_json / to_json_pretty ---
+
+func TestToJSON(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak to_json({ "b": [1, 2.5, true, nil], "a": "x<y" })`, `{"b":[1,2.5,true,null],"a":"x<y"}` + "\n"},
+		{`speak to_json([])`, "[]\n"},
+		{`speak to_json("say \"hi\"")`, `"say \"hi\""` + "\n"},
+		{`let a = [1]; let m = { "x": a }; speak to_json([a, a, m, m])`, `[[1],[1],{"x":[1]},{"x":[1]}]` + "\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestToJSONPretty(t *testing.T) {
+	_, result, err := evalSource(t, `to_json_pretty({ "name": "app", "ports": [80, 443], "db": { "host": "h", "opts": {} } }, 2)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "name": "app",
+  "ports": [
+    80,
+    443
+  ],
+  "db": {
+    "host": "h",
+    "opts": {}
+  }
+}`
+	if result.Str != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.Str, want)
+	}
+
+	_, result, err = evalSource(t, `to_json_pretty([1, [2]], "\t")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[\n\t1,\n\t[\n\t\t2\n\t]\n]"; result.Str != want {
+		t.Errorf("tab indent: got %q, want %q", result.Str, want)
+	}
+
+	for _, src := range []string{
+		`to_json(fn() { 1 })`,
+		`to_json([ok(1)])`,
+		`to_json_pretty(1, -1)`,
+		`to_json_pretty(1, nil)`,
+		`to_json_pretty(1, 9223372036854775807)`,
+		`to_json_pretty(1, "           ")`,
+		`decree "zero_indexed"; let a = [1]; a[0] = a; to_json(a)`,
+		`decree "zero_indexed"; let a = [1]; a[0] = [2, a]; to_json_pretty(a, 2)`,
+		`let m = { "x": 1 }; m["self"] = m; to_json(m)`,
+		`let m = { "x": 1 }; m["list"] = [m]; to_json(m)`,
+	} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- escape_html / escape_shell / escape_json ---
+
+func TestEscapeBuiltins(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak escape_html("<a href=\"x\">Tom & 'Jerry'</a>")`, "&lt;a href=&#34;x&#34;&gt;Tom &amp; &#39;Jerry&#39;&lt;/a&gt;\n"},
+		{`speak escape_html("plain")`, "plain\n"},
+		{`speak escape_shell("it's $HOME; rm -rf \\")`, "'it'\\''s $HOME; rm -rf \\'\n"},
+		{`speak escape_shell("")`, "''\n"},
+		{`speak escape_json("say \"hi\"\\ <b>\n\ttab")`, `say \"hi\"\\ <b>\n\ttab` + "\n"},
+		{`speak escape_json("\0")`, `\u0000` + "\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	for _, src := range []string{`escape_html(1)`, `escape_shell()`, `escape_json(["a"])`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- parse_csv / to_csv ---
+
+func TestCSVRoundTrip(t *testing.T) {
+	_, result, err := evalSource(t, `
+let table = [["name", "note"], ["Ann", "likes tea, coffee"], ["Bo", "said \"hi\""]]
+let text = to_csv(table)
+match parse_csv(text) {
+  ok(rows) => [text, rows == table],
+  err(e) => e,
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantText := "name,note\nAnn,\"likes tea, coffee\"\nBo,\"said \"\"hi\"\"\"\n"
+	if result.Kind != ValArray || result.Array[0].Str != wantText {
+		t.Fatalf("to_csv: got %s, want %q", result.String(), wantText)
+	}
+	if !result.Array[1].Bool {
+		t.Error("parse_csv(to_csv(table)) did not round-trip")
+	}
+}
+
+func TestCSVBuiltins(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak parse_csv("a;b\n1;2", ";")`, "ok([[a, b], [1, 2]])\n"},
+		{`speak to_csv([[1, true, nil]], "|")`, "1|true|nil\n\n"},
+		{`speak parse_csv("a,b\nc")`, "ok([[a, b], [c]])\n"},
+		{`speak parse_csv("")`, "ok([])\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	_, result, err := evalSource(t, `parse_csv("a,\"b\nc")`)
+	if err != nil {
+		t.Fatalf("malformed CSV should not doom: %v", err)
+	}
+	if result.Kind != ValErr {
+		t.Errorf("malformed CSV: got %s, want err(...)", result.String())
+	}
+
+	for _, src := range []string{`parse_csv(1)`, `parse_csv("a", ",,")`, `to_csv([1])`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- read_lines / iter_lines ---
+
+func TestReadLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(file, []byte("alpha\nbeta\r\n\ngamma\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := strconv.Quote(file)
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`chant "fs"; speak read_lines(PATH)`, "ok([alpha, beta, , gamma])\n"},
+		{`chant "fs"; speak type(read_lines(PATH + ".missing"))`, "err\n"},
+		{"let before = 1\nuse \"fs\"\nspeak read_lines(PATH)", "ok([alpha, beta, , gamma])\n"},
+		{`chant "fs"
+fn drain(it) { speak next(it), next(it), next(it), next(it), next(it) }
+match iter_lines(PATH) { ok(it) => drain(it), err(e) => speak e }`, "ok(alpha) ok(beta) ok() ok(gamma) err(done)\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, strings.ReplaceAll(tt.source, "PATH", path))
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`read_lines(PATH)`, `iter_lines(PATH)`} {
+		_, _, err := evalSource(t, strings.ReplaceAll(src, "PATH", path))
+		if doomErr, ok := err.(*DoomError); !ok || !strings.Contains(doomErr.Message, `requires chant "fs"`) {
+			t.Errorf("source %q: got %v, want doom requiring chant", src, err)
+		}
+	}
+}
+
+// --- glob / path helpers ---
+
+func TestPathHelpers(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak join_path("a", "b/", "../c", "d.txt")`, "a/c/d.txt\n"},
+		{`speak join_path()`, "\n"},
+		{`speak base_name("/tmp/x/report.tar.gz")`, "report.tar.gz\n"},
+		{`speak dir_name("/tmp/x/report.tar.gz")`, "/tmp/x\n"},
+		{`speak ext("/tmp/x/report.tar.gz"), ext("Makefile")`, ".gz \n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	if _, _, err := evalSource(t, `join_path("a", 1)`); err == nil {
+		t.Error("expected doom for non-string join_path argument")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.mor", "a.mor", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pattern := strconv.Quote(filepath.Join(dir, "*.mor"))
+	out, _, err := evalSource(t, `decree "zero_indexed"
+chant "fs"
+let found = glob(`+pattern+`)
+speak len(found)
+speak base_name(found[0]), base_name(found[1])`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "2\na.mor b.mor\n" {
+		t.Errorf("got %q", out)
+	}
+
+	if _, _, err := evalSource(t, `glob(`+pattern+`)`); err == nil {
+		t.Error("expected doom for glob without chant")
+	}
+	if _, _, err := evalSource(t, `chant "fs"; glob("[")`); err == nil {
+		t.Error("expected doom for a malformed pattern")
+	}
+}
+
+// --- take / drop / chunk ---
+
+func TestTakeDropChunk(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak take([1, 2, 3], 2)`, "[1, 2]\n"},
+		{`speak take([1, 2, 3], 10)`, "[1, 2, 3]\n"},
+		{`speak take([1, 2, 3], -1)`, "[]\n"},
+		{`speak drop([1, 2, 3], 1)`, "[2, 3]\n"},
+		{`speak drop([1, 2, 3], 10)`, "[]\n"},
+		{`speak chunk([1, 2, 3, 4, 5], 2)`, "[[1, 2], [3, 4], [5]]\n"},
+		{`speak chunk([1, 2], 5)`, "[[1, 2]]\n"},
+		{`speak chunk([], 3)`, "[]\n"},
+		{`speak chunk([1, 2, 3], 9223372036854775807)`, "[[1, 2, 3]]\n"},
+		{`speak chunk([], 9223372036854775807)`, "[]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestChunkNonPositiveSize(t *testing.T) {
+	for _, src := range []string{`chunk([1, 2], 0)`, `chunk([1, 2], -3)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- group_by / flat_map ---
+
+func TestGroupByAndFlatMap(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{
+			`speak group_by([1, 2, 3, 4, 5], fn(n) { if n % 2 == 0 { "even" } else { "odd" } })`,
+			"{odd: [1, 3, 5], even: [2, 4]}\n",
+		},
+		{`speak group_by([], fn(n) { n })`, "{}\n"},
+		{`speak flat_map([1, 2], fn(n) { [n, n * 10] })`, "[1, 10, 2, 20]\n"},
+		{`speak flat_map([1, 2], fn(n) { n + 1 })`, "[2, 3]\n"},
+		{`speak flat_map([1, 2], fn(n) { [[n]] })`, "[[1], [2]]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestGroupByPropagatesDoom(t *testing.T) {
+	_, _, err := evalSource(t, `group_by([1], fn(n) { doom("bad key") })`)
+	doomErr, ok := err.(*DoomError)
+	if !ok || doomErr.Message != "bad key" {
+		t.Fatalf("expected doom \"bad key\", got %v", err)
+	}
+}
+
+// --- windows / pairwise ---
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak windows([1, 2, 3, 4], 2)`, "[[1, 2], [2, 3], [3, 4]]\n"},
+		{`speak windows([1, 2, 3, 4], 3)`, "[[1, 2, 3], [2, 3, 4]]\n"},
+		{`speak windows([1, 2, 3], 3)`, "[[1, 2, 3]]\n"},
+		{`speak windows([1, 2], 3)`, "[]\n"},
+		{`speak pairwise(["a", "b", "c"])`, "[[a, b], [b, c]]\n"},
+		{`speak pairwise([1]), pairwise([])`, "[] []\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`windows([1, 2], 0)`, `windows([1, 2], -1)`, `windows("ab", 1)`, `pairwise(1)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- flatten ---
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak flatten([1, [2, [3, [4, [5]]]], 6])`, "[1, 2, 3, 4, 5, 6]\n"},
+		{`speak flatten([1, [2, [3, [4]]]], 1)`, "[1, 2, [3, [4]]]\n"},
+		{`speak flatten([1, [2, [3, [4]]]], 2)`, "[1, 2, 3, [4]]\n"},
+		{`speak flatten([[1], [2]], 0)`, "[[1], [2]]\n"},
+		{`speak flatten([[], [[]], "a", { "k": [1] }])`, "[a, {k: [1]}]\n"},
+		{`let inner = [1, 2]; speak flatten([inner, [inner]])`, "[1, 2, 1, 2]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`flatten(5)`, `flatten([1], -1)`, `flatten([1], "2")`, `decree "zero_indexed"; let xs = [1, 2]; xs[0] = xs; flatten(xs)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- partition ---
+
+func TestPartition(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak partition([1, 2, 3, 4, 5], fn(n) { n % 2 == 0 })`, "[[2, 4], [1, 3, 5]]\n"},
+		{`speak partition([], fn(n) { true })`, "[[], []]\n"},
+		{`speak partition([0, "", nil, 1, "a"], fn(x) { x })`, "[[1, a], [0, , nil]]\n"},
+		{`fn show([big, small]) { speak big, small }; show(partition([5, 12, 8, 1], fn(n) { n > 6 }))`, "[12, 8] [5, 1]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	_, _, err := evalSource(t, `partition([1, 2], fn(n) { if n == 2 { doom("two") } else { true } })`)
+	if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != "two" {
+		t.Errorf("got %v, want doom from the callback", err)
+	}
+}
+
+// --- unwrap / expect ---
+
+func TestUnwrapExpect(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak unwrap(ok(42))`, "42\n"},
+		{`speak unwrap(ok([1, 2]))[1]`, "2\n"},
+		{`speak expect(ok("fine"), "should not doom")`, "fine\n"},
+		{`match catch(fn() { unwrap(err({ "code": 7 })) }) { err(e) => speak e["payload"]["code"], ok(v) => speak v }`, "7\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	dooms := []struct {
+		source string
+		want   string
+	}{
+		{`unwrap(err("file not found"))`, "unwrap() on err: file not found"},
+		{`expect(err("file not found"), "loading config")`, "loading config: file not found"},
+		{`unwrap(5)`, "unwrap() takes exactly 1 result argument"},
+		{`expect(ok(1))`, "expect() takes a result and a message"},
+	}
+	for _, tt := range dooms {
+		_, _, err := evalSource(t, tt.source)
+		if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != tt.want {
+			t.Errorf("source %q: got %v, want doom %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+// --- unique ---
+
+func TestUnique(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak unique([3, 1, 3, 2, 1])`, "[3, 1, 2]\n"},
+		{`speak unique([[1, 2], [1], [1, 2], [2, 1]])`, "[[1, 2], [1], [2, 1]]\n"},
+		{`speak unique([1, "1", 1.0, 1])`, "[1, 1, 1]\n"},
+		{`speak len(unique([{ "a": 1, "b": 2 }, { "b": 2, "a": 1 }]))`, "1\n"},
+		{`speak unique([ok(1), err(1), ok(1), nil, nil])`, "[ok(1), err(1), nil]\n"},
+		{`speak unique([])`, "[]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	if _, _, err := evalSource(t, `unique("aab")`); err == nil {
+		t.Error("expected doom for unique on a string")
+	}
+}
+
+// --- sort_by ---
+
+func TestSortBy(t *testing.T) {
+	people := `let people = [
+  { "name": "cy", "age": 30 },
+  { "name": "al", "age": 25 },
+  { "name": "bo", "age": 30 },
+  { "name": "di", "age": 25 },
+]
+`
+	tests := []struct {
+		source string
+		want   string
+	}{
+		// Stable: equal ages keep their input order.
+		{people + `speak sort_by(people, fn(p) { p["age"] })`, "[{name: al, age: 25}, {name: di, age: 25}, {name: cy, age: 30}, {name: bo, age: 30}]\n"},
+		{people + `speak sort_by(people, fn(p) { p["name"] })[0]["name"]`, "al\n"},
+		{people + `speak sort_by(people, [fn(p) { -p["age"] }, fn(p) { p["name"] }])`, "[{name: bo, age: 30}, {name: cy, age: 30}, {name: al, age: 25}, {name: di, age: 25}]\n"},
+		{`speak sort_by(["ccc", "a", "bb", "dd"], fn(s) { len(s) })`, "[a, bb, dd, ccc]\n"},
+		{`let xs = [3, 1, 2]; speak sort_by(xs, fn(n) { -n }), xs`, "[3, 2, 1] [3, 1, 2]\n"},
+		{`let calls = 0; sort_by([3, 1, 2], fn(n) { calls = calls + 1; n }); speak calls`, "3\n"},
+		{`speak sort_by([], fn(n) { n }), sort_by([nil], fn(n) { n })`, "[] [nil]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	dooms := []string{
+		`sort_by([1, 2], fn(n) { if n == 2 { doom("bad key") } else { n } })`,
+		`sort_by([1, 2], fn(n) { nil })`,
+		`sort_by([1, "a"], fn(n) { n })`,
+		`sort_by([1, 2], [])`,
+		`sort_by([1, 2], [fn(n) { n }, 3])`,
+		`sort_by("ba", fn(c) { c })`,
+	}
+	for _, src := range dooms {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+	_, _, err := evalSource(t, dooms[0])
+	if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != "bad key" {
+		t.Errorf("got %v, want doom from the callback", err)
+	}
+}
+
+// --- min_by / max_by ---
+
+func TestMinMaxBy(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak max_by(["ab", "abcd", "a", "wxyz"], fn(s) { len(s) })`, "abcd\n"},
+		{`speak min_by(["ab", "abcd", "a"], fn(s) { len(s) })`, "a\n"},
+		{`speak min_by([3, -7, 5], fn(n) { n * n })`, "3\n"},
+		{`speak max_by([1, 2, 3], fn(n) { n / 2.0 })`, "3\n"},
+		{`speak max_by(["pear", "fig"], fn(s) { s })`, "pear\n"},
+		{`speak min_by([{ "n": 2 }, { "n": 1 }], fn(m) { m.n })`, "{n: 1}\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	for _, src := range []string{
+		`min_by([], fn(x) { x })`,
+		`max_by([1, 2], fn(x) { [x] })`,
+		`max_by([1, "a"], fn(x) { x })`,
+		`min_by([1], 5)`,
+	} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- bit builtins ---
+
+func TestBitBuiltins(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak popcount(0)`, "0\n"},
+		{`speak popcount(255)`, "8\n"},
+		{`speak popcount(-1)`, "64\n"},
+		{`speak leading_zeros(1)`, "63\n"},
+		{`speak leading_zeros(0)`, "64\n"},
+		{`speak leading_zeros(-1)`, "0\n"},
+		{`speak trailing_zeros(8)`, "3\n"},
+		{`speak trailing_zeros(0)`, "64\n"},
+		{`speak rotate_left(1, 3)`, "8\n"},
+		{`speak rotate_left(1, -1)`, "-9223372036854775808\n"},
+		{`speak rotate_left(0x7FFFFFFFFFFFFFFF, 1)`, "-2\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestBitBuiltinsRejectNonInts(t *testing.T) {
+	for _, src := range []string{`popcount(1.5)`, `rotate_left(1, "2")`, `trailing_zeros()`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestRat(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak rat(1, 3) + rat(1, 6) == rat(1, 2)`, "true\n"},
+		{`speak rat(1, 3) + rat(1, 6)`, "1/2\n"},
+		{`speak rat(2, 4)`, "1/2\n"},
+		{`speak rat(3, -6)`, "-1/2\n"},
+		{`speak rat(1, 3) * 3`, "1/1\n"},
+		{`speak 1 - rat(1, 4)`, "3/4\n"},
+		{`speak rat(1, 2) / rat(1, 4)`, "2/1\n"},
+		{`speak -rat(1, 2)`, "-1/2\n"},
+		{`speak rat(1, 3) < rat(1, 2)`, "true\n"},
+		{`speak rat(1, 2) === rat(2, 4)`, "true\n"},
+		{`speak rat(1, 4) as float`, "0.25\n"},
+		{`speak rat(1, 2) + 0.25`, "0.75\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestRatErrors(t *testing.T) {
+	for _, src := range []string{`rat(1, 0)`, `rat(1.5, 2)`, `rat(1)`, `rat(1, 2) / 0`, `rat(1, 2) % 2`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak decimal("19.99")`, "19.99\n"},
+		{`speak decimal("1.5")`, "1.50\n"},
+		{`speak decimal("-0.05")`, "-0.05\n"},
+		{`speak decimal(3)`, "3.00\n"},
+		{`speak decimal("0.1") + decimal("0.2")`, "0.30\n"},
+		{`speak decimal("0.1") + decimal("0.2") == decimal("0.3")`, "true\n"},
+		{`speak decimal("19.99") * 3`, "59.97\n"},
+		{`speak decimal("10.00") / 3`, "3.33\n"},
+		{`speak decimal("2.00") / 3`, "0.67\n"},
+		{`speak decimal("5") - decimal("7.25")`, "-2.25\n"},
+		{`speak -decimal("1.10")`, "-1.10\n"},
+		{`speak decimal("1.10") < decimal("1.2")`, "true\n"},
+		{`speak decimal("2.50") as float`, "2.5\n"},
+		{`decree "decimal_scale:4"
+speak decimal("1") / 8`, "0.1250\n"},
+		{`decree "decimal_scale:0"
+speak decimal("2.5")`, "3\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestDecimalSumHasNoDrift(t *testing.T) {
+	out, _, err := evalSource(t, `
+let total = decimal("0")
+fn add_cents(t, n) {
+  if n == 0 { return t }
+  return add_cents(t + decimal("0.10"), n - 1)
+}
+speak add_cents(total, 1000)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "100.00\n" {
+		t.Errorf("got %q, want %q", out, "100.00\n")
+	}
+}
+
+func TestDecimalErrors(t *testing.T) {
+	for _, src := range []string{`decimal("abc")`, `decimal("1.2.3")`, `decimal("")`, `decimal(1.5)`, `decimal("1") + 0.5`, `decimal("1") / 0`, `decimal("1") < 0.5`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestComplex(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak complex(1, 2)`, "1+2i\n"},
+		{`speak complex(1.5, -2)`, "1.5-2i\n"},
+		{`speak complex(1, 2) * complex(3, 4)`, "-5+10i\n"},
+		{`speak complex(1, 2) + 1`, "2+2i\n"},
+		{`speak 2 * complex(0, 1)`, "0+2i\n"},
+		{`speak complex(-5, 10) / complex(3, 4)`, "1+2i\n"},
+		{`speak complex(0, 1) * complex(0, 1) == complex(-1, 0)`, "true\n"},
+		{`speak -complex(1, -1)`, "-1+1i\n"},
+		{`speak real(complex(3, 4))`, "3\n"},
+		{`speak imag(complex(3, 4))`, "4\n"},
+		{`speak abs(complex(3, 4))`, "5\n"},
+		{`speak abs(-7)`, "7\n"},
+		{`speak abs(-2.5)`, "2.5\n"},
+		{`speak abs(rat(-1, 3))`, "1/3\n"},
+		{`speak abs(decimal("-2.50"))`, "2.50\n"},
+		{`decree "arbitrary_precision"; speak abs(-9223372036854775807 - 1)`, "9223372036854775808\n"},
+		{`decree "arbitrary_precision"; speak abs(-9223372036854775807 * 10)`, "92233720368547758070\n"},
+		{`decree "wrapping_math"; speak abs(-9223372036854775807 - 1)`, "-9223372036854775808\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestComplexErrors(t *testing.T) {
+	for _, src := range []string{`complex(1)`, `complex("1", 2)`, `complex(1, 2) < complex(2, 3)`, `complex(1, 2) / 0`, `complex(1, 2) % 2`, `abs("x")`, `real("x")`, `abs(-9223372036854775807 - 1)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestSleepUsesInjectedTimer(t *testing.T) {
+	p := parser.New(lexer.New(`sleep(250); sleep(0); speak "awake"`))
+	prog := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	var buf bytes.Buffer
+	var slept []time.Duration
+	ev := New()
+	ev.SetOutput(&buf)
+	ev.SetSleep(func(d time.Duration) { slept = append(slept, d) })
+	if _, err := ev.Eval(prog); err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{250 * time.Millisecond, 0}
+	if len(slept) != len(want) || slept[0] != want[0] || slept[1] != want[1] {
+		t.Errorf("slept %v, want %v", slept, want)
+	}
+	if buf.String() != "awake\n" {
+		t.Errorf("got %q, want %q", buf.String(), "awake\n")
+	}
+}
+
+func TestSleepRejectsBadDurations(t *testing.T) {
+	for _, src := range []string{`sleep(-1)`, `sleep(1.5)`, `sleep()`, `sleep(9223372036854775807)`, `sleep(9223372036855)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- uuid / ulid ---
+
+// seededRun evaluates source with a fixed seed and clock and returns its output.
+func seededRun(t *testing.T, source string, seed int64, now time.Time) string {
+	t.Helper()
+	p := parser.New(lexer.New(source))
+	prog := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	var buf bytes.Buffer
+	ev := New()
+	ev.SetOutput(&buf)
+	ev.SetSeed(seed)
+	ev.SetClock(func() time.Time { return now })
+	if _, err := ev.Eval(prog); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestUUIDSeeded(t *testing.T) {
+	now := time.UnixMilli(1469918176385)
+	first := seededRun(t, `speak uuid(); speak uuid()`, 42, now)
+	again := seededRun(t, `speak uuid(); speak uuid()`, 42, now)
+	other := seededRun(t, `speak uuid(); speak uuid()`, 43, now)
+	if first != again {
+		t.Errorf("same seed gave %q and %q", first, again)
+	}
+	if first == other {
+		t.Errorf("different seeds both gave %q", first)
+	}
+	ids := strings.Fields(first)
+	if len(ids) != 2 || ids[0] == ids[1] {
+		t.Fatalf("expected two distinct uuids, got %q", first)
+	}
+	for _, id := range ids {
+		if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[14] != '4' || id[18] != '-' || !strings.ContainsRune("89ab", rune(id[19])) || id[23] != '-' {
+			t.Errorf("%q is not a version 4 uuid", id)
+		}
+	}
+}
+
+func TestULIDSeeded(t *testing.T) {
+	// The timestamp from the ULID spec's example, 01ARYZ6S41TSV4RRFFQ69G5FAV.
+	now := time.UnixMilli(1469918176385)
+	first := seededRun(t, `speak ulid()`, 7, now)
+	if first != seededRun(t, `speak ulid()`, 7, now) {
+		t.Errorf("same seed and clock gave different ulids")
+	}
+	id := strings.TrimSpace(first)
+	if len(id) != 26 || !strings.HasPrefix(id, "01ARYZ6S41") {
+		t.Errorf("got %q, want 26 characters starting with the encoded timestamp 01ARYZ6S41", id)
+	}
+	later := strings.TrimSpace(seededRun(t, `speak ulid()`, 7, now.Add(time.Millisecond)))
+	if later <= id {
+		t.Errorf("ulid from a later millisecond %q does not sort after %q", later, id)
+	}
+	if _, _, err := evalSource(t, `ulid(1)`); err == nil {
+		t.Error("expected doom for ulid with an argument")
+	}
+}
+
+// --- retry ---
+
+func TestRetrySucceedsOnThirdAttempt(t *testing.T) {
+	p := parser.New(lexer.New(`
+let calls = 0
+fn flaky() {
+  calls = calls + 1
+  if calls < 3 { err("try again") } else { ok(calls * 10) }
+}
+speak retry(5, flaky, 100)
+speak calls
+`))
+	prog := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	var buf bytes.Buffer
+	var slept []time.Duration
+	ev := New()
+	ev.SetOutput(&buf)
+	ev.SetSleep(func(d time.Duration) { slept = append(slept, d) })
+	if _, err := ev.Eval(prog); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "ok(30)\n3\n" {
+		t.Errorf("got %q, want %q", buf.String(), "ok(30)\n3\n")
+	}
+	if len(slept) != 2 || slept[0] != 100*time.Millisecond || slept[1] != 100*time.Millisecond {
+		t.Errorf("slept %v, want two 100ms backoffs", slept)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let calls = 0; speak retry(3, fn() { calls = calls + 1; err(calls) }); speak calls`, "err(3)\n3\n"},
+		{`let calls = 0; speak retry(4, fn() { calls = calls + 1; ok("first") }); speak calls`, "ok(first)\n1\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`retry(0, fn() { ok(1) })`, `retry(2, 5)`, `retry(2, fn() { 1 })`, `retry(2, fn() { err(1) }, -5)`, `retry(2, fn() { err(1) }, 9223372036854775807)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- times ---
+
+func TestTimes(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`decree "zero_indexed"; times(3, fn(i) { speak i })`, "0\n1\n2\n"},
+		{`decree "one_indexed"; times(3, fn(i) { speak i })`, "1\n2\n3\n"},
+		{`decree "zero_indexed"; let calls = 0; times(4, fn() { calls = calls + 1 }); speak calls`, "4\n"},
+		{`let calls = 0; speak times(0, fn() { calls = calls + 1 }); speak calls`, "nil\n0\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`times(-1, fn() { 1 })`, `times(2)`, `times("3", fn() { 1 })`, `times(2, fn(i) { doom("stop") })`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestMutexGuardsSpawnedIncrements(t *testing.T) {
+	out, _, err := evalSource(t, `
+let m = mutex()
+let total = 0
+fn bump() {
+  lock(m)
+  total = total + 1
+  unlock(m)
+}
+fn spawn_n(n) {
+  if n == 0 { return 0 }
+  spawn { bump() }
+  spawn_n(n - 1)
+}
+spawn_n(100)
+await_all()
+speak total
+speak m
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "100\n<mutex>\n" {
+		t.Errorf("got %q, want %q", out, "100\n<mutex>\n")
+	}
+}
+
+func TestMutexErrors(t *testing.T) {
+	for _, src := range []string{`unlock(mutex())`, `lock(1)`, `mutex(1)`, `let m = mutex(); lock(m); unlock(m); unlock(m)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+// --- spawn_pool / submit / await_pool ---
+
+func TestSpawnPoolBoundsConcurrency(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "zero_indexed"
+let m = mutex()
+let active = 0
+let peak = 0
+let pool = spawn_pool(3)
+times(12, fn(i) {
+  submit(pool, fn() {
+    lock(m)
+    active = active + 1
+    if active > peak { peak = active }
+    unlock(m)
+    sleep(2)
+    lock(m)
+    active = active - 1
+    unlock(m)
+    i * i
+  })
+})
+speak await_pool(pool)
+speak peak <= 3, active
+speak await_pool(pool)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[ok(0), ok(1), ok(4), ok(9), ok(16), ok(25), ok(36), ok(49), ok(64), ok(81), ok(100), ok(121)]\ntrue 0\n[]\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestSpawnPool(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let p = spawn_pool(1); submit(p, fn() { doom("bad") }); submit(p, fn() { 2 }); speak await_pool(p)`, "[err(bad), ok(2)]\n"},
+		{`let p = spawn_pool(2); let h = submit(p, fn() { "one" }); speak await(h), len(await_pool(p))`, "ok(one) 1\n"},
+		{`let p = spawn_pool(2); speak p, type(p)`, "<pool> pool\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`spawn_pool(0)`, `spawn_pool("2")`, `submit(spawn_pool(1), 5)`, `submit(1, fn() { 1 })`, `await_pool([])`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestIterSources(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let it = iter([1, 2])
+speak next(it), next(it), next(it), next(it)`, "ok(1) ok(2) err(done) err(done)\n"},
+		{`let it = iter({"a": 1, "b": 2})
+speak next(it), next(it), next(it)`, "ok([a, 1]) ok([b, 2]) err(done)\n"},
+		{`let it = iter("hé")
+speak next(it), next(it), next(it)`, "ok(h) ok(é) err(done)\n"},
+		{`let it = range(0, 3)
+speak next(it), next(it), next(it), next(it)`, "ok(0) ok(1) ok(2) err(done)\n"},
+		{`let it = iter(range(5, 0, -2))
+speak next(it), next(it), next(it), next(it)`, "ok(5) ok(3) ok(1) err(done)\n"},
+		{`let it = iter([])
+speak next(it)`, "err(done)\n"},
+		{`decree "zero_indexed"
+let xs = [1]
+let it = iter(xs)
+xs[0] = 9
+speak next(it)`, "ok(1)\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestIterDrainsWithRecursion(t *testing.T) {
+	out, _, err := evalSource(t, `
+fn sum(it, acc) {
+  match next(it) {
+    ok(v) => sum(it, acc + v),
+    err(e) => acc,
+  }
+}
+speak sum(range(1, 101), 0)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "5050\n" {
+		t.Errorf("got %q, want %q", out, "5050\n")
+	}
+}
+
+func TestIterErrors(t *testing.T) {
+	for _, src := range []string{`iter(1)`, `next([1])`, `range(1)`, `range(0, 5, 0)`, `range(0, 1.5)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestNumberFormatting(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak hex(255)`, "0xff\n"},
+		{`speak hex(-255)`, "-0xff\n"},
+		{`speak hex(0)`, "0x0\n"},
+		{`speak oct(8)`, "0o10\n"},
+		{`speak bin(5)`, "0b101\n"},
+		{`speak bin(-2)`, "-0b10\n"},
+		{`speak hex(-9223372036854775807 - 1)`, "-0x8000000000000000\n"},
+		{`speak commas(1234567)`, "1,234,567\n"},
+		{`speak commas(-1234567)`, "-1,234,567\n"},
+		{`speak commas(123)`, "123\n"},
+		{`speak commas(1000)`, "1,000\n"},
+		{`speak commas(-100)`, "-100\n"},
+		{`speak commas(0)`, "0\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestNumberFormattingRejectsNonInts(t *testing.T) {
+	for _, src := range []string{`hex(1.5)`, `oct("8")`, `bin()`, `commas(1.5)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestFloorDivMod(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak -7 / 3, floor_div(-7, 3)`, "-2 -3\n"},
+		{`speak -7 % 3, floor_mod(-7, 3)`, "-1 2\n"},
+		{`speak 7 % -3, floor_mod(7, -3)`, "1 -2\n"},
+		{`speak -7 % -3, floor_mod(-7, -3)`, "-1 -1\n"},
+		{`speak 7 % 3, floor_mod(7, 3)`, "1 1\n"},
+		{`speak floor_mod(-6, 3)`, "0\n"},
+		{`speak floor_div(7.5, -2)`, "-4\n"},
+		{`decree "arbitrary_precision"
+speak floor_mod(0 - 9223372036854775807 * 2, 10)`, "6\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestFloorDivModErrors(t *testing.T) {
+	for _, src := range []string{`floor_mod(1, 0)`, `floor_div(1, 0)`, `floor_mod(1.5, 1)`, `floor_div(1)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestGcdLcmParity(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak gcd(12, 18), lcm(4, 6)`, "6 12\n"},
+		{`speak gcd(-12, 18), lcm(-4, 6)`, "6 12\n"},
+		{`speak gcd(7, 0), gcd(1, 1)`, "7 1\n"},
+		{`speak is_even(4), is_even(-3), is_odd(-3), is_odd(0)`, "true false true false\n"},
+		{`decree "arbitrary_precision"
+speak lcm(9223372036854775807, 2)`, "18446744073709551614\n"},
+		{`decree "arbitrary_precision"
+speak is_odd(9223372036854775807 * 3)`, "true\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	for _, src := range []string{`gcd(0, 0)`, `lcm(0, 5)`, `gcd(1.5, 3)`, `lcm(2)`, `is_even("2")`, `is_odd(2.0)`, `lcm(9223372036854775807, 2)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
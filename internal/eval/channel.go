@@ -0,0 +1,173 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/joeabbey/morgoth/internal/parser"
+)
+
+// Channels carry values between spawned tasks. channel(n) makes a channel
+// with buffer size n (unbuffered when omitted); send and recv block like
+// their Go counterparts, and doom instead when no task is left to unblock
+// them.
+
+// maxChannelSize bounds channel()'s buffer size.
+const maxChannelSize = 1 << 20
+
+// Channel is a FIFO between tasks. recvq holds tasks parked in recv or
+// select; sendq holds tasks parked in send, with the value each is sending.
+// All fields are guarded by the interpreter lock.
+type Channel struct {
+	size  int
+	buf   []*Value
+	recvq []waitReg
+	sendq []waitReg
+}
+
+func ChannelVal(ch *Channel) *Value { return &Value{Kind: ValChannel, Chan: ch} }
+
+func (ev *Evaluator) builtinChannel(args []*Value) (*Value, bool, error) {
+	size := int64(0)
+	switch {
+	case len(args) == 1 && args[0].Kind == ValInt && args[0].Int >= 0:
+		size = args[0].Int
+	case len(args) != 0:
+		return nil, true, &DoomError{Message: "channel() takes an optional non-negative int buffer size"}
+	}
+	if size > maxChannelSize {
+		return nil, true, &DoomError{Message: fmt.Sprintf("channel() buffer size must be at most %d, got %d", maxChannelSize, size)}
+	}
+	return ChannelVal(&Channel{size: int(size)}), true, nil
+}
+
+// tryRecv takes the next value from c without parking: from the buffer,
+// refilling it from a parked sender, or straight from a parked sender when
+// the buffer is empty.
+func (g *taskGroup) tryRecv(c *Channel) (*Value, bool) {
+	if len(c.buf) > 0 {
+		v := c.buf[0]
+		c.buf = c.buf[1:]
+		if s, ok := takeLive(&c.sendq); ok {
+			c.buf = append(c.buf, s.val)
+			g.wake(s, nil)
+		}
+		return v, true
+	}
+	if s, ok := takeLive(&c.sendq); ok {
+		g.wake(s, nil)
+		return s.val, true
+	}
+	return nil, false
+}
+
+func (ev *Evaluator) builtinSend(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValChannel {
+		return nil, true, &DoomError{Message: "send() takes a channel and a value"}
+	}
+	c := args[0].Chan
+	if r, ok := takeLive(&c.recvq); ok {
+		ev.tasks.wake(r, args[1])
+		return NilVal(), true, nil
+	}
+	if len(c.buf) < c.size {
+		c.buf = append(c.buf, args[1])
+		return NilVal(), true, nil
+	}
+	w := newWaiter()
+	c.sendq = append(c.sendq, waitReg{w: w, val: args[1]})
+	if !ev.park(w) {
+		return nil, true, deadlockDoom("send()")
+	}
+	return NilVal(), true, nil
+}
+
+func (ev *Evaluator) builtinRecv(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValChannel {
+		return nil, true, &DoomError{Message: "recv() takes exactly 1 channel argument"}
+	}
+	c := args[0].Chan
+	if v, ok := ev.tasks.tryRecv(c); ok {
+		return v, true, nil
+	}
+	w := newWaiter()
+	c.recvq = append(c.recvq, waitReg{w: w})
+	if !ev.park(w) {
+		return nil, true, deadlockDoom("recv()")
+	}
+	return w.val, true, nil
+}
+
+// evalSelectExpr blocks until one recv or await arm is ready (or runs the
+// default arm if none is), binds the received value, and evaluates that
+// arm's body.
+func (ev *Evaluator) evalSelectExpr(expr *parser.SelectExpr) (*Value, error) {
+	if len(expr.Arms) == 0 {
+		return nil, &DoomError{Message: "select needs at least one arm"}
+	}
+	srcs := make([]*Value, len(expr.Arms))
+	deflt := -1
+	for i, arm := range expr.Arms {
+		if arm.Op == "default" {
+			deflt = i
+			continue
+		}
+		src, err := ev.evalExpr(arm.Source)
+		if err != nil {
+			return nil, err
+		}
+		if !(arm.Op == "recv" && src.Kind == ValChannel) && !(arm.Op == "await" && src.Kind == ValFuture) {
+			return nil, &DoomError{Message: fmt.Sprintf("select %s() cannot wait on %s", arm.Op, src.String())}
+		}
+		srcs[i] = src
+	}
+
+	chosen, val := ev.selectReady(srcs)
+	if chosen < 0 && deflt >= 0 {
+		chosen = deflt
+	}
+	if chosen < 0 {
+		w := newWaiter()
+		for i, src := range srcs {
+			switch {
+			case src == nil:
+			case src.Kind == ValChannel:
+				src.Chan.recvq = append(src.Chan.recvq, waitReg{w: w, arm: i})
+			default:
+				src.Future.waiters = append(src.Future.waiters, waitReg{w: w, arm: i})
+			}
+		}
+		if !ev.park(w) {
+			return nil, deadlockDoom("select")
+		}
+		chosen, val = w.arm, w.val
+	}
+
+	arm := expr.Arms[chosen]
+	selectEnv := NewEnv(ev.env)
+	if arm.Name != "" {
+		selectEnv.Define(arm.Name, val, false)
+	}
+	savedEnv := ev.env
+	ev.env = selectEnv
+	result, err := ev.evalExpr(arm.Body)
+	ev.env = savedEnv
+	return result, err
+}
+
+// selectReady returns the first arm whose channel has a value or whose task
+// has finished, with that value, or -1 when none is ready. nil entries
+// (the default arm) are skipped.
+func (ev *Evaluator) selectReady(srcs []*Value) (int, *Value) {
+	for i, src := range srcs {
+		switch {
+		case src == nil:
+		case src.Kind == ValChannel:
+			if v, ok := ev.tasks.tryRecv(src.Chan); ok {
+				return i, v
+			}
+		case src.Future.finished():
+			return i, src.Future.result
+		}
+	}
+	return -1, nil
+}
@@ -0,0 +1,124 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/cmplx"
+	"strconv"
+)
+
+// Complex numbers, created with complex(re, im). Arithmetic with a complex
+// operand promotes the other side (int, float, bigint or rat) to complex.
+
+func ComplexVal(c complex128) *Value { return &Value{Kind: ValComplex, Complex: c} }
+
+func formatComplex(c complex128) string {
+	im := imag(c)
+	sign := "+"
+	if math.Signbit(im) && !math.IsNaN(im) {
+		sign = "-"
+		im = -im
+	}
+	return strconv.FormatFloat(real(c), 'g', -1, 64) + sign + strconv.FormatFloat(im, 'g', -1, 64) + "i"
+}
+
+func isComplexOperand(v *Value) bool {
+	switch v.Kind {
+	case ValComplex, ValInt, ValFloat, ValBigInt, ValRat:
+		return true
+	}
+	return false
+}
+
+// useComplex reports whether an operation should be carried out on
+// complex numbers: at least one side is complex and the other is numeric.
+func useComplex(left, right *Value) bool {
+	if left.Kind != ValComplex && right.Kind != ValComplex {
+		return false
+	}
+	return isComplexOperand(left) && isComplexOperand(right)
+}
+
+func toComplex(v *Value) complex128 {
+	if v.Kind == ValComplex {
+		return v.Complex
+	}
+	return complex(toFloat(v), 0)
+}
+
+func (ev *Evaluator) evalComplexArith(left, right *Value, op string) (*Value, error) {
+	l, r := toComplex(left), toComplex(right)
+	switch op {
+	case "+":
+		return ComplexVal(l + r), nil
+	case "-":
+		return ComplexVal(l - r), nil
+	case "*":
+		return ComplexVal(l * r), nil
+	case "/":
+		if r == 0 {
+			return nil, &DoomError{Message: "division by zero"}
+		}
+		return ComplexVal(l / r), nil
+	default:
+		return nil, &DoomError{Message: fmt.Sprintf("cannot perform %s on complex numbers", op)}
+	}
+}
+
+func (ev *Evaluator) builtinComplex(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 {
+		return nil, true, &DoomError{Message: "complex() takes exactly 2 arguments (real, imaginary)"}
+	}
+	for _, a := range args {
+		if a.Kind != ValInt && a.Kind != ValFloat {
+			return nil, true, &DoomError{Message: fmt.Sprintf("complex() arguments must be numbers, got %s", a.String())}
+		}
+	}
+	return ComplexVal(complex(toFloat(args[0]), toFloat(args[1]))), true, nil
+}
+
+// builtinComplexPart implements real() and imag(). Plain numbers are treated
+// as complex numbers with a zero imaginary part.
+func (ev *Evaluator) builtinComplexPart(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || !isComplexOperand(args[0]) {
+		return nil, true, &DoomError{Message: fmt.Sprintf("%s() takes exactly 1 number", name)}
+	}
+	c := toComplex(args[0])
+	if name == "real" {
+		return FloatVal(real(c)), true, nil
+	}
+	return FloatVal(imag(c)), true, nil
+}
+
+// builtinAbs returns the magnitude of a complex number, or the absolute
+// value of an int or float (keeping its kind).
+func (ev *Evaluator) builtinAbs(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: "abs() takes exactly 1 argument"}
+	}
+	switch v := args[0]; v.Kind {
+	case ValComplex:
+		return FloatVal(cmplx.Abs(v.Complex)), true, nil
+	case ValFloat:
+		return FloatVal(math.Abs(v.Float)), true, nil
+	case ValInt:
+		if v.Int >= 0 {
+			return v, true, nil
+		}
+		// Negating the most negative int overflows, as unary - does.
+		if ev.decrees.ArbitraryPrecision {
+			return normalizeBig(new(big.Int).Neg(big.NewInt(v.Int))), true, nil
+		}
+		res, err := ev.checkedIntArith(0, v.Int, "-")
+		return res, true, err
+	case ValBigInt:
+		return normalizeBig(new(big.Int).Abs(v.Big)), true, nil
+	case ValRat:
+		return RatVal(new(big.Rat).Abs(v.Rat)), true, nil
+	case ValDecimal:
+		return DecimalVal(&Decimal{Unscaled: new(big.Int).Abs(v.Dec.Unscaled), Scale: v.Dec.Scale}), true, nil
+	default:
+		return nil, true, &DoomError{Message: fmt.Sprintf("abs() takes a number, got %s", v.String())}
+	}
+}
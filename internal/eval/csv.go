@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"encoding/csv"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvDelimiter reads the optional delimiter argument at position i, which
+// must be a single-character string; the default is a comma.
+func csvDelimiter(name string, args []*Value, i int) (rune, error) {
+	if len(args) <= i {
+		return ',', nil
+	}
+	d := args[i]
+	if d.Kind != ValStr || utf8.RuneCountInString(d.Str) != 1 {
+		return 0, &DoomError{Message: name + "() delimiter must be a single character"}
+	}
+	r, _ := utf8.DecodeRuneInString(d.Str)
+	return r, nil
+}
+
+// builtinParseCSV parses CSV text into ok(rows), each row an array of string
+// fields. Malformed input yields err(message) instead of dooming. Rows may
+// have differing field counts.
+func (ev *Evaluator) builtinParseCSV(args []*Value) (*Value, bool, error) {
+	if len(args) < 1 || len(args) > 2 || args[0].Kind != ValStr {
+		return nil, true, &DoomError{Message: "parse_csv() takes a string and an optional delimiter"}
+	}
+	delim, err := csvDelimiter("parse_csv", args, 1)
+	if err != nil {
+		return nil, true, err
+	}
+	r := csv.NewReader(strings.NewReader(args[0].Str))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return ErrVal(StrVal(err.Error())), true, nil
+	}
+	rows := make([]*Value, len(records))
+	for i, rec := range records {
+		fields := make([]*Value, len(rec))
+		for j, f := range rec {
+			fields[j] = StrVal(f)
+		}
+		rows[i] = ArrayVal(fields)
+	}
+	return OkVal(ArrayVal(rows)), true, nil
+}
+
+// builtinToCSV formats an array of rows as CSV text, quoting fields as
+// needed. Non-string fields are written in their speak form.
+func (ev *Evaluator) builtinToCSV(args []*Value) (*Value, bool, error) {
+	if len(args) < 1 || len(args) > 2 || args[0].Kind != ValArray {
+		return nil, true, &DoomError{Message: "to_csv() takes an array of rows and an optional delimiter"}
+	}
+	delim, err := csvDelimiter("to_csv", args, 1)
+	if err != nil {
+		return nil, true, err
+	}
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	w.Comma = delim
+	for _, row := range args[0].Array {
+		if row.Kind != ValArray {
+			return nil, true, &DoomError{Message: "to_csv() rows must be arrays"}
+		}
+		rec := make([]string, len(row.Array))
+		for j, f := range row.Array {
+			rec[j] = f.String()
+		}
+		if err := w.Write(rec); err != nil {
+			return nil, true, &DoomError{Message: err.Error()}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, true, &DoomError{Message: err.Error()}
+	}
+	return StrVal(sb.String()), true, nil
+}
@@ -0,0 +1,34 @@
+package eval
+
+import "github.com/joeabbey/morgoth/internal/parser"
+
+// Debugger is consulted before every top-level item and block statement once
+// installed with SetDebugger. A non-nil error from Before aborts evaluation
+// and is returned from Eval unchanged, so a debugger can implement "quit".
+type Debugger interface {
+	Before(ev *Evaluator, node parser.Node) error
+}
+
+// SetDebugger installs d, or removes the current debugger when d is nil.
+// Spawned tasks run without the debugger so they never compete for input.
+func (ev *Evaluator) SetDebugger(d Debugger) {
+	ev.debugger = d
+}
+
+// Lookup returns the value bound to name in the scope currently being
+// evaluated, for use by debuggers.
+func (ev *Evaluator) Lookup(name string) (*Value, bool) {
+	val, err := ev.env.Get(name)
+	if err != nil {
+		return nil, false
+	}
+	return val, true
+}
+
+// debugStep runs the debugger hook, if any, before node is evaluated.
+func (ev *Evaluator) debugStep(node parser.Node) error {
+	if ev.debugger == nil {
+		return nil
+	}
+	return ev.debugger.Before(ev, node)
+}
@@ -0,0 +1,170 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Decimal is a fixed-point number: Unscaled / 10^Scale. decimal() values and
+// the results of decimal arithmetic are rounded (half away from zero) to the
+// scale set by decree "decimal_scale:N", so 19.99 + 0.01 is exactly 20.00.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+func DecimalVal(d *Decimal) *Value { return &Value{Kind: ValDecimal, Dec: d} }
+
+// String renders the decimal with exactly Scale fractional digits, keeping
+// trailing zeros.
+func (d *Decimal) String() string {
+	digits := new(big.Int).Abs(d.Unscaled).String()
+	sign := ""
+	if d.Unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	if d.Scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= d.Scale {
+		digits = strings.Repeat("0", d.Scale-len(digits)+1) + digits
+	}
+	cut := len(digits) - d.Scale
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+// Rescale returns d rounded half away from zero to the given scale.
+func (d *Decimal) Rescale(scale int) *Decimal {
+	if scale >= d.Scale {
+		n := new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale))
+		return &Decimal{Unscaled: n, Scale: scale}
+	}
+	return &Decimal{Unscaled: roundQuo(d.Unscaled, pow10(d.Scale-scale)), Scale: scale}
+}
+
+// Cmp compares d and other numerically, regardless of scale.
+func (d *Decimal) Cmp(other *Decimal) int {
+	s := max(d.Scale, other.Scale)
+	return d.Rescale(s).Unscaled.Cmp(other.Rescale(s).Unscaled)
+}
+
+func (d *Decimal) Float64() float64 {
+	f, _ := new(big.Rat).SetFrac(d.Unscaled, pow10(d.Scale)).Float64()
+	return f
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundQuo divides n by d, rounding half away from zero.
+func roundQuo(n, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if new(big.Int).Abs(new(big.Int).Lsh(r, 1)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		if n.Sign()*d.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// parseDecimal accepts an optional sign, digits, and an optional fractional
+// part, e.g. "19.99", "-0.5" or "3".
+func parseDecimal(s string) (*Decimal, bool) {
+	body := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(body, ".")
+	if whole == "" && frac == "" {
+		return nil, false
+	}
+	n, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok || strings.ContainsAny(whole+frac, "+-_") {
+		return nil, false
+	}
+	if strings.HasPrefix(s, "-") {
+		n.Neg(n)
+	}
+	return &Decimal{Unscaled: n, Scale: len(frac)}, true
+}
+
+func toDecimal(v *Value) *Decimal {
+	if v.Kind == ValDecimal {
+		return v.Dec
+	}
+	return &Decimal{Unscaled: toBig(v), Scale: 0}
+}
+
+// useDecimal reports whether either operand is a decimal.
+func useDecimal(left, right *Value) bool {
+	return left.Kind == ValDecimal || right.Kind == ValDecimal
+}
+
+// decimalOperands converts both operands to decimals. Integers convert
+// exactly; anything else, floats in particular, is rejected rather than
+// silently rounded.
+func decimalOperands(left, right *Value) (*Decimal, *Decimal, error) {
+	for _, v := range []*Value{left, right} {
+		if v.Kind != ValDecimal && !isIntegral(v) {
+			return nil, nil, &DoomError{Message: fmt.Sprintf("cannot mix decimal with %s; use decimal()", v.String())}
+		}
+	}
+	return toDecimal(left), toDecimal(right), nil
+}
+
+func (ev *Evaluator) evalDecimalArith(left, right *Value, op string) (*Value, error) {
+	l, r, err := decimalOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	scale := ev.decrees.DecimalScale
+	var res *Decimal
+	switch op {
+	case "+", "-":
+		s := max(l.Scale, r.Scale)
+		a, b := l.Rescale(s).Unscaled, r.Rescale(s).Unscaled
+		n := new(big.Int)
+		if op == "+" {
+			n.Add(a, b)
+		} else {
+			n.Sub(a, b)
+		}
+		res = &Decimal{Unscaled: n, Scale: s}
+	case "*":
+		res = &Decimal{Unscaled: new(big.Int).Mul(l.Unscaled, r.Unscaled), Scale: l.Scale + r.Scale}
+	case "/":
+		if r.Unscaled.Sign() == 0 {
+			return nil, &DoomError{Message: "division by zero"}
+		}
+		// l/r at the target scale is l.Unscaled * 10^(scale - l.Scale + r.Scale) / r.Unscaled.
+		num, den := new(big.Int).Set(l.Unscaled), new(big.Int).Set(r.Unscaled)
+		if shift := scale - l.Scale + r.Scale; shift >= 0 {
+			num.Mul(num, pow10(shift))
+		} else {
+			den.Mul(den, pow10(-shift))
+		}
+		return DecimalVal(&Decimal{Unscaled: roundQuo(num, den), Scale: scale}), nil
+	default:
+		return nil, &DoomError{Message: fmt.Sprintf("cannot perform %s on decimals", op)}
+	}
+	return DecimalVal(res.Rescale(scale)), nil
+}
+
+func (ev *Evaluator) builtinDecimal(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: "decimal() takes exactly 1 argument"}
+	}
+	switch args[0].Kind {
+	case ValStr:
+		d, ok := parseDecimal(args[0].Str)
+		if !ok {
+			return nil, true, &DoomError{Message: fmt.Sprintf("decimal() cannot parse %q", args[0].Str)}
+		}
+		return DecimalVal(d.Rescale(ev.decrees.DecimalScale)), true, nil
+	case ValInt, ValBigInt, ValDecimal:
+		return DecimalVal(toDecimal(args[0]).Rescale(ev.decrees.DecimalScale)), true, nil
+	default:
+		return nil, true, &DoomError{Message: fmt.Sprintf("decimal() takes a string or int, got %s", args[0].String())}
+	}
+}
@@ -1,5 +1,11 @@
 package eval
 
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
 // DecreeConfig holds runtime flags set by decree statements. spec:SEC-6-2
 type DecreeConfig struct {
 	IndexingBase   string // "zero", "one", "weekday" (default)
@@ -8,17 +14,65 @@ type DecreeConfig struct {
 	SoftCasts      bool
 	SequentialMood bool
 	NoForgiveness  bool
+	DeepSorry      bool // sorry() forgives consts in enclosing scopes too
+	// SaturatingCasts makes `as int` clamp out-of-range values to int64 bounds.
+	SaturatingCasts bool
+	// ConstPurity rejects const initializers that have side effects.
+	ConstPurity bool
+	// Methods binds self to the map when calling obj.field(...).
+	Methods bool
+	// StrictTruthiness makes only false and nil falsy.
+	StrictTruthiness bool
+	// ArbitraryPrecision promotes int arithmetic to math/big on overflow.
+	ArbitraryPrecision bool
+	// WrappingMath makes int overflow wrap around instead of dooming.
+	WrappingMath bool
+	// TrueDivision makes / between ints produce a float; // still floors.
+	TrueDivision bool
+	// DecimalScale is the number of fractional digits decimal values keep.
+	DecimalScale int
+	// Weekend holds the days on which "weekday" indexing is 0-based.
+	Weekend map[time.Weekday]bool
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
 }
 
 // NewDecreeConfig returns a DecreeConfig with defaults.
 func NewDecreeConfig() *DecreeConfig {
 	return &DecreeConfig{
 		IndexingBase: "weekday",
+		DecimalScale: 2,
+		Weekend:      map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
 	}
 }
 
+// Clone returns a copy of d. Weekend is shared, which is safe because a
+// weekend decree replaces the map rather than modifying it.
+func (d *DecreeConfig) Clone() *DecreeConfig {
+	c := *d
+	return &c
+}
+
 // Apply parses a decree string and updates the config.
 func (d *DecreeConfig) Apply(decree string) {
+	if days, ok := strings.CutPrefix(decree, "weekend:"); ok {
+		d.applyWeekend(days)
+		return
+	}
+	if scale, ok := strings.CutPrefix(decree, "decimal_scale:"); ok {
+		if n, err := strconv.Atoi(scale); err == nil && n >= 0 {
+			d.DecimalScale = n
+		}
+		return
+	}
 	switch decree {
 	case "zero_indexed":
 		d.IndexingBase = "zero"
@@ -28,11 +82,84 @@ func (d *DecreeConfig) Apply(decree string) {
 		d.DetHashing = true
 	case "soft_casts":
 		d.SoftCasts = true
+	case "saturating_casts":
+		d.SaturatingCasts = true
 	case "ambitious_mode":
 		d.AmbitiousMode = true
 	case "sequential_mood":
 		d.SequentialMood = true
 	case "no_forgiveness":
 		d.NoForgiveness = true
+	case "deep_sorry":
+		d.DeepSorry = true
+	case "const_purity":
+		d.ConstPurity = true
+	case "methods":
+		d.Methods = true
+	case "strict_truthiness":
+		d.StrictTruthiness = true
+	case "arbitrary_precision":
+		d.ArbitraryPrecision = true
+	case "wrapping_math":
+		d.WrappingMath = true
+	case "true_division":
+		d.TrueDivision = true
+	}
+}
+
+// Revoke undoes a decree by resetting the field it sets to its default.
+// "zero_indexed" and "one_indexed" both restore weekday indexing, and
+// "weekend" and "decimal_scale" restore their defaults. Unknown names are
+// ignored, as in Apply.
+func (d *DecreeConfig) Revoke(decree string) {
+	def := NewDecreeConfig()
+	switch decree {
+	case "zero_indexed", "one_indexed":
+		d.IndexingBase = def.IndexingBase
+	case "weekend":
+		d.Weekend = def.Weekend
+	case "decimal_scale":
+		d.DecimalScale = def.DecimalScale
+	case "deterministic_hashing":
+		d.DetHashing = false
+	case "soft_casts":
+		d.SoftCasts = false
+	case "saturating_casts":
+		d.SaturatingCasts = false
+	case "ambitious_mode":
+		d.AmbitiousMode = false
+	case "sequential_mood":
+		d.SequentialMood = false
+	case "no_forgiveness":
+		d.NoForgiveness = false
+	case "deep_sorry":
+		d.DeepSorry = false
+	case "const_purity":
+		d.ConstPurity = false
+	case "methods":
+		d.Methods = false
+	case "strict_truthiness":
+		d.StrictTruthiness = false
+	case "arbitrary_precision":
+		d.ArbitraryPrecision = false
+	case "wrapping_math":
+		d.WrappingMath = false
+	case "true_division":
+		d.TrueDivision = false
+	}
+}
+
+// applyWeekend replaces the weekend schedule with a comma-separated list of
+// three-letter day names, e.g. "fri,sat". Unknown names are ignored, like
+// unknown decrees; a list with no recognizable days leaves the schedule alone.
+func (d *DecreeConfig) applyWeekend(days string) {
+	weekend := make(map[time.Weekday]bool)
+	for _, name := range strings.Split(days, ",") {
+		if day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+			weekend[day] = true
+		}
+	}
+	if len(weekend) > 0 {
+		d.Weekend = weekend
 	}
 }
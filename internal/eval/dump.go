@@ -0,0 +1,64 @@
+package eval
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// builtinDump writes a type-annotated rendering of its argument to the error
+// stream and returns the argument unchanged, so it can wrap any expression.
+func (ev *Evaluator) builtinDump(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: "dump() takes exactly 1 argument"}
+	}
+	ev.outMu.Lock()
+	fmt.Fprintln(ev.errOutput, dumpLine(args[0]))
+	ev.outMu.Unlock()
+	return args[0], true, nil
+}
+
+// dumpLine renders v for dump(). Arrays and maps get a size header, e.g.
+// `array[2] = [int(1), str("a")]`; other values are just annotated.
+func dumpLine(v *Value) string {
+	switch v.Kind {
+	case ValArray:
+		return fmt.Sprintf("array[%d] = %s", len(v.Array), annotate(v))
+	case ValMap:
+		return fmt.Sprintf("map[%d] = %s", v.Map.Len(), annotate(v))
+	default:
+		return annotate(v)
+	}
+}
+
+// annotate renders v with its kind spelled out at every level: scalars as
+// kind(value), strings quoted, arrays as [...] and maps as map{...}.
+func annotate(v *Value) string {
+	switch v.Kind {
+	case ValStr:
+		return "str(" + strconv.Quote(v.Str) + ")"
+	case ValNil:
+		return "nil"
+	case ValArray:
+		parts := make([]string, len(v.Array))
+		for i, elem := range v.Array {
+			parts[i] = annotate(elem)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case ValMap:
+		parts := make([]string, 0, v.Map.Len())
+		for _, k := range v.Map.Keys() {
+			val, _ := v.Map.Get(k)
+			parts = append(parts, keyText(k)+": "+annotate(val))
+		}
+		return "map{" + strings.Join(parts, ", ") + "}"
+	case ValOk, ValErr:
+		return v.Kind.String() + "(" + annotate(v.Inner) + ")"
+	case ValFn:
+		return "fn(" + v.Fn.Name + ")"
+	case ValMutex, ValFuture, ValChannel, ValLazy, ValIter, ValPool:
+		return v.Kind.String()
+	default:
+		return v.Kind.String() + "(" + v.String() + ")"
+	}
+}
@@ -1,6 +1,10 @@
 package eval
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
 
 // Binding holds a named value with const/forgiven metadata.
 type Binding struct {
@@ -9,8 +13,17 @@ type Binding struct {
 	Forgiven bool
 }
 
-// Env is a lexical scope with an optional parent.
+// ConstAssignError is returned by Set when the target is an unforgiven const.
+type ConstAssignError struct {
+	Name string
+}
+
+func (e *ConstAssignError) Error() string { return "cannot reassign const: " + e.Name }
+
+// Env is a lexical scope with an optional parent. It is safe for use by
+// concurrently spawned tasks.
 type Env struct {
+	mu       sync.RWMutex
 	bindings map[string]*Binding
 	parent   *Env
 }
@@ -25,13 +38,22 @@ func NewEnv(parent *Env) *Env {
 
 // Define creates a new binding in the current scope. spec:SEC-4-3
 func (e *Env) Define(name string, val *Value, isConst bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.bindings[name] = &Binding{Value: val, IsConst: isConst}
 }
 
 // Get looks up a binding by name, walking the scope chain.
 func (e *Env) Get(name string) (*Value, error) {
-	if b, ok := e.bindings[name]; ok {
-		return b.Value, nil
+	e.mu.RLock()
+	b, ok := e.bindings[name]
+	var val *Value
+	if ok {
+		val = b.Value
+	}
+	e.mu.RUnlock()
+	if ok {
+		return val, nil
 	}
 	if e.parent != nil {
 		return e.parent.Get(name)
@@ -41,13 +63,16 @@ func (e *Env) Get(name string) (*Value, error) {
 
 // Set updates an existing binding. Returns error if const and not forgiven.
 func (e *Env) Set(name string, val *Value) error {
+	e.mu.Lock()
 	if b, ok := e.bindings[name]; ok {
+		defer e.mu.Unlock()
 		if b.IsConst && !b.Forgiven {
-			return fmt.Errorf("cannot reassign const: %s", name)
+			return &ConstAssignError{Name: name}
 		}
 		b.Value = val
 		return nil
 	}
+	e.mu.Unlock()
 	if e.parent != nil {
 		return e.parent.Set(name, val)
 	}
@@ -57,9 +82,47 @@ func (e *Env) Set(name string, val *Value) error {
 // Forgive marks a const binding as forgiven so it can be reassigned.
 // Only searches the current scope — sorry() must be called in the same scope. spec:SEC-4-4
 func (e *Env) Forgive(name string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if b, ok := e.bindings[name]; ok {
 		b.Forgiven = true
 		return nil
 	}
 	return fmt.Errorf("sorry: %s not found in current scope", name)
 }
+
+// ForgiveDeep is like Forgive but walks the scope chain to forgive the
+// binding where it is actually defined. Used under decree "deep_sorry".
+func (e *Env) ForgiveDeep(name string) error {
+	for env := e; env != nil; env = env.parent {
+		env.mu.Lock()
+		b, ok := env.bindings[name]
+		if ok {
+			b.Forgiven = true
+		}
+		env.mu.Unlock()
+		if ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("sorry: %s not found in any enclosing scope", name)
+}
+
+// Names returns every name visible from this scope, walking the scope chain,
+// in sorted order. A shadowed name appears once.
+func (e *Env) Names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for env := e; env != nil; env = env.parent {
+		env.mu.RLock()
+		for name := range env.bindings {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		env.mu.RUnlock()
+	}
+	sort.Strings(names)
+	return names
+}
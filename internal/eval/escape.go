@@ -0,0 +1,34 @@
+package eval
+
+import (
+	"bytes"
+	"html"
+	"strings"
+)
+
+// builtinEscape implements escape_html, escape_shell and escape_json, which
+// make a string safe to paste into generated HTML, shell commands and JSON.
+func (ev *Evaluator) builtinEscape(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValStr {
+		return nil, true, &DoomError{Message: name + "() takes exactly 1 string argument"}
+	}
+	s := args[0].Str
+	switch name {
+	case "escape_html":
+		return StrVal(html.EscapeString(s)), true, nil
+	case "escape_shell":
+		return StrVal(shellQuote(s)), true, nil
+	default: // escape_json
+		var buf bytes.Buffer
+		writeJSONString(&buf, s)
+		quoted := buf.String()
+		return StrVal(quoted[1 : len(quoted)-1]), true, nil
+	}
+}
+
+// shellQuote wraps s in single quotes, inside which a POSIX shell treats
+// every character literally. An embedded ' closes the quote, adds an escaped
+// quote and reopens it.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
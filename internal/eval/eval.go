@@ -1,13 +1,19 @@
 package eval
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/joeabbey/morgoth/internal/lexer"
 	"github.com/joeabbey/morgoth/internal/parser"
 )
 
@@ -16,6 +22,7 @@ import (
 // DoomError is a non-local exit (like an exception).
 type DoomError struct {
 	Message string
+	Payload *Value // set by doom(msg, payload); nil otherwise
 }
 
 func (e *DoomError) Error() string { return "doom: " + e.Message }
@@ -41,6 +48,13 @@ type GuardReturnSignal struct {
 
 func (e *GuardReturnSignal) Error() string { return "guard return" }
 
+// ParseError aggregates the lexer and parser errors from EvalString.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string { return "parse error: " + strings.Join(e.Errors, "; ") }
+
 // SigilDef stores a sigil macro definition for later invocation.
 type SigilDef struct {
 	Name   string
@@ -50,19 +64,38 @@ type SigilDef struct {
 
 // Evaluator walks the AST and produces values.
 type Evaluator struct {
-	env     *Env
-	decrees *DecreeConfig
-	output  io.Writer
-	sigils  map[string]*SigilDef
+	env       *Env
+	decrees   *DecreeConfig
+	output    io.Writer
+	errOutput io.Writer
+	sigils    map[string]*SigilDef
+	now       func() time.Time
+	sleep     func(time.Duration)
+	tasks     *taskGroup  // spawned tasks, shared with forks
+	outMu     *sync.Mutex // serializes speak output across tasks
+	debugger  Debugger    // nil unless stepping under a debugger
+	chants    *sync.Map   // names passed to chant, shared with forks
+	ids       *idSource   // randomness for uuid() and ulid(), shared with forks
+	// interp is held by whichever task is evaluating, so tasks never touch
+	// shared arrays, maps or scopes at the same time. See blocking.
+	interp *sync.Mutex
 }
 
 // New creates a new Evaluator with default settings.
 func New() *Evaluator {
 	return &Evaluator{
-		env:     NewEnv(nil),
-		decrees: NewDecreeConfig(),
-		output:  os.Stdout,
-		sigils:  make(map[string]*SigilDef),
+		env:       NewEnv(nil),
+		decrees:   NewDecreeConfig(),
+		output:    os.Stdout,
+		errOutput: os.Stderr,
+		sigils:    make(map[string]*SigilDef),
+		now:       time.Now,
+		sleep:     time.Sleep,
+		tasks:     &taskGroup{},
+		outMu:     &sync.Mutex{},
+		chants:    &sync.Map{},
+		ids:       newIDSource(time.Now().UnixNano()),
+		interp:    &sync.Mutex{},
 	}
 }
 
@@ -71,8 +104,34 @@ func (ev *Evaluator) SetOutput(w io.Writer) {
 	ev.output = w
 }
 
+// SetErrOutput sets the writer for speak ... to "stderr" output.
+func (ev *Evaluator) SetErrOutput(w io.Writer) {
+	ev.errOutput = w
+}
+
+// SetClock replaces the clock consulted by weekday indexing (useful for testing).
+func (ev *Evaluator) SetClock(now func() time.Time) {
+	ev.now = now
+}
+
+// SetSleep replaces the timer used by sleep() (useful for testing).
+func (ev *Evaluator) SetSleep(sleep func(time.Duration)) {
+	ev.sleep = sleep
+}
+
 // Eval evaluates a complete program. spec:SEC-4 spec:SEC-7
 func (ev *Evaluator) Eval(program *parser.Program) (*Value, error) {
+	ev.interp.Lock()
+	ev.tasks.runnable++
+	result, err := ev.evalItems(program)
+	ev.tasks.exit()
+	ev.interp.Unlock()
+	// Let spawned tasks finish before the program is considered done.
+	ev.tasks.wg.Wait()
+	return result, err
+}
+
+func (ev *Evaluator) evalItems(program *parser.Program) (*Value, error) {
 	var result *Value
 	for _, item := range program.Items {
 		val, err := ev.evalItem(item)
@@ -97,7 +156,35 @@ func (ev *Evaluator) Eval(program *parser.Program) (*Value, error) {
 	return result, nil
 }
 
+// EvalString lexes, parses and evaluates src in the evaluator's persistent
+// environment, so bindings from earlier calls remain visible (as in the REPL).
+// Parse failures are returned as a *ParseError and nothing is evaluated.
+func (ev *Evaluator) EvalString(src string) (*Value, error) {
+	p := parser.New(lexer.New(src))
+	program := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, &ParseError{Errors: errs}
+	}
+	return ev.Eval(program)
+}
+
+// RunString is EvalString with speak output captured: it returns everything
+// src wrote to stdout alongside the result, including output written before
+// a doom. The previous output writer is restored afterwards; stderr output
+// is not captured.
+func (ev *Evaluator) RunString(src string) (output string, result *Value, err error) {
+	var buf bytes.Buffer
+	saved := ev.output
+	ev.output = &buf
+	defer func() { ev.output = saved }()
+	result, err = ev.EvalString(src)
+	return buf.String(), result, err
+}
+
 func (ev *Evaluator) evalItem(item parser.Item) (*Value, error) {
+	if err := ev.debugStep(item); err != nil {
+		return nil, err
+	}
 	switch n := item.(type) {
 	case *parser.FnDecl:
 		return ev.evalFnDecl(n)
@@ -143,51 +230,71 @@ func (ev *Evaluator) evalItem(item parser.Item) (*Value, error) {
 
 // --- Statement evaluation ---
 
-func (ev *Evaluator) evalFnDecl(decl *parser.FnDecl) (*Value, error) {
-	params := make([]string, len(decl.Params))
-	for i, p := range decl.Params {
-		params[i] = p.Name
+// paramList splits parsed parameters into the names and destructuring
+// patterns stored on a FnValue.
+func paramList(params []parser.Param) ([]string, []parser.Pattern) {
+	names := make([]string, len(params))
+	var patterns []parser.Pattern
+	for i, p := range params {
+		names[i] = p.Name
+		if p.Pattern != nil {
+			if patterns == nil {
+				patterns = make([]parser.Pattern, len(params))
+			}
+			patterns[i] = p.Pattern
+		}
 	}
+	return names, patterns
+}
+
+func (ev *Evaluator) evalFnDecl(decl *parser.FnDecl) (*Value, error) {
+	params, patterns := paramList(decl.Params)
 	fn := &FnValue{
-		Name:   decl.Name,
-		Params: params,
-		Body:   decl.Body,
-		Env:    ev.env,
+		Name:     decl.Name,
+		Params:   params,
+		Patterns: patterns,
+		Body:     decl.Body,
+		Env:      ev.env,
 	}
 	ev.env.Define(decl.Name, FnVal(fn), false)
 	return NilVal(), nil
 }
 
 func (ev *Evaluator) evalFnLitExpr(expr *parser.FnLitExpr) (*Value, error) {
-	params := make([]string, len(expr.Params))
-	for i, p := range expr.Params {
-		params[i] = p.Name
-	}
+	params, patterns := paramList(expr.Params)
 	fn := &FnValue{
-		Name:   "<anonymous>",
-		Params: params,
-		Body:   expr.Body,
-		Env:    ev.env,
+		Name:     "<anonymous>",
+		Params:   params,
+		Patterns: patterns,
+		Body:     expr.Body,
+		Env:      ev.env,
 	}
 	return FnVal(fn), nil
 }
 
+// evalLetStmt binds the value and also returns it, so the REPL echoes
+// `let x = 5`. Blocks ignore statement results, so their value is unchanged.
 func (ev *Evaluator) evalLetStmt(stmt *parser.LetStmt) (*Value, error) {
 	val, err := ev.evalExpr(stmt.Value)
 	if err != nil {
 		return nil, err
 	}
 	ev.env.Define(stmt.Name, val, false)
-	return NilVal(), nil
+	return val, nil
 }
 
 func (ev *Evaluator) evalConstStmt(stmt *parser.ConstStmt) (*Value, error) {
+	if ev.decrees.ConstPurity {
+		if err := ev.checkConstPurity(stmt); err != nil {
+			return nil, err
+		}
+	}
 	val, err := ev.evalExpr(stmt.Value)
 	if err != nil {
 		return nil, err
 	}
 	ev.env.Define(stmt.Name, val, true)
-	return NilVal(), nil
+	return val, nil
 }
 
 func (ev *Evaluator) evalReturnStmt(stmt *parser.ReturnStmt) (*Value, error) {
@@ -200,11 +307,18 @@ func (ev *Evaluator) evalReturnStmt(stmt *parser.ReturnStmt) (*Value, error) {
 
 // spec:SEC-6-2
 func (ev *Evaluator) evalDecreeStmt(stmt *parser.DecreeStmt) (*Value, error) {
+	if stmt.Revoke {
+		ev.decrees.Revoke(stmt.Value)
+		return NilVal(), nil
+	}
 	ev.decrees.Apply(stmt.Value)
 	return NilVal(), nil
 }
 
 func (ev *Evaluator) evalStmt(stmt parser.Stmt) (*Value, error) {
+	if err := ev.debugStep(stmt); err != nil {
+		return nil, err
+	}
 	switch n := stmt.(type) {
 	case *parser.LetStmt:
 		return ev.evalLetStmt(n)
@@ -268,6 +382,8 @@ func (ev *Evaluator) evalExpr(expr parser.Expr) (*Value, error) {
 		return ev.evalMatchExpr(n)
 	case *parser.GuardExpr:
 		return ev.evalGuardExpr(n)
+	case *parser.ForExpr:
+		return ev.evalForExpr(n)
 	case *parser.BlockExpr:
 		return ev.evalBlockExpr(n)
 	case *parser.OkExpr:
@@ -286,16 +402,14 @@ func (ev *Evaluator) evalExpr(expr parser.Expr) (*Value, error) {
 		return ev.evalChantExpr(n)
 	case *parser.FnLitExpr:
 		return ev.evalFnLitExpr(n)
-	case *parser.SpawnExpr: // spec:SEC-6-1
-		// MVP stub: run spawn body synchronously, return nil.
-		_, err := ev.evalBlockExpr(n.Body)
-		if err != nil {
-			return nil, err
-		}
-		return NilVal(), nil
+	case *parser.SpawnExpr:
+		return ev.evalSpawnExpr(n)
 	case *parser.AwaitAllExpr:
-		// MVP stub: no-op since spawn runs synchronously.
-		return NilVal(), nil
+		return ev.evalAwaitAllExpr(n)
+	case *parser.SelectExpr:
+		return ev.evalSelectExpr(n)
+	case *parser.LazyExpr:
+		return ev.evalLazyExpr(n)
 	case *parser.InvokeExpr:
 		return ev.evalInvokeExpr(n)
 	case *parser.AlignExpr:
@@ -364,13 +478,24 @@ func (ev *Evaluator) evalMapLitExpr(expr *parser.MapLitExpr) (*Value, error) {
 		if err != nil {
 			return nil, err
 		}
-		m.Set(key.String(), val)
+		k, err := MapKey(key)
+		if err != nil {
+			return nil, &DoomError{Message: err.Error()}
+		}
+		m.Set(k, val)
 	}
 	return MapVal(m), nil
 }
 
 // spec:SEC-3-1 spec:SEC-4-6
 func (ev *Evaluator) evalBinaryExpr(expr *parser.BinaryExpr) (*Value, error) {
+	if expr.Op == "==" && ev.decrees.AmbitiousMode {
+		switch expr.Left.(type) {
+		case *parser.IndexExpr, *parser.DotExpr:
+			return ev.evalAmbitiousEq(expr)
+		}
+	}
+
 	left, err := ev.evalExpr(expr.Left)
 	if err != nil {
 		return nil, err
@@ -378,13 +503,13 @@ func (ev *Evaluator) evalBinaryExpr(expr *parser.BinaryExpr) (*Value, error) {
 
 	// Short-circuit for logical operators
 	if expr.Op == "and" {
-		if !left.IsTruthy() {
+		if !ev.truthy(left) {
 			return left, nil
 		}
 		return ev.evalExpr(expr.Right)
 	}
 	if expr.Op == "or" {
-		if left.IsTruthy() {
+		if ev.truthy(left) {
 			return left, nil
 		}
 		return ev.evalExpr(expr.Right)
@@ -395,6 +520,12 @@ func (ev *Evaluator) evalBinaryExpr(expr *parser.BinaryExpr) (*Value, error) {
 		return nil, err
 	}
 
+	if left.Kind == ValInt && right.Kind == ValInt && !ev.decrees.ArbitraryPrecision {
+		if val, ok, err := ev.intFastPath(left.Int, right.Int, expr.Op); ok {
+			return val, err
+		}
+	}
+
 	switch expr.Op {
 	case "+":
 		return ev.evalAdd(left, right)
@@ -404,53 +535,21 @@ func (ev *Evaluator) evalBinaryExpr(expr *parser.BinaryExpr) (*Value, error) {
 		return ev.evalArith(left, right, "*")
 	case "/":
 		return ev.evalArith(left, right, "/")
+	case "//":
+		return ev.evalArith(left, right, "//")
 	case "%":
 		return ev.evalArith(left, right, "%")
 	case "==":
-		if ev.decrees.AmbitiousMode && right.IsTruthy() {
-			switch lhs := expr.Left.(type) {
-			case *parser.IdentExpr:
-				if err := ev.env.Set(lhs.Name, right); err != nil {
-					return nil, &DoomError{Message: err.Error()}
-				}
-				return right, nil
-			case *parser.IndexExpr:
-				collection, err := ev.evalExpr(lhs.Left)
-				if err != nil {
-					return nil, err
-				}
-				index, err := ev.evalExpr(lhs.Index)
-				if err != nil {
-					return nil, err
-				}
-				switch collection.Kind {
-				case ValArray:
-					if index.Kind == ValInt {
-						idx := ev.adjustIndex(index.Int)
-						if idx >= 0 && idx < int64(len(collection.Array)) {
-							collection.Array[idx] = right
-						}
-					}
-				case ValMap:
-					collection.Map.Set(index.String(), right)
-				}
-				return right, nil
-			case *parser.DotExpr:
-				obj, err := ev.evalExpr(lhs.Left)
-				if err != nil {
-					return nil, err
-				}
-				if obj.Kind == ValMap {
-					obj.Map.Set(lhs.Field, right)
-				}
-				return right, nil
+		if ev.decrees.AmbitiousMode && ev.truthy(right) {
+			if lhs, ok := expr.Left.(*parser.IdentExpr); ok {
+				return ev.assign(lhs.Name, right)
 			}
 		}
-		return BoolVal(ev.valuesEqual(left, right)), nil
+		return BoolVal(left.Equal(right)), nil
 	case "===":
 		return BoolVal(ev.valuesStrictEqual(left, right)), nil
 	case "!=":
-		return BoolVal(!ev.valuesEqual(left, right)), nil
+		return BoolVal(!left.Equal(right)), nil
 	case "<":
 		return ev.evalCompare(left, right, "<")
 	case ">":
@@ -464,22 +563,110 @@ func (ev *Evaluator) evalBinaryExpr(expr *parser.BinaryExpr) (*Value, error) {
 	}
 }
 
+// evalAmbitiousEq handles == under ambitious_mode when the left side is an
+// index or field access. The collection and index are evaluated exactly once
+// and shared between the comparison and the assignment, so side effects in
+// either subexpression run a single time.
+func (ev *Evaluator) evalAmbitiousEq(expr *parser.BinaryExpr) (*Value, error) {
+	var collection, index, left *Value
+	var err error
+	switch lhs := expr.Left.(type) {
+	case *parser.IndexExpr:
+		if collection, err = ev.evalExpr(lhs.Left); err != nil {
+			return nil, err
+		}
+		if index, err = ev.evalExpr(lhs.Index); err != nil {
+			return nil, err
+		}
+		if left, err = ev.indexValue(collection, index); err != nil {
+			return nil, err
+		}
+	case *parser.DotExpr:
+		if collection, err = ev.evalExpr(lhs.Left); err != nil {
+			return nil, err
+		}
+		if left, err = fieldValue(collection, lhs.Field); err != nil {
+			return nil, err
+		}
+		index = StrVal(lhs.Field)
+	}
+
+	right, err := ev.evalExpr(expr.Right)
+	if err != nil {
+		return nil, err
+	}
+	if !ev.truthy(right) {
+		return BoolVal(left.Equal(right)), nil
+	}
+
+	switch collection.Kind {
+	case ValArray:
+		if index.Kind == ValInt {
+			idx := ev.adjustIndex(index.Int)
+			if idx >= 0 && idx < int64(len(collection.Array)) {
+				collection.Array[idx] = right
+			}
+		}
+	case ValMap:
+		key, err := MapKey(index)
+		if err != nil {
+			return nil, &DoomError{Message: err.Error()}
+		}
+		collection.Map.Set(key, right)
+	}
+	return right, nil
+}
+
 func (ev *Evaluator) evalAdd(left, right *Value) (*Value, error) {
 	if left.Kind == ValStr || right.Kind == ValStr {
 		return StrVal(left.String() + right.String()), nil
 	}
+	if useRat(left, right) {
+		return ev.evalRatArith(left, right, "+")
+	}
+	if useDecimal(left, right) {
+		return ev.evalDecimalArith(left, right, "+")
+	}
+	if useComplex(left, right) {
+		return ev.evalComplexArith(left, right, "+")
+	}
+	if ev.useBig(left, right) {
+		return ev.evalBigArith(left, right, "+")
+	}
 	if left.Kind == ValFloat || right.Kind == ValFloat {
 		lf := toFloat(left)
 		rf := toFloat(right)
 		return FloatVal(lf + rf), nil
 	}
 	if left.Kind == ValInt && right.Kind == ValInt {
-		return IntVal(left.Int + right.Int), nil
+		return ev.checkedIntArith(left.Int, right.Int, "+")
 	}
 	return nil, &DoomError{Message: fmt.Sprintf("cannot add %v and %v", left.Kind, right.Kind)}
 }
 
+// evalArith applies a binary arithmetic operator. Besides the source-level
+// operators it accepts "%%", floored modulo, which is only reachable through
+// floor_mod().
 func (ev *Evaluator) evalArith(left, right *Value, op string) (*Value, error) {
+	if op == "/" && ev.decrees.TrueDivision && isIntegral(left) && isIntegral(right) {
+		rf := toFloat(right)
+		if rf == 0 {
+			return nil, &DoomError{Message: "division by zero"}
+		}
+		return FloatVal(toFloat(left) / rf), nil
+	}
+	if useRat(left, right) {
+		return ev.evalRatArith(left, right, op)
+	}
+	if useDecimal(left, right) {
+		return ev.evalDecimalArith(left, right, op)
+	}
+	if useComplex(left, right) {
+		return ev.evalComplexArith(left, right, op)
+	}
+	if ev.useBig(left, right) {
+		return ev.evalBigArith(left, right, op)
+	}
 	if left.Kind == ValFloat || right.Kind == ValFloat {
 		lf := toFloat(left)
 		rf := toFloat(right)
@@ -493,32 +680,157 @@ func (ev *Evaluator) evalArith(left, right *Value, op string) (*Value, error) {
 				return nil, &DoomError{Message: "division by zero"}
 			}
 			return FloatVal(lf / rf), nil
-		case "%":
+		case "//":
+			if rf == 0 {
+				return nil, &DoomError{Message: "division by zero"}
+			}
+			return FloatVal(math.Floor(lf / rf)), nil
+		case "%", "%%":
 			return nil, &DoomError{Message: "modulo on floats not supported"}
 		}
 	}
 	if left.Kind == ValInt && right.Kind == ValInt {
 		switch op {
-		case "-":
-			return IntVal(left.Int - right.Int), nil
-		case "*":
-			return IntVal(left.Int * right.Int), nil
-		case "/":
+		case "-", "*":
+			return ev.checkedIntArith(left.Int, right.Int, op)
+		case "/", "//":
 			if right.Int == 0 {
 				return nil, &DoomError{Message: "division by zero"}
 			}
-			return IntVal(left.Int / right.Int), nil
+			return ev.checkedIntArith(left.Int, right.Int, op)
 		case "%":
 			if right.Int == 0 {
 				return nil, &DoomError{Message: "division by zero"}
 			}
 			return IntVal(left.Int % right.Int), nil
+		case "%%":
+			if right.Int == 0 {
+				return nil, &DoomError{Message: "division by zero"}
+			}
+			r := left.Int % right.Int
+			if r != 0 && (r < 0) != (right.Int < 0) {
+				r += right.Int
+			}
+			return IntVal(r), nil
 		}
 	}
 	return nil, &DoomError{Message: fmt.Sprintf("cannot perform %s on %v and %v", op, left.Kind, right.Kind)}
 }
 
+// intFastPath evaluates the common int-int operators without going through
+// evalAdd, evalArith and evalCompare. It reports ok=false for anything those
+// helpers treat specially (true division, ambitious ==), leaving it to them;
+// results must stay identical to the general path.
+func (ev *Evaluator) intFastPath(a, b int64, op string) (val *Value, ok bool, err error) {
+	switch op {
+	case "+", "-", "*":
+		val, err = ev.checkedIntArith(a, b, op)
+		return val, true, err
+	case "/":
+		if ev.decrees.TrueDivision {
+			return nil, false, nil
+		}
+		fallthrough
+	case "//":
+		if b == 0 {
+			return nil, true, &DoomError{Message: "division by zero"}
+		}
+		val, err = ev.checkedIntArith(a, b, op)
+		return val, true, err
+	case "%":
+		if b == 0 {
+			return nil, true, &DoomError{Message: "division by zero"}
+		}
+		return IntVal(a % b), true, nil
+	case "<":
+		return BoolVal(a < b), true, nil
+	case ">":
+		return BoolVal(a > b), true, nil
+	case "<=":
+		return BoolVal(a <= b), true, nil
+	case ">=":
+		return BoolVal(a >= b), true, nil
+	case "==":
+		if ev.decrees.AmbitiousMode {
+			return nil, false, nil
+		}
+		return BoolVal(a == b), true, nil
+	case "!=":
+		return BoolVal(a != b), true, nil
+	}
+	return nil, false, nil
+}
+
+// checkedIntArith performs int64 +, -, *, / or // and dooms on overflow unless
+// decree "wrapping_math" asks for two's-complement wraparound.
+func (ev *Evaluator) checkedIntArith(a, b int64, op string) (*Value, error) {
+	var r int64
+	var overflow bool
+	switch op {
+	case "+":
+		r = a + b
+		overflow = (a >= 0) == (b >= 0) && (r >= 0) != (a >= 0)
+	case "-":
+		r = a - b
+		overflow = (a >= 0) != (b >= 0) && (r >= 0) != (a >= 0)
+	case "*":
+		r = a * b
+		overflow = a != 0 && (r/a != b || a == -1 && b == math.MinInt64)
+	case "/":
+		r = a / b
+		overflow = a == math.MinInt64 && b == -1
+	case "//":
+		r = a / b
+		if a%b != 0 && (a < 0) != (b < 0) {
+			r--
+		}
+		overflow = a == math.MinInt64 && b == -1
+	}
+	if overflow && !ev.decrees.WrappingMath {
+		return nil, &DoomError{Message: fmt.Sprintf("integer overflow: %d %s %d", a, op, b)}
+	}
+	return IntVal(r), nil
+}
+
 func (ev *Evaluator) evalCompare(left, right *Value, op string) (*Value, error) {
+	if left.Kind == ValComplex || right.Kind == ValComplex {
+		return nil, &DoomError{Message: "complex numbers are not ordered"}
+	}
+	if left.Kind == ValNil || right.Kind == ValNil {
+		return nil, &DoomError{Message: fmt.Sprintf("nil is not ordered: cannot evaluate %s %s %s; use == to test for nil", left.String(), op, right.String())}
+	}
+	if useDecimal(left, right) {
+		l, r, err := decimalOperands(left, right)
+		if err != nil {
+			return nil, err
+		}
+		c := l.Cmp(r)
+		switch op {
+		case "<":
+			return BoolVal(c < 0), nil
+		case ">":
+			return BoolVal(c > 0), nil
+		case "<=":
+			return BoolVal(c <= 0), nil
+		case ">=":
+			return BoolVal(c >= 0), nil
+		}
+	}
+	if left.Kind == ValBigInt || right.Kind == ValBigInt || useRat(left, right) {
+		if useRat(left, right) || isIntegral(left) && isIntegral(right) {
+			c := toRat(left).Cmp(toRat(right))
+			switch op {
+			case "<":
+				return BoolVal(c < 0), nil
+			case ">":
+				return BoolVal(c > 0), nil
+			case "<=":
+				return BoolVal(c <= 0), nil
+			case ">=":
+				return BoolVal(c >= 0), nil
+			}
+		}
+	}
 	if left.Kind == ValInt && right.Kind == ValInt {
 		switch op {
 		case "<":
@@ -557,33 +869,75 @@ func (ev *Evaluator) evalCompare(left, right *Value, op string) (*Value, error)
 			return BoolVal(left.Str >= right.Str), nil
 		}
 	}
+	if isResult(left) && isResult(right) {
+		// Every ok orders before every err; results of the same kind order
+		// by their inner values.
+		if left.Kind == right.Kind {
+			return ev.evalCompare(left.Inner, right.Inner, op)
+		}
+		l, r := resultRank(left), resultRank(right)
+		switch op {
+		case "<":
+			return BoolVal(l < r), nil
+		case ">":
+			return BoolVal(l > r), nil
+		case "<=":
+			return BoolVal(l <= r), nil
+		case ">=":
+			return BoolVal(l >= r), nil
+		}
+	}
+	if left.Kind == ValBool && right.Kind == ValBool {
+		// false orders before true.
+		l, r := boolRank(left.Bool), boolRank(right.Bool)
+		switch op {
+		case "<":
+			return BoolVal(l < r), nil
+		case ">":
+			return BoolVal(l > r), nil
+		case "<=":
+			return BoolVal(l <= r), nil
+		case ">=":
+			return BoolVal(l >= r), nil
+		}
+	}
 	return nil, &DoomError{Message: fmt.Sprintf("cannot compare %v and %v", left.Kind, right.Kind)}
 }
 
-func (ev *Evaluator) valuesEqual(a, b *Value) bool {
-	if a.Kind != b.Kind {
+// truthy reports whether v counts as true in a condition. By default that is
+// IsTruthy; under decree "strict_truthiness" only false and nil (and coward
+// values) are falsy, so 0, "" and empty collections count as true.
+func (ev *Evaluator) truthy(v *Value) bool {
+	if !ev.decrees.StrictTruthiness {
+		return v.IsTruthy()
+	}
+	if v.Coward {
 		return false
 	}
-	switch a.Kind {
-	case ValInt:
-		return a.Int == b.Int
-	case ValFloat:
-		return a.Float == b.Float
+	switch v.Kind {
 	case ValBool:
-		return a.Bool == b.Bool
-	case ValStr:
-		return a.Str == b.Str
+		return v.Bool
 	case ValNil:
-		return true
-	case ValOk:
-		return ev.valuesEqual(a.Inner, b.Inner)
-	case ValErr:
-		return ev.valuesEqual(a.Inner, b.Inner)
-	case ValPtr:
-		return a.Int == b.Int
-	default:
 		return false
+	default:
+		return true
+	}
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func isResult(v *Value) bool { return v.Kind == ValOk || v.Kind == ValErr }
+
+func resultRank(v *Value) int {
+	if v.Kind == ValErr {
+		return 1
 	}
+	return 0
 }
 
 func (ev *Evaluator) valuesStrictEqual(a, b *Value) bool {
@@ -607,6 +961,8 @@ func (ev *Evaluator) valuesStrictEqual(a, b *Value) bool {
 		return ev.valuesStrictEqual(a.Inner, b.Inner)
 	case ValPtr:
 		return a.Int == b.Int
+	case ValBigInt, ValRat, ValDecimal, ValComplex:
+		return a.Equal(b)
 	default:
 		// Arrays, Maps, Fns: reference identity (always false for distinct values)
 		return a == b
@@ -619,6 +975,14 @@ func toFloat(v *Value) float64 {
 		return v.Float
 	case ValInt:
 		return float64(v.Int)
+	case ValBigInt:
+		f, _ := new(big.Float).SetInt(v.Big).Float64()
+		return f
+	case ValRat:
+		f, _ := v.Rat.Float64()
+		return f
+	case ValDecimal:
+		return v.Dec.Float64()
 	default:
 		return 0
 	}
@@ -633,14 +997,25 @@ func (ev *Evaluator) evalUnaryExpr(expr *parser.UnaryExpr) (*Value, error) {
 	case "-":
 		switch right.Kind {
 		case ValInt:
-			return IntVal(-right.Int), nil
+			if ev.decrees.ArbitraryPrecision {
+				return normalizeBig(new(big.Int).Neg(big.NewInt(right.Int))), nil
+			}
+			return ev.checkedIntArith(0, right.Int, "-")
+		case ValBigInt:
+			return normalizeBig(new(big.Int).Neg(right.Big)), nil
+		case ValRat:
+			return RatVal(new(big.Rat).Neg(right.Rat)), nil
+		case ValDecimal:
+			return DecimalVal(&Decimal{Unscaled: new(big.Int).Neg(right.Dec.Unscaled), Scale: right.Dec.Scale}), nil
+		case ValComplex:
+			return ComplexVal(-right.Complex), nil
 		case ValFloat:
 			return FloatVal(-right.Float), nil
 		default:
 			return nil, &DoomError{Message: "cannot negate non-numeric value"}
 		}
 	case "!":
-		return BoolVal(!right.IsTruthy()), nil
+		return BoolVal(!ev.truthy(right)), nil
 	case "&":
 		// Address-of operator: for MVP, return a ptr(0)
 		return PtrVal(0), nil
@@ -654,7 +1029,17 @@ func (ev *Evaluator) evalAssignExpr(expr *parser.AssignExpr) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := ev.env.Set(expr.Name, val); err != nil {
+	return ev.assign(expr.Name, val)
+}
+
+// assign rebinds name to val. Under no_forgiveness a const can never be
+// forgiven, so reassigning one yields err(...) like the refused sorry
+// instead of dooming; scripts can detect the locked state.
+func (ev *Evaluator) assign(name string, val *Value) (*Value, error) {
+	if err := ev.env.Set(name, val); err != nil {
+		if _, isConst := err.(*ConstAssignError); isConst && ev.decrees.NoForgiveness {
+			return ErrVal(StrVal(err.Error())), nil
+		}
 		return nil, &DoomError{Message: err.Error()}
 	}
 	return val, nil
@@ -679,14 +1064,17 @@ func (ev *Evaluator) evalIndexAssignExpr(expr *parser.IndexAssignExpr) (*Value,
 		if index.Kind != ValInt {
 			return nil, &DoomError{Message: "array index must be int"}
 		}
-		idx := ev.adjustIndex(index.Int)
-		if idx < 0 || idx >= int64(len(left.Array)) {
-			return nil, &DoomError{Message: fmt.Sprintf("array index out of bounds: %d", idx)}
+		idx, err := ev.resolveIndex(index.Int, len(left.Array), "array")
+		if err != nil {
+			return nil, err
 		}
 		left.Array[idx] = val
 		return val, nil
 	case ValMap:
-		key := index.String()
+		key, err := MapKey(index)
+		if err != nil {
+			return nil, &DoomError{Message: err.Error()}
+		}
 		left.Map.Set(key, val)
 		return val, nil
 	default:
@@ -722,15 +1110,22 @@ func (ev *Evaluator) evalCallExpr(expr *parser.CallExpr) (*Value, error) {
 		args[i] = val
 	}
 
-	// Check for built-in functions by name before evaluating the function expression,
-	// since builtins like len() are not defined in the environment.
+	// Builtins like len() are not defined in the environment, so a bare name
+	// falls back to them only when no binding shadows it; a shadowing
+	// non-function dooms below rather than silently reaching the builtin.
 	if ident, ok := expr.Function.(*parser.IdentExpr); ok {
-		result, isBuiltin, err := ev.callBuiltin(ident.Name, args)
-		if isBuiltin {
-			return result, err
+		if _, err := ev.env.Get(ident.Name); err != nil {
+			result, isBuiltin, err := ev.callBuiltin(ident.Name, args)
+			if isBuiltin {
+				return result, err
+			}
 		}
 	}
 
+	if dot, ok := expr.Function.(*parser.DotExpr); ok && ev.decrees.Methods {
+		return ev.callMethod(dot, args)
+	}
+
 	fn, err := ev.evalExpr(expr.Function)
 	if err != nil {
 		return nil, err
@@ -743,19 +1138,140 @@ func (ev *Evaluator) evalCallExpr(expr *parser.CallExpr) (*Value, error) {
 	return ev.callFunction(fn.Fn, args)
 }
 
+// callMethod calls obj.field(args) under the methods decree. When obj is a map
+// and the field holds a function, the function runs with self bound to obj in
+// a scope between its closure and its parameters, so a parameter named self
+// still wins.
+func (ev *Evaluator) callMethod(dot *parser.DotExpr, args []*Value) (*Value, error) {
+	obj, err := ev.evalExpr(dot.Left)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := fieldValue(obj, dot.Field)
+	if err != nil {
+		return nil, err
+	}
+	if fn.Kind != ValFn {
+		return nil, &DoomError{Message: fmt.Sprintf("cannot call non-function: %s", fn.String())}
+	}
+	bound := *fn.Fn
+	bound.Env = NewEnv(fn.Fn.Env)
+	bound.Env.Define("self", obj, false)
+	bound.Memo = nil // memoize() keys on arguments only and cannot see self
+	return ev.callFunction(&bound, args)
+}
+
 func (ev *Evaluator) callFunction(fn *FnValue, args []*Value) (*Value, error) {
 	// Extern stub: no body, just return nil.
-	if fn.Body == nil {
+	if fn.Body == nil && fn.Chain == nil {
 		return NilVal(), nil
 	}
 
+	if fn.Once != nil {
+		return ev.callOnce(fn)
+	}
+	if fn.Memo != nil {
+		key := memoKey(args)
+		memoMu.Lock()
+		cached, ok := fn.Memo[key]
+		memoMu.Unlock()
+		// The cache keeps its own copy, and every caller gets a fresh one,
+		// so mutating a returned array or map does not change later results.
+		if ok {
+			return cached.Clone(), nil
+		}
+		result, err := ev.invokeFunction(fn, args)
+		if err != nil {
+			return nil, err
+		}
+		memoMu.Lock()
+		fn.Memo[key] = result.Clone()
+		memoMu.Unlock()
+		return result, nil
+	}
+	return ev.invokeFunction(fn, args)
+}
+
+// callChain runs a composed function: the first link gets the call's
+// arguments and each later link gets the previous link's result.
+func (ev *Evaluator) callChain(chain []*FnValue, args []*Value) (*Value, error) {
+	val, err := ev.callFunction(chain[0], args)
+	for _, fn := range chain[1:] {
+		if err != nil {
+			return nil, err
+		}
+		val, err = ev.callFunction(fn, []*Value{val})
+	}
+	return val, err
+}
+
+// callOnce runs a once() function's body on the first call and returns the
+// cached result afterwards. A call that dooms caches nothing, so the next
+// call tries again.
+func (ev *Evaluator) callOnce(fn *FnValue) (*Value, error) {
+	cell := fn.Once
+	// A first call parked in another task may still doom, so check again
+	// after every wake-up.
+	for cell.running {
+		w := newWaiter()
+		cell.waiters = append(cell.waiters, waitReg{w: w})
+		if !ev.park(w) {
+			return nil, deadlockDoom(fn.Name + "()")
+		}
+	}
+	if cell.done {
+		return cell.value, nil
+	}
+	cell.running = true
+	result, err := ev.invokeFunction(fn, nil)
+	cell.running = false
+	if err == nil {
+		cell.value, cell.done = result, true
+	}
+	ev.tasks.wakeAll(cell.waiters, nil)
+	cell.waiters = nil
+	return result, err
+}
+
+// memoMu guards every memoize() cache, since spawned tasks may call the same
+// memoized function concurrently.
+var memoMu sync.Mutex
+
+// memoKey builds a cache key from the canonical key of each argument, so
+// arguments share an entry only when they are equal: 1 and "1" do not.
+func memoKey(args []*Value) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		parts[i] = canonicalKey(a)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func (ev *Evaluator) invokeFunction(fn *FnValue, args []*Value) (*Value, error) {
+	if fn.Chain != nil {
+		if fn.Bound != nil {
+			args = append(append([]*Value(nil), fn.Bound...), args...)
+		}
+		return ev.callChain(fn.Chain, args)
+	}
+
 	callEnv := NewEnv(fn.Env)
 	for i, param := range fn.Params {
+		arg := NilVal()
 		if i < len(args) {
-			callEnv.Define(param, args[i], false)
-		} else {
-			callEnv.Define(param, NilVal(), false)
+			arg = args[i]
+		}
+		if fn.Patterns != nil && fn.Patterns[i] != nil {
+			matched, bindings := ev.matchPattern(fn.Patterns[i], arg)
+			if !matched {
+				return nil, &DoomError{Message: fmt.Sprintf("%s: argument %d does not match its parameter pattern: %s", fn.Name, i+1, arg.String())}
+			}
+			for name, val := range bindings {
+				callEnv.Define(name, val, false)
+			}
+			continue
 		}
+		callEnv.Define(param, arg, false)
 	}
 
 	savedEnv := ev.env
@@ -789,19 +1305,26 @@ func (ev *Evaluator) evalIndexExpr(expr *parser.IndexExpr) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ev.indexValue(left, index)
+}
 
+// indexValue reads left[index] from already-evaluated operands.
+func (ev *Evaluator) indexValue(left, index *Value) (*Value, error) {
 	switch left.Kind {
 	case ValArray:
 		if index.Kind != ValInt {
 			return nil, &DoomError{Message: "array index must be int"}
 		}
-		idx := ev.adjustIndex(index.Int)
-		if idx < 0 || idx >= int64(len(left.Array)) {
-			return nil, &DoomError{Message: fmt.Sprintf("array index out of bounds: %d", idx)}
+		idx, err := ev.resolveIndex(index.Int, len(left.Array), "array")
+		if err != nil {
+			return nil, err
 		}
 		return left.Array[idx], nil
 	case ValMap:
-		key := index.String()
+		key, err := MapKey(index)
+		if err != nil {
+			return nil, &DoomError{Message: err.Error()}
+		}
 		val, ok := left.Map.Get(key)
 		if !ok {
 			return NilVal(), nil
@@ -812,9 +1335,9 @@ func (ev *Evaluator) evalIndexExpr(expr *parser.IndexExpr) (*Value, error) {
 			return nil, &DoomError{Message: "string index must be int"}
 		}
 		runes := []rune(left.Str)
-		idx := ev.adjustIndex(index.Int)
-		if idx < 0 || idx >= int64(len(runes)) {
-			return nil, &DoomError{Message: fmt.Sprintf("string index out of bounds: %d", idx)}
+		idx, err := ev.resolveIndex(index.Int, len(runes), "string")
+		if err != nil {
+			return nil, err
 		}
 		return StrVal(string(runes[idx])), nil
 	default:
@@ -822,6 +1345,19 @@ func (ev *Evaluator) evalIndexExpr(expr *parser.IndexExpr) (*Value, error) {
 	}
 }
 
+// resolveIndex converts a user-facing index into a bounds-checked offset.
+// Errors report the index as the user wrote it, not the adjusted offset.
+func (ev *Evaluator) resolveIndex(index int64, length int, what string) (int64, error) {
+	idx := ev.adjustIndex(index)
+	if index == 0 && idx == -1 {
+		return 0, &DoomError{Message: "one-indexed arrays start at 1"}
+	}
+	if idx < 0 || idx >= int64(length) {
+		return 0, &DoomError{Message: fmt.Sprintf("%s index out of bounds: %d", what, index)}
+	}
+	return idx, nil
+}
+
 // spec:SEC-4-8
 func (ev *Evaluator) adjustIndex(idx int64) int64 {
 	switch ev.decrees.IndexingBase {
@@ -830,8 +1366,7 @@ func (ev *Evaluator) adjustIndex(idx int64) int64 {
 	case "one":
 		return idx - 1
 	case "weekday":
-		weekday := time.Now().Weekday()
-		if weekday == time.Saturday || weekday == time.Sunday {
+		if ev.decrees.Weekend[ev.now().Weekday()] {
 			return idx // 0-based on weekends
 		}
 		return idx - 1 // 1-based on weekdays
@@ -845,14 +1380,19 @@ func (ev *Evaluator) evalDotExpr(expr *parser.DotExpr) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	return fieldValue(left, expr.Field)
+}
+
+// fieldValue reads left.field from an already-evaluated operand.
+func fieldValue(left *Value, field string) (*Value, error) {
 	if left.Kind == ValMap {
-		val, ok := left.Map.Get(expr.Field)
+		val, ok := left.Map.Get(field)
 		if !ok {
 			return NilVal(), nil
 		}
 		return val, nil
 	}
-	return nil, &DoomError{Message: fmt.Sprintf("cannot access field %s on %s", expr.Field, left.String())}
+	return nil, &DoomError{Message: fmt.Sprintf("cannot access field %s on %s", field, left.String())}
 }
 
 // spec:SEC-4-7
@@ -866,21 +1406,34 @@ func (ev *Evaluator) evalPropagateExpr(expr *parser.PropagateExpr) (*Value, erro
 	case ValOk:
 		return inner.Inner, nil
 	case ValErr:
-		return nil, &PropagateError{Value: inner.Inner}
+		return nil, ev.propagate(expr, inner.Inner)
 	case ValNil:
-		return nil, &PropagateError{Value: StrVal("nil")}
+		return nil, ev.propagate(expr, StrVal("nil"))
 	default:
 		return inner, nil
 	}
 }
 
+// propagate builds the error a failed ? carries out. With an else context
+// the error becomes the string "context: error".
+func (ev *Evaluator) propagate(expr *parser.PropagateExpr, val *Value) error {
+	if expr.Context == nil {
+		return &PropagateError{Value: val}
+	}
+	ctx, err := ev.evalExpr(expr.Context)
+	if err != nil {
+		return err
+	}
+	return &PropagateError{Value: StrVal(ctx.String() + ": " + val.String())}
+}
+
 // spec:SEC-3-3
 func (ev *Evaluator) evalIfExpr(expr *parser.IfExpr) (*Value, error) {
 	cond, err := ev.evalExpr(expr.Condition)
 	if err != nil {
 		return nil, err
 	}
-	if cond.IsTruthy() {
+	if ev.truthy(cond) {
 		return ev.evalBlockExpr(expr.Then)
 	}
 	if expr.Else != nil {
@@ -898,6 +1451,9 @@ func (ev *Evaluator) evalIfExpr(expr *parser.IfExpr) (*Value, error) {
 
 // spec:SEC-3-4
 func (ev *Evaluator) evalMatchExpr(expr *parser.MatchExpr) (*Value, error) {
+	if expr.Subject == nil {
+		return ev.evalCondMatch(expr)
+	}
 	subject, err := ev.evalExpr(expr.Subject)
 	if err != nil {
 		return nil, err
@@ -920,6 +1476,24 @@ func (ev *Evaluator) evalMatchExpr(expr *parser.MatchExpr) (*Value, error) {
 	return nil, &DoomError{Message: fmt.Sprintf("match exhausted: no arm matched value %s", subject.String())}
 }
 
+// evalCondMatch runs the first arm of a subject-less match whose condition
+// is truthy; a `_` arm always runs.
+func (ev *Evaluator) evalCondMatch(expr *parser.MatchExpr) (*Value, error) {
+	for _, arm := range expr.Arms {
+		if arm.Cond != nil {
+			cond, err := ev.evalExpr(arm.Cond)
+			if err != nil {
+				return nil, err
+			}
+			if !ev.truthy(cond) {
+				continue
+			}
+		}
+		return ev.evalExpr(arm.Body)
+	}
+	return nil, &DoomError{Message: "match exhausted: no condition was truthy"}
+}
+
 func (ev *Evaluator) matchPattern(pat parser.Pattern, subject *Value) (bool, map[string]*Value) {
 	bindings := make(map[string]*Value)
 
@@ -932,32 +1506,24 @@ func (ev *Evaluator) matchPattern(pat parser.Pattern, subject *Value) (bool, map
 		if err != nil {
 			return false, nil
 		}
-		return ev.valuesEqual(subject, litVal), bindings
+		return subject.Equal(litVal), bindings
 
 	case *parser.IdentPattern:
-		// Check for ok(v) / err(e) patterns
-		if strings.HasPrefix(p.Name, "ok(") && strings.HasSuffix(p.Name, ")") {
-			inner := p.Name[3 : len(p.Name)-1]
-			if subject.Kind != ValOk {
-				return false, nil
-			}
-			if inner != "" {
-				bindings[inner] = subject.Inner
-			}
-			return true, bindings
+		bindings[p.Name] = subject
+		return true, bindings
+
+	case *parser.ResultPattern:
+		want := ValOk
+		if p.Token.Literal == "err" {
+			want = ValErr
 		}
-		if strings.HasPrefix(p.Name, "err(") && strings.HasSuffix(p.Name, ")") {
-			inner := p.Name[4 : len(p.Name)-1]
-			if subject.Kind != ValErr {
-				return false, nil
-			}
-			if inner != "" {
-				bindings[inner] = subject.Inner
-			}
+		if subject.Kind != want {
+			return false, nil
+		}
+		if p.Inner == nil {
 			return true, bindings
 		}
-		bindings[p.Name] = subject
-		return true, bindings
+		return ev.matchPattern(p.Inner, subject.Inner)
 
 	case *parser.TypedPattern:
 		if ev.matchesType(subject, p.TypeName) {
@@ -966,6 +1532,47 @@ func (ev *Evaluator) matchPattern(pat parser.Pattern, subject *Value) (bool, map
 		}
 		return false, nil
 
+	case *parser.ArrayPattern:
+		if subject.Kind != ValArray {
+			return false, nil
+		}
+		n := len(p.Elems)
+		if len(subject.Array) < n || !p.HasRest && len(subject.Array) != n {
+			return false, nil
+		}
+		for i, elem := range p.Elems {
+			matched, inner := ev.matchPattern(elem, subject.Array[i])
+			if !matched {
+				return false, nil
+			}
+			for name, val := range inner {
+				bindings[name] = val
+			}
+		}
+		if p.HasRest {
+			bindings[p.Rest] = ArrayVal(append([]*Value(nil), subject.Array[n:]...))
+		}
+		return true, bindings
+
+	case *parser.MapPattern:
+		if subject.Kind != ValMap {
+			return false, nil
+		}
+		for i, key := range p.Keys {
+			val, ok := subject.Map.Get(key)
+			if !ok {
+				return false, nil
+			}
+			matched, inner := ev.matchPattern(p.Values[i], val)
+			if !matched {
+				return false, nil
+			}
+			for name, v := range inner {
+				bindings[name] = v
+			}
+		}
+		return true, bindings
+
 	case *parser.GuardedPattern:
 		matched, innerBindings := ev.matchPattern(p.Inner, subject)
 		if !matched {
@@ -980,7 +1587,7 @@ func (ev *Evaluator) matchPattern(pat parser.Pattern, subject *Value) (bool, map
 		ev.env = guardEnv
 		guardVal, err := ev.evalExpr(p.Guard)
 		ev.env = savedEnv
-		if err != nil || !guardVal.IsTruthy() {
+		if err != nil || !ev.truthy(guardVal) {
 			return false, nil
 		}
 		return true, innerBindings
@@ -994,6 +1601,26 @@ func (ev *Evaluator) matchesType(val *Value, typeName string) bool {
 	switch typeName {
 	case "int":
 		return val.Kind == ValInt
+	case "bigint":
+		return val.Kind == ValBigInt
+	case "rat":
+		return val.Kind == ValRat
+	case "decimal":
+		return val.Kind == ValDecimal
+	case "complex":
+		return val.Kind == ValComplex
+	case "mutex":
+		return val.Kind == ValMutex
+	case "future":
+		return val.Kind == ValFuture
+	case "channel":
+		return val.Kind == ValChannel
+	case "lazy":
+		return val.Kind == ValLazy
+	case "iter":
+		return val.Kind == ValIter
+	case "pool":
+		return val.Kind == ValPool
 	case "float":
 		return val.Kind == ValFloat
 	case "bool":
@@ -1017,7 +1644,8 @@ func (ev *Evaluator) matchesType(val *Value, typeName string) bool {
 	case "result":
 		return val.Kind == ValOk || val.Kind == ValErr
 	default:
-		return false
+		// Any other name is a struct type, matched by the map's tag.
+		return val.Kind == ValMap && val.Map.Tag() == typeName
 	}
 }
 
@@ -1027,7 +1655,7 @@ func (ev *Evaluator) evalGuardExpr(expr *parser.GuardExpr) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	if !cond.IsTruthy() {
+	if !ev.truthy(cond) {
 		val, err := ev.evalExpr(expr.ElseBody)
 		if err != nil {
 			return nil, err
@@ -1038,6 +1666,42 @@ func (ev *Evaluator) evalGuardExpr(expr *parser.GuardExpr) (*Value, error) {
 	return NilVal(), nil
 }
 
+// evalForExpr runs the body once per array element, string character, map
+// key (in insertion order) or iterator value, binding the loop variable in a
+// fresh scope each time. Iteration order ignores the indexing decrees. The
+// loop itself evaluates to nil; return and guard exits pass through.
+func (ev *Evaluator) evalForExpr(expr *parser.ForExpr) (*Value, error) {
+	coll, err := ev.evalExpr(expr.Iterable)
+	if err != nil {
+		return nil, err
+	}
+	var it *Iterator
+	if coll.Kind == ValMap {
+		keys := coll.Map.Keys()
+		vals := make([]*Value, len(keys))
+		for i, k := range keys {
+			vals[i] = StrVal(keyText(k))
+		}
+		it = sliceIter(vals)
+	} else if it, err = toIterator(coll); err != nil {
+		return nil, err
+	}
+
+	savedEnv := ev.env
+	defer func() { ev.env = savedEnv }()
+	for {
+		item, ok := it.Next()
+		if !ok {
+			return NilVal(), nil
+		}
+		ev.env = NewEnv(savedEnv)
+		ev.env.Define(expr.Var, item, false)
+		if _, err := ev.evalBlockExpr(expr.Body); err != nil {
+			return nil, err
+		}
+	}
+}
+
 func (ev *Evaluator) evalBlockExpr(block *parser.BlockExpr) (*Value, error) {
 	blockEnv := NewEnv(ev.env)
 	savedEnv := ev.env
@@ -1096,10 +1760,17 @@ func (ev *Evaluator) evalAsExpr(expr *parser.AsExpr) (*Value, error) {
 		case ValInt:
 			return left, nil
 		case ValFloat:
+			if ev.decrees.SaturatingCasts {
+				return IntVal(saturateFloat(left.Float)), nil
+			}
 			return IntVal(int64(left.Float)), nil
 		case ValStr:
-			n, err := strconv.ParseInt(strings.TrimSpace(left.Str), 10, 64)
+			n, err := strconv.ParseInt(ungroupDigits(left.Str), 10, 64)
 			if err != nil {
+				// ParseInt already clamps out-of-range input to the int64 bounds.
+				if ev.decrees.SaturatingCasts && errors.Is(err, strconv.ErrRange) {
+					return IntVal(n), nil
+				}
 				if ev.decrees.SoftCasts {
 					return ErrVal(StrVal(fmt.Sprintf("cannot convert %q to int", left.Str))), nil
 				}
@@ -1111,6 +1782,19 @@ func (ev *Evaluator) evalAsExpr(expr *parser.AsExpr) (*Value, error) {
 				return IntVal(1), nil
 			}
 			return IntVal(0), nil
+		case ValBigInt:
+			if ev.decrees.SaturatingCasts {
+				if left.Big.Sign() < 0 {
+					return IntVal(math.MinInt64), nil
+				}
+				return IntVal(math.MaxInt64), nil
+			}
+			// Normalized bigints never fit in int64.
+			msg := fmt.Sprintf("integer overflow: %s does not fit in int", left.String())
+			if ev.decrees.SoftCasts {
+				return ErrVal(StrVal(msg)), nil
+			}
+			return nil, &DoomError{Message: msg}
 		default:
 			msg := fmt.Sprintf("cannot cast %s to int", left.String())
 			if ev.decrees.SoftCasts {
@@ -1122,10 +1806,10 @@ func (ev *Evaluator) evalAsExpr(expr *parser.AsExpr) (*Value, error) {
 		switch left.Kind {
 		case ValFloat:
 			return left, nil
-		case ValInt:
-			return FloatVal(float64(left.Int)), nil
+		case ValInt, ValBigInt, ValRat, ValDecimal:
+			return FloatVal(toFloat(left)), nil
 		case ValStr:
-			f, err := strconv.ParseFloat(strings.TrimSpace(left.Str), 64)
+			f, err := strconv.ParseFloat(ungroupDigits(left.Str), 64)
 			if err != nil {
 				msg := fmt.Sprintf("cannot convert %q to float", left.Str)
 				if ev.decrees.SoftCasts {
@@ -1144,7 +1828,69 @@ func (ev *Evaluator) evalAsExpr(expr *parser.AsExpr) (*Value, error) {
 	case "str", "string":
 		return StrVal(left.String()), nil
 	case "bool":
-		return BoolVal(left.IsTruthy()), nil
+		if left.Kind != ValStr {
+			return BoolVal(ev.truthy(left)), nil
+		}
+		switch strings.ToLower(strings.TrimSpace(left.Str)) {
+		case "true", "1", "yes":
+			return BoolVal(true), nil
+		case "false", "0", "no":
+			return BoolVal(false), nil
+		}
+		msg := fmt.Sprintf("cannot convert %q to bool", left.Str)
+		if ev.decrees.SoftCasts {
+			return ErrVal(StrVal(msg)), nil
+		}
+		return nil, &DoomError{Message: msg}
+	case "array":
+		switch left.Kind {
+		case ValArray:
+			return left, nil
+		case ValStr:
+			elems := make([]*Value, 0, len(left.Str))
+			for _, r := range left.Str {
+				elems = append(elems, StrVal(string(r)))
+			}
+			return ArrayVal(elems), nil
+		case ValMap:
+			entries, _, err := ev.builtinEntries([]*Value{left})
+			return entries, err
+		default:
+			msg := fmt.Sprintf("cannot cast %s to array", left.String())
+			if ev.decrees.SoftCasts {
+				return ErrVal(StrVal(msg)), nil
+			}
+			return nil, &DoomError{Message: msg}
+		}
+	case "map":
+		switch left.Kind {
+		case ValMap:
+			return left, nil
+		case ValArray:
+			m := NewOrderedMap()
+			for i, pair := range left.Array {
+				var msg string
+				if pair.Kind != ValArray || len(pair.Array) != 2 {
+					msg = fmt.Sprintf("cannot cast array to map: element %d is not a [key, value] pair", i)
+				} else if key, err := MapKey(pair.Array[0]); err != nil {
+					msg = fmt.Sprintf("cannot cast array to map: %v", err)
+				} else {
+					m.Set(key, pair.Array[1])
+					continue
+				}
+				if ev.decrees.SoftCasts {
+					return ErrVal(StrVal(msg)), nil
+				}
+				return nil, &DoomError{Message: msg}
+			}
+			return MapVal(m), nil
+		default:
+			msg := fmt.Sprintf("cannot cast %s to map", left.String())
+			if ev.decrees.SoftCasts {
+				return ErrVal(StrVal(msg)), nil
+			}
+			return nil, &DoomError{Message: msg}
+		}
 	default:
 		msg := fmt.Sprintf("unknown cast target: %s", expr.TypeName)
 		if ev.decrees.SoftCasts {
@@ -1154,13 +1900,70 @@ func (ev *Evaluator) evalAsExpr(expr *parser.AsExpr) (*Value, error) {
 	}
 }
 
+// ungroupDigits trims s and removes the _ digit separators numeric literals
+// allow, so "1_000" parses as 1000. An underscore that is not between two
+// digits is left in place, so the parse rejects "_1", "1__0" and "1_.5".
+func ungroupDigits(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "_") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && i > 0 && i < len(s)-1 && isDigitByte(s[i-1]) && isDigitByte(s[i+1]) {
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func isDigitByte(c byte) bool { return c >= '0' && c <= '9' }
+
+// saturateFloat converts f to int64, clamping values beyond the int64 range
+// to its bounds. NaN has no sensible clamp and converts to 0.
+func saturateFloat(f float64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	case f <= math.MinInt64:
+		return math.MinInt64
+	default:
+		return int64(f)
+	}
+}
+
+// evalSpeakExpr writes its values and yields ok(nil), or err(message) when the
+// write fails and there is no else clause, so (speak x)? propagates failures.
 // spec:SEC-5
 func (ev *Evaluator) evalSpeakExpr(expr *parser.SpeakExpr) (*Value, error) {
-	val, err := ev.evalExpr(expr.Value)
-	if err != nil {
-		return nil, err
+	parts := make([]string, len(expr.Values))
+	for i, e := range expr.Values {
+		val, err := ev.evalExpr(e)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = val.String()
+	}
+	w := ev.output
+	if expr.Stream != nil {
+		stream, err := ev.evalExpr(expr.Stream)
+		if err != nil {
+			return nil, err
+		}
+		switch stream.String() {
+		case "stdout":
+		case "stderr":
+			w = ev.errOutput
+		default:
+			return nil, &DoomError{Message: fmt.Sprintf("unknown speak stream: %s", stream.String())}
+		}
 	}
-	_, writeErr := fmt.Fprintln(ev.output, val.String())
+	ev.outMu.Lock()
+	_, writeErr := fmt.Fprintln(w, strings.Join(parts, " "))
+	ev.outMu.Unlock()
 	if writeErr != nil {
 		if expr.ElseBody != nil {
 			return ev.evalExpr(expr.ElseBody)
@@ -1176,7 +1979,13 @@ func (ev *Evaluator) evalDoomExpr(expr *parser.DoomExpr) (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	return nil, &DoomError{Message: msg.String()}
+	var payload *Value
+	if expr.Payload != nil {
+		if payload, err = ev.evalExpr(expr.Payload); err != nil {
+			return nil, err
+		}
+	}
+	return nil, &DoomError{Message: msg.String(), Payload: payload}
 }
 
 // spec:SEC-4-4
@@ -1184,7 +1993,11 @@ func (ev *Evaluator) evalSorryExpr(expr *parser.SorryExpr) (*Value, error) {
 	if ev.decrees.NoForgiveness {
 		return ErrVal(StrVal("no")), nil
 	}
-	if err := ev.env.Forgive(expr.Name); err != nil {
+	forgive := ev.env.Forgive
+	if ev.decrees.DeepSorry {
+		forgive = ev.env.ForgiveDeep
+	}
+	if err := forgive(expr.Name); err != nil {
 		return ErrVal(StrVal(err.Error())), nil
 	}
 	return OkVal(NilVal()), nil
@@ -1192,10 +2005,11 @@ func (ev *Evaluator) evalSorryExpr(expr *parser.SorryExpr) (*Value, error) {
 
 // spec:SEC-5
 func (ev *Evaluator) evalChantExpr(expr *parser.ChantExpr) (*Value, error) {
-	_, err := ev.evalExpr(expr.Name)
+	name, err := ev.evalExpr(expr.Name)
 	if err != nil {
 		return nil, err
 	}
+	ev.chants.Store(name.String(), true)
 	return OkVal(NilVal()), nil
 }
 
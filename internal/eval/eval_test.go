@@ -2,10 +2,13 @@ package eval
 
 import (
 	"bytes"
+	"errors"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/joeabbey/morgoth/internal/lexer"
 	"github.com/joeabbey/morgoth/internal/parser"
@@ -27,6 +30,106 @@ func evalSource(t *testing.T, source string) (stdout string, result *Value, err
 	return buf.String(), result, err
 }
 
+// --- EvalString ---
+
+func TestEvalStringPersistsBindings(t *testing.T) {
+	ev := New()
+	var buf bytes.Buffer
+	ev.SetOutput(&buf)
+	if _, err := ev.EvalString("let x = 40\nfn add(a, b) { a + b }"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	result, err := ev.EvalString("add(x, 2)")
+	if err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+	if result.Kind != ValInt || result.Int != 42 {
+		t.Errorf("got %s, want 42", result.String())
+	}
+}
+
+func TestEvalStringLetYieldsValue(t *testing.T) {
+	ev := New()
+	for _, tt := range []struct {
+		source string
+		want   string
+	}{
+		{"let x = 5", "5"},
+		{"const y = x + 1", "6"},
+		{"let z = { let w = 2; w * 10 }", "20"},
+		{"fn f() { let a = 1; }\nf()", "nil"},
+	} {
+		result, err := ev.EvalString(tt.source)
+		if err != nil {
+			t.Fatalf("source %q: %v", tt.source, err)
+		}
+		if result.String() != tt.want {
+			t.Errorf("source %q: got %s, want %s", tt.source, result.String(), tt.want)
+		}
+	}
+}
+
+func TestEvalStringParseError(t *testing.T) {
+	ev := New()
+	_, err := ev.EvalString("let = 5")
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, got %T: %v", err, err)
+	}
+	if len(pe.Errors) == 0 {
+		t.Error("expected at least one parse error")
+	}
+}
+
+// --- RunString ---
+
+func TestRunStringCapturesOutput(t *testing.T) {
+	ev := New()
+	var prev bytes.Buffer
+	ev.SetOutput(&prev)
+	out, result, err := ev.RunString("speak \"a\"\nspeak 1, 2\n40 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "a\n1 2\n" {
+		t.Errorf("got output %q, want %q", out, "a\n1 2\n")
+	}
+	if result.Kind != ValInt || result.Int != 42 {
+		t.Errorf("got result %s, want 42", result.String())
+	}
+
+	// Bindings persist across calls, each call captures only its own output,
+	// and the original writer is restored.
+	ev.EvalString("let x = 7")
+	out, _, err = ev.RunString("speak x")
+	if err != nil || out != "7\n" {
+		t.Errorf("got %q, %v; want %q", out, err, "7\n")
+	}
+	ev.EvalString("speak \"after\"")
+	if prev.String() != "after\n" {
+		t.Errorf("original writer got %q, want %q", prev.String(), "after\n")
+	}
+}
+
+func TestRunStringErrors(t *testing.T) {
+	out, _, err := New().RunString("speak \"before\"\ndoom(\"boom\")\nspeak \"after\"")
+	if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != "boom" {
+		t.Errorf("got %v, want doom boom", err)
+	}
+	if out != "before\n" {
+		t.Errorf("got output %q, want %q", out, "before\n")
+	}
+
+	out, _, err = New().RunString("speak \"x\"\nlet = 5\nspeak )")
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, got %T: %v", err, err)
+	}
+	if len(pe.Errors) < 2 || out != "" {
+		t.Errorf("got %d parse errors and output %q; want every error and no output", len(pe.Errors), out)
+	}
+}
+
 // --- Arithmetic ---
 
 func TestArithmetic(t *testing.T) {
@@ -79,6 +182,126 @@ func TestComparisons(t *testing.T) {
 		{`speak 3 > 5;`, "false\n"},
 		{`speak 3 >= 3;`, "true\n"},
 		{`speak 3 <= 2;`, "false\n"},
+		{`speak false < true;`, "true\n"},
+		{`speak true < false;`, "false\n"},
+		{`speak true >= true;`, "true\n"},
+		{`speak false > false;`, "false\n"},
+		{`speak nil == nil;`, "true\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestCompareNilDooms(t *testing.T) {
+	for _, src := range []string{`nil < nil`, `1 < nil`, `nil >= "a"`} {
+		_, _, err := evalSource(t, src)
+		doomErr, ok := err.(*DoomError)
+		if !ok || !strings.Contains(doomErr.Message, "nil is not ordered") {
+			t.Errorf("source %q: got %v, want doom about nil ordering", src, err)
+		}
+	}
+	if _, _, err := evalSource(t, `true < 1`); err == nil {
+		t.Error("expected doom comparing bool with int")
+	}
+}
+
+// generalBinary evaluates op on two values the way evalBinaryExpr does
+// without its int fast path.
+func generalBinary(ev *Evaluator, left, right *Value, op string) (*Value, error) {
+	switch op {
+	case "+":
+		return ev.evalAdd(left, right)
+	case "-", "*", "/", "//", "%":
+		return ev.evalArith(left, right, op)
+	case "==":
+		return BoolVal(left.Equal(right)), nil
+	case "!=":
+		return BoolVal(!left.Equal(right)), nil
+	default:
+		return ev.evalCompare(left, right, op)
+	}
+}
+
+func TestIntFastPathMatchesGeneralPath(t *testing.T) {
+	ints := []int64{0, 1, -1, 2, -7, 3, 1 << 40, -(1 << 40), math.MaxInt64, math.MinInt64}
+	ops := []string{"+", "-", "*", "/", "//", "%", "<", ">", "<=", ">=", "==", "!="}
+	for _, decree := range []string{"", "wrapping_math", "true_division", "ambitious_mode"} {
+		ev := New()
+		ev.decrees.Apply(decree)
+		for _, a := range ints {
+			for _, b := range ints {
+				for _, op := range ops {
+					fast, ok, fastErr := ev.intFastPath(a, b, op)
+					if !ok {
+						continue
+					}
+					slow, slowErr := generalBinary(ev, IntVal(a), IntVal(b), op)
+					if (fastErr == nil) != (slowErr == nil) || fastErr != nil && fastErr.Error() != slowErr.Error() {
+						t.Errorf("decree %q: %d %s %d: fast error %v, general error %v", decree, a, op, b, fastErr, slowErr)
+						continue
+					}
+					if fastErr == nil && (fast.Kind != slow.Kind || !fast.Equal(slow)) {
+						t.Errorf("decree %q: %d %s %d: fast %s, general %s", decree, a, op, b, fast, slow)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestIntFastPathDefersToDecrees(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`decree "true_division"; speak 7 / 2`, "3.5\n"},
+		{`decree "arbitrary_precision"; speak 9223372036854775807 + 1`, "9223372036854775808\n"},
+		{`decree "ambitious_mode"; let x = 1; x == 5; speak x`, "5\n"},
+		{`decree "wrapping_math"; speak 9223372036854775807 + 1`, "-9223372036854775808\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func BenchmarkIntArithmetic(b *testing.B) {
+	prog := parser.New(lexer.New(`
+fn fib(n) { if n < 2 { n } else { fib(n - 1) + fib(n - 2) } }
+fib(20)
+`)).Parse()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := New().Eval(prog); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCompareResults(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak ok(99) < err(1)`, "true\n"},
+		{`speak err("a") > ok("z")`, "true\n"},
+		{`speak ok(1) < ok(2), err("b") <= err("a")`, "true false\n"},
+		{`speak sort([err("b"), ok(3), err("a"), ok(1), ok(2)])`, "[ok(1), ok(2), ok(3), err(a), err(b)]\n"},
 	}
 	for _, tt := range tests {
 		out, _, err := evalSource(t, tt.source)
@@ -90,6 +313,11 @@ func TestComparisons(t *testing.T) {
 			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
 		}
 	}
+	for _, src := range []string{`ok(1) < ok("a")`, `ok(1) < 2`, `sort([ok(1), 2])`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
 }
 
 // --- Let / Const / Sorry ---
@@ -229,6 +457,132 @@ greet("");
 	}
 }
 
+// --- for ---
+
+func TestForLoop(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`for x in [1, 2, 3] { speak x * 10 }`, "10\n20\n30\n"},
+		{`for c in "héllo" { speak c }`, "h\né\nl\nl\no\n"},
+		{`for k in { "b": 2, "a": 1, "c": 3 } { speak k }`, "b\na\nc\n"},
+		{`let m = { "x": 1, "y": 2 }; for k in m { speak k + "=" + (m[k] as str) }`, "x=1\ny=2\n"},
+		{`for x in [] { speak x }; for c in "" { speak c }; for k in {} { speak k }; speak "done"`, "done\n"},
+		{`decree "one_indexed"; for x in ["a", "b"] { speak x }`, "a\nb\n"},
+		{`decree "zero_indexed"; for x in ["a", "b"] { speak x }`, "a\nb\n"},
+		{`speak type(for x in [1] { x })`, "nil\n"},
+		{`let total = 0; for x in [1, 2, 3, 4] { total = total + x }; speak total`, "10\n"},
+		{`for i in range(0, 3) { speak i }`, "0\n1\n2\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`for x in 42 { speak x }`, `for x in [1] { }; speak x`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestForLoopFreshScopePerIteration(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "zero_indexed"
+let fns = new_array(3)
+let i = 0
+for x in ["a", "b", "c"] {
+  fns[i] = fn() { x }
+  i = i + 1
+}
+speak fns[0]() + fns[1]() + fns[2]()
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "abc\n" {
+		t.Errorf("got %q, want %q", out, "abc\n")
+	}
+}
+
+func TestForLoopClosuresCaptureTheirIteration(t *testing.T) {
+	// Each closure gets its own i, while a variable declared outside the
+	// loop is still one binding shared by every closure that sees it.
+	out, _, err := evalSource(t, `
+decree "zero_indexed"
+let fns = new_array(3)
+let total = 0
+for i in range(0, 3) {
+  fns[i] = fn() { total = total + i; i }
+}
+speak [fns[0](), fns[1](), fns[2]()]
+speak total
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "[0, 1, 2]\n3\n" {
+		t.Errorf("got %q, want %q", out, "[0, 1, 2]\n3\n")
+	}
+}
+
+func TestForLoopReturnsFromFunction(t *testing.T) {
+	out, _, err := evalSource(t, `
+fn first_big(xs) {
+  for x in xs {
+    if x > 10 { return x }
+  }
+  return -1
+}
+speak first_big([3, 12, 40])
+speak first_big([1, 2])
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "12\n-1\n" {
+		t.Errorf("got %q, want %q", out, "12\n-1\n")
+	}
+}
+
+// --- doom payloads / catch ---
+
+func TestDoomPayload(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`match catch(fn() { doom("bad input", { "code": 42, "field": "age" }) }) { err(e) => speak e["message"], ok(v) => speak v }`, "bad input\n"},
+		{`match catch(fn() { doom("bad input", { "code": 42, "field": "age" }) }) { err(e) => speak e["payload"]["code"], ok(v) => speak v }`, "42\n"},
+		{`match catch(fn() { doom("plain") }) { err(e) => speak e["payload"], ok(v) => speak v }`, "nil\n"},
+		{`speak catch(fn() { 1 + 2 })`, "ok(3)\n"},
+		{`fn check(n) { guard n > 0 else doom("not positive", [n]); n }; match catch(fn() { check(-3) }) { err(e) => speak e["payload"], ok(v) => speak v }`, "[-3]\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	_, _, err := evalSource(t, `doom("boom", { "retry": false })`)
+	doomErr, ok := err.(*DoomError)
+	if !ok || doomErr.Message != "boom" || doomErr.Payload == nil || doomErr.Payload.Kind != ValMap {
+		t.Errorf("got %#v, want DoomError carrying a map payload", err)
+	}
+}
+
 // --- Ok / Err / ? propagation ---
 
 func TestOkErr(t *testing.T) {
@@ -286,6 +640,41 @@ match use() {
 	}
 }
 
+func TestPropagateWithContext(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`fn load() { err("file missing") }
+fn config() { let text = load()? else "loading config"; ok(text) }
+speak config()`, "err(loading config: file missing)\n"},
+		{`fn load() { err("file missing") }
+fn config() { ok(load()? else "loading config") }
+fn start() { let c = config()? else "starting"; ok(c) }
+speak start()`, "err(starting: loading config: file missing)\n"},
+		{`fn name() { nil }
+fn greet() { ok("hi " + name()? else "looking up name") }
+speak greet()`, "err(looking up name: nil)\n"},
+		{`fn load() { ok(7) }
+fn config() { ok(load()? else doom("context is only built on failure")) }
+speak config()`, "ok(7)\n"},
+		{`fn check(r) { guard r? else "guard fallback"; "passed" }
+speak check(ok(true)), check(ok(false))`, "passed guard fallback\n"},
+		{`fn show(r) { speak r? else "speak fallback" }
+speak show(err("x"))`, "err(x)\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
 // --- Match ---
 
 func TestMatchLiteral(t *testing.T) {
@@ -427,6 +816,89 @@ match check(-1) {
 	}
 }
 
+// Control-flow signals must cross every nested block, if and match between
+// them and the function boundary without being swallowed. Loop evaluators are
+// held to the same rule.
+func TestReturnFromNestedBlocks(t *testing.T) {
+	out, _, err := evalSource(t, `
+fn find(x) {
+  let depth = "outer"
+  if x > 0 {
+    let depth = "if"
+    {
+      let depth = "block"
+      match x {
+        2 => { if true { return "two from " + depth } else { 0 } },
+        _ => 0,
+      }
+    }
+  } else { 0 }
+  "fell through at " + depth
+}
+fn check(x) {
+  if x > 0 {
+    {
+      match x {
+        1 => { guard x > 5 else "guarded" },
+        _ => 0,
+      }
+    }
+  } else { 0 }
+  "not guarded"
+}
+speak find(2)
+speak find(1)
+speak check(1)
+speak check(2)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "two from block\nfell through at outer\nguarded\nnot guarded\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestReturnAndGuardFromNestedLoops(t *testing.T) {
+	out, _, err := evalSource(t, `
+fn find_pair(xs, target) {
+  if len(xs) > 0 {
+    for a in xs {
+      for b in xs {
+        if a + b == target {
+          { return [a, b] }
+        }
+      }
+    }
+  } else { 0 }
+  "none"
+}
+fn all_positive(xs) {
+  for x in xs {
+    if x != 0 {
+      for d in [x] { guard d > 0 else "found " + (d as str) }
+    } else { 0 }
+  }
+  "all positive"
+}
+let a = "caller's a"
+speak find_pair([1, 4, 6], 10)
+speak find_pair([1, 2], 10)
+speak find_pair([], 1)
+speak all_positive([3, 0, -2, 5])
+speak all_positive([1, 2])
+speak a
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[4, 6]\nnone\nnone\nfound -2\nall positive\ncaller's a\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
 func TestGuardWithDoomStillDooms(t *testing.T) {
 	_, _, err := evalSource(t, `
 fn check(x) {
@@ -493,6 +965,64 @@ speak result;
 	}
 }
 
+func TestDeepSorryCrossScope(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "deep_sorry"
+const x = 5
+fn forgive_x() {
+  sorry(x)
+}
+speak forgive_x()
+x = 6
+speak x
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "ok(nil)\n6\n" {
+		t.Errorf("got %q, want %q", out, "ok(nil)\n6\n")
+	}
+}
+
+func TestDeepSorryUnknownName(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "deep_sorry"
+fn f() { sorry(ghost) }
+speak f()
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "err(") {
+		t.Errorf("expected err result for unknown name, got %q", out)
+	}
+}
+
+func TestNoForgivenessConstAssignReturnsErr(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "no_forgiveness"
+const x = 5
+speak sorry(x)
+let r = (x = 6)
+speak r
+speak x
+`)
+	if err != nil {
+		t.Fatalf("expected no doom under no_forgiveness, got %v", err)
+	}
+	want := "err(no)\nerr(cannot reassign const: x)\n5\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestConstAssignStillDoomsByDefault(t *testing.T) {
+	_, _, err := evalSource(t, "const x = 5\nx = 6\n")
+	if _, ok := err.(*DoomError); !ok {
+		t.Fatalf("expected *DoomError, got %T: %v", err, err)
+	}
+}
+
 func TestChantEvaluatesArgument(t *testing.T) {
 	_, _, err := evalSource(t, `chant doom("should doom");`)
 	if err == nil {
@@ -573,6 +1103,33 @@ xs[5] = 99
 	}
 }
 
+func TestOneIndexedRejectsZero(t *testing.T) {
+	for _, src := range []string{
+		"decree \"one_indexed\"\nlet xs = [1, 2, 3]\nxs[0] = 9\n",
+		"decree \"one_indexed\"\nlet xs = [1, 2, 3]\nspeak xs[0]\n",
+	} {
+		_, _, err := evalSource(t, src)
+		doomErr, ok := err.(*DoomError)
+		if !ok {
+			t.Fatalf("source %q: expected *DoomError, got %T: %v", src, err, err)
+		}
+		if doomErr.Message != "one-indexed arrays start at 1" {
+			t.Errorf("source %q: got %q", src, doomErr.Message)
+		}
+	}
+}
+
+func TestOutOfBoundsReportsUserIndex(t *testing.T) {
+	_, _, err := evalSource(t, "decree \"one_indexed\"\nlet xs = [1, 2, 3]\nxs[4] = 9\n")
+	doomErr, ok := err.(*DoomError)
+	if !ok {
+		t.Fatalf("expected *DoomError, got %T: %v", err, err)
+	}
+	if doomErr.Message != "array index out of bounds: 4" {
+		t.Errorf("got %q, want %q", doomErr.Message, "array index out of bounds: 4")
+	}
+}
+
 func TestStrictEqualDifferentTypes(t *testing.T) {
 	// === should be false for different types even if values look similar
 	out, _, err := evalSource(t, `speak 1 === 1;`)
@@ -651,6 +1208,45 @@ speak xs[0]
 	}
 }
 
+func TestWeekendSchedule(t *testing.T) {
+	// xs[1] is 20 on a (0-based) weekend day and 10 on a (1-based) weekday.
+	// 2026-10-16 is a Friday, 2026-10-17 a Saturday, 2026-10-19 a Monday.
+	friday := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	saturday := friday.AddDate(0, 0, 1)
+	monday := friday.AddDate(0, 0, 3)
+	tests := []struct {
+		decree string
+		day    time.Time
+		want   string
+	}{
+		{"", saturday, "20\n"},
+		{"", friday, "10\n"},
+		{"decree \"weekend:fri,sat\"\n", friday, "20\n"},
+		{"decree \"weekend:fri,sat\"\n", monday, "10\n"},
+		{"decree \"weekend:wed\"\n", saturday, "10\n"},
+	}
+	for _, tt := range tests {
+		src := tt.decree + "let xs = [10, 20, 30]\nspeak xs[1]\n"
+		p := parser.New(lexer.New(src))
+		prog := p.Parse()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		var buf bytes.Buffer
+		ev := New()
+		ev.SetOutput(&buf)
+		day := tt.day
+		ev.SetClock(func() time.Time { return day })
+		if _, err := ev.Eval(prog); err != nil {
+			t.Errorf("%q on %s: unexpected error: %v", tt.decree, tt.day.Weekday(), err)
+			continue
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%q on %s: got %q, want %q", tt.decree, tt.day.Weekday(), buf.String(), tt.want)
+		}
+	}
+}
+
 func TestReturnAtTopLevel(t *testing.T) {
 	_, _, err := evalSource(t, `return 42;`)
 	if err == nil {
@@ -686,8 +1282,65 @@ func TestDecreeSemicolonInsertion(t *testing.T) {
 	}
 }
 
-func TestMatchTypedNil(t *testing.T) {
-	out, _, err := evalSource(t, `
+func TestUndecree(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"decree \"true_division\"\nspeak 7 / 2\nundecree \"true_division\"\nspeak 7 / 2\n", "3.5\n3\n"},
+		{`decree "soft_casts"; speak "x" as int; undecree "soft_casts"; speak catch(fn() { "x" as int })`, "err(cannot convert \"x\" to int)\nerr({message: cannot convert \"x\" to int, payload: nil})\n"},
+		{`decree "decimal_scale:4"; undecree "decimal_scale"; speak decimal("1.23456")`, "1.23\n"},
+		{`undecree "never_decreed"; speak 1`, "1\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	d := NewDecreeConfig()
+	d.Apply("one_indexed")
+	d.Apply("weekend:fri")
+	d.Revoke("one_indexed")
+	d.Revoke("weekend")
+	if d.IndexingBase != "weekday" || !d.Weekend[time.Saturday] || d.Weekend[time.Friday] {
+		t.Errorf("got %q indexing and weekend %v, want defaults", d.IndexingBase, d.Weekend)
+	}
+}
+
+func TestStrictTruthiness(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak if 0 { "truthy" } else { "falsy" }`, "falsy\n"},
+		{`decree "strict_truthiness"; speak if 0 { "truthy" } else { "falsy" }`, "truthy\n"},
+		{`decree "strict_truthiness"; speak if "" { "truthy" } else { "falsy" }`, "truthy\n"},
+		{`decree "strict_truthiness"; speak if nil { "truthy" } else { "falsy" }`, "falsy\n"},
+		{`decree "strict_truthiness"; speak 0 or 5, nil or 5, !0, !false, [] and 1`, "0 5 false true 1\n"},
+		{`decree "strict_truthiness"; speak 0 as bool, nil as bool, "0" as bool`, "true false false\n"},
+		{`decree "strict_truthiness"; speak coward(1) or "coward"`, "coward\n"},
+		{`decree "strict_truthiness"; undecree "strict_truthiness"; speak 0 or "default"`, "default\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+func TestMatchTypedNil(t *testing.T) {
+	out, _, err := evalSource(t, `
 match nil {
   n: nil => speak "got nil",
   _ => speak "other",
@@ -732,6 +1385,57 @@ match err("bad") {
 	}
 }
 
+func TestMatchStructType(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"let p = tag({\"x\": 1, \"y\": 2}, \"Point\")\nmatch p {\n  v: Point => speak v.x + v.y,\n  _ => speak \"other\",\n}", "3\n"},
+		{"let p = tag({\"x\": 1}, \"Point\")\nmatch p {\n  v: Size => speak \"size\",\n  v: map => speak \"plain map\",\n}", "plain map\n"},
+		{"match ({\"x\": 1}) {\n  v: Point => speak \"point\",\n  _ => speak \"untagged\",\n}", "untagged\n"},
+		{"let p = tag({\"x\": 1}, \"Point\")\nspeak p == {\"x\": 1}, p == tag({\"x\": 1}, \"Point\")", "false true\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	_, _, err := evalSource(t, `tag({}, "int")`)
+	if err == nil {
+		t.Error("expected doom tagging a map with a lowercase type name")
+	}
+}
+
+func TestMatchResultDestructuring(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"match ok([1, 2]) {\n  ok([a, b]) => { speak a + b },\n  _ => speak \"no\",\n}", "3\n"},
+		{"match ok([1, 2, 3]) {\n  ok([a, b]) => speak \"pair\",\n  ok([h, ..t]) => { speak h, len(t) },\n}", "1 2\n"},
+		{"match err({\"code\": 404, \"msg\": \"gone\"}) {\n  ok(v) => speak v,\n  err({\"code\": c, \"msg\": m}) => { speak c\n speak m },\n}", "404\ngone\n"},
+		{"match ok(ok([1, \"x\"])) {\n  ok(ok([n, s])) => { speak s, n },\n  _ => speak \"no\",\n}", "x 1\n"},
+		{"match ok(5) {\n  ok([a, b]) => speak \"pair\",\n  ok(n: int) if n > 3 => { speak \"big\", n },\n  _ => speak \"other\",\n}", "big 5\n"},
+		{"match err(1) {\n  ok() => speak \"ok\",\n  err() => speak \"err\",\n}", "err\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("%q: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
 func TestMapIntKeys(t *testing.T) {
 	out, _, err := evalSource(t, `
 decree "deterministic_hashing"
@@ -746,6 +1450,90 @@ speak m[1]
 	}
 }
 
+func TestMapNegativeAndComputedKeys(t *testing.T) {
+	out, _, err := evalSource(t, `
+let k = "id"
+let m = { -1: "neg", -0.5: "half", (1 + 1): "two", (k + "_2"): "computed" }
+speak m[-1], m[-0.5], m[2], m["id_2"]
+speak len(m)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "neg half two computed\n4\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMapFloatKeysDistinctFromInts(t *testing.T) {
+	out, _, err := evalSource(t, `
+let m = { 1.0: "a", 1: "b", 2.5: "c" }
+speak len(m)
+speak m[1.0], m[1], m[2.5]
+speak m
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "3\na b c\n{1.0: a, 1: b, 2.5: c}\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMapStringKeysDistinctFromNumbers(t *testing.T) {
+	out, _, err := evalSource(t, `
+let m = { 1.0: "a", "1.0": "b", 1: "c", "1": "d", true: "e", "true": "f" }
+speak len(m)
+speak m[1.0], m["1.0"], m[1], m["1"], m[true], m["true"]
+m["1"] = "D"
+speak m[1], m["1"], exists({ 2: nil }, "2")
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "6\na b c d e f\nc D false\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMapNaNKeyDooms(t *testing.T) {
+	_, _, err := evalSource(t, `
+let NaN = "NaN" as float
+let m = { NaN: "boom" }
+`)
+	if err == nil {
+		t.Fatal("expected doom for NaN map key")
+	}
+	if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != "NaN cannot be used as a map key" {
+		t.Errorf("got %v, want doom about NaN key", err)
+	}
+}
+
+func TestEmptyMapLiteral(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let m = {}; speak len(m)`, "0\n"},
+		{`let m = {}; m["a"] = 1; speak m`, "{a: 1}\n"},
+		{`speak {} == {}`, "true\n"},
+		{`fn f() {}; speak f()`, "nil\n"},
+		{`speak if true {} else { 1 }`, "nil\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
 func TestAmbitiousModeIndexAssign(t *testing.T) {
 	out, _, err := evalSource(t, `
 decree "ambitious_mode"
@@ -778,6 +1566,158 @@ speak m.x
 	}
 }
 
+func TestAmbitiousModeEvaluatesCollectionOnce(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "ambitious_mode"
+decree "zero_indexed"
+let calls = 0
+let xs = [1, 2, 3]
+let m = { "x": 1 }
+fn get_xs() {
+  calls = calls + 1
+  xs
+}
+fn get_m() {
+  calls = calls + 1
+  m
+}
+get_xs()[0] == 5
+get_m().x == 7
+speak xs[0]
+speak m.x
+speak calls
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "5\n7\n2\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestLocalBindingShadowsBuiltin(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak len([1, 2, 3])`, "3\n"},
+		{`fn len(x) { "mine" }; speak len([1, 2, 3])`, "mine\n"},
+		{`let keys = fn(m) { 0 }; speak keys({ "a": 1 })`, "0\n"},
+		{`fn f() { let len = fn(x) { -1 }; len("abc") }; speak f(); speak len("abc")`, "-1\n3\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	_, _, err := evalSource(t, `let len = 5; len(3)`)
+	if err == nil {
+		t.Fatal("expected doom calling a shadowed non-function")
+	}
+	if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != "cannot call non-function: 5" {
+		t.Errorf("got %v, want doom about calling non-function", err)
+	}
+}
+
+func TestMethodsDecree(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "methods"
+let counter = { "n": 0, "name": "c" }
+counter["bump"] = fn(by) { self["n"] = self.n + by; self.n }
+counter["label"] = fn() { self.name + "=" + (self.n as str) }
+counter.bump(2)
+speak counter.bump(3)
+speak counter.label()
+let other = { "n": 100, "name": "o", "label": counter.label }
+speak other.label()
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "5\nc=5\no=100\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMethodsRequireDecree(t *testing.T) {
+	_, _, err := evalSource(t, `
+let obj = { "get": fn() { self } }
+obj.get()
+`)
+	if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != "undefined variable: self" {
+		t.Errorf("got %v, want doom about undefined self", err)
+	}
+
+	_, _, err = evalSource(t, `
+decree "methods"
+let obj = { "x": 1 }
+obj.x()
+`)
+	if err == nil {
+		t.Error("expected doom calling a non-function field")
+	}
+}
+
+func TestConstPurity(t *testing.T) {
+	pure := []string{
+		`const x = 1 + 2 * 3`,
+		`const xs = [1, { "a": len("abc") }, if true { 1 } else { 2 }]`,
+		`fn double(n) { let d = n * 2; d }; const y = double(21)`,
+		`const f = fn() { speak "only when called" }`,
+		`fn fact(n) { if n < 2 { 1 } else { n * fact(n - 1) } }; const z = fact(5)`,
+		`const w = (fn() { 1 + 2 })()`,
+	}
+	for _, src := range pure {
+		out, _, err := evalSource(t, "decree \"const_purity\"\n"+src)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", src, err)
+		}
+		if out != "" {
+			t.Errorf("source %q: unexpected output %q", src, out)
+		}
+	}
+
+	impure := []struct {
+		source string
+		what   string
+	}{
+		{`const x = speak "hi"`, "speak"},
+		{`const x = doom("no")`, "doom"},
+		{`const x = [1, sleep(0)]`, "sleep()"},
+		{`let n = 0; fn bump() { n = n + 1; n }; const x = bump()`, "assignment to n (via bump())"},
+		{`fn loud() { speak "hi"; 1 }; fn outer() { loud() + 1 }; const x = outer()`, "speak (via loud()) (via outer())"},
+		{`fn loud(n) { speak n; n }; let f = compose(fn(x) { x + 1 }, loud); const x = f(1)`, "speak (via f())"},
+		{`const x = (fn() { speak "side effect"; 1 })()`, "speak"},
+		{`let o = {"f": fn() { speak "side"; 2 }}; const y = o.f()`, "call to an unknown function"},
+		{`let fs = [fn() { 1 }]; const y = fs[0]()`, "call to an unknown function"},
+		{`const y = no_such_fn(1)`, "call to an unknown function"},
+	}
+	for _, tt := range impure {
+		out, _, err := evalSource(t, "decree \"const_purity\"\n"+tt.source)
+		doomErr, ok := err.(*DoomError)
+		if !ok || !strings.HasSuffix(doomErr.Message, "its initializer uses "+tt.what) {
+			t.Errorf("source %q: got %v, want doom mentioning %q", tt.source, err, tt.what)
+		}
+		if out != "" {
+			t.Errorf("source %q: initializer ran and printed %q", tt.source, out)
+		}
+	}
+
+	// Without the decree, consts may still have effects.
+	out, _, err := evalSource(t, `const x = speak "hi"`)
+	if err != nil || out != "hi\n" {
+		t.Errorf("without const_purity: got %q, %v", out, err)
+	}
+}
+
 func testExampleFile(t *testing.T, filename string) {
 	t.Helper()
 
@@ -847,58 +1787,232 @@ speak x
 	}
 }
 
-func TestLenUnicode(t *testing.T) {
-	out, _, err := evalSource(t, `speak len("héllo");`)
-	if err != nil {
-		t.Fatal(err)
+func TestSaturatingCasts(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let big = 1000000000000000.0 * 1000000000000000.0; speak big as int`, "9223372036854775807\n"},
+		{`let big = 1000000000000000.0 * 1000000000000000.0; speak (-big) as int`, "-9223372036854775808\n"},
+		{`speak 2.9 as int`, "2\n"},
+		{`speak "99999999999999999999" as int`, "9223372036854775807\n"},
+		{`speak "-99999999999999999999" as int`, "-9223372036854775808\n"},
+		{`decree "arbitrary_precision"; speak (9223372036854775807 + 1) as int`, "9223372036854775807\n"},
 	}
-	if out != "5\n" {
-		t.Errorf("got %q, want %q", out, "5\n")
+	for _, tt := range tests {
+		out, _, err := evalSource(t, "decree \"saturating_casts\"\n"+tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
 	}
-}
 
-func TestMatchExhaustion(t *testing.T) {
-	_, _, err := evalSource(t, `
-match 99 {
-  1 => speak "one",
-  2 => speak "two",
-}
-`)
-	if err == nil {
-		t.Fatal("expected doom from exhausted match")
+	// Unparseable strings still doom; only overflow saturates.
+	if _, _, err := evalSource(t, "decree \"saturating_casts\"\nspeak \"abc\" as int"); err == nil {
+		t.Error("expected doom for non-numeric string under saturating_casts")
+	}
+	// Without the decree, out-of-range strings keep dooming.
+	if _, _, err := evalSource(t, `speak "99999999999999999999" as int`); err == nil {
+		t.Error("expected doom for out-of-range string without saturating_casts")
 	}
 }
 
-// --- Anonymous functions ---
-
-func TestAnonymousFnBasic(t *testing.T) {
-	out, _, err := evalSource(t, `
-let double = fn(x) { x * 2 }
-speak double(5)
-`)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+func TestCastStringToBool(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak "true" as bool`, "true\n"},
+		{`speak "false" as bool`, "false\n"},
+		{`speak "1" as bool`, "true\n"},
+		{`speak "0" as bool`, "false\n"},
+		{`speak "yes" as bool`, "true\n"},
+		{`speak "no" as bool`, "false\n"},
+		{`speak " FALSE " as bool`, "false\n"},
+		{`speak 0 as bool`, "false\n"},
+		{`speak [1] as bool`, "true\n"},
+		{`decree "soft_casts"; speak "maybe" as bool`, "err(cannot convert \"maybe\" to bool)\n"},
 	}
-	if out != "10\n" {
-		t.Errorf("got %q, want %q", out, "10\n")
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
 	}
-}
 
-func TestAnonymousFnNoParams(t *testing.T) {
-	out, _, err := evalSource(t, `
-let greet = fn() { "hello" }
-speak greet()
-`)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
-	}
-	if out != "hello\n" {
-		t.Errorf("got %q, want %q", out, "hello\n")
+	_, _, err := evalSource(t, `speak "maybe" as bool`)
+	if doomErr, ok := err.(*DoomError); !ok || doomErr.Message != `cannot convert "maybe" to bool` {
+		t.Errorf("got %v, want doom about converting to bool", err)
 	}
 }
 
-func TestAnonymousFnMultipleParams(t *testing.T) {
-	out, _, err := evalSource(t, `
+func TestCastGroupedNumericStrings(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak "1_000" as int`, "1000\n"},
+		{`speak " -1_000_000 " as int`, "-1000000\n"},
+		{`speak "1_000.25" as float`, "1000.25\n"},
+		{`speak "3.141_592" as float`, "3.141592\n"},
+		{`decree "soft_casts"; speak "1__000" as int`, "err(cannot convert \"1__000\" to int)\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`"_1000" as int`, `"1000_" as int`, `"1__000" as int`, `"-_1" as int`, `"1_.5" as float`, `"1._5" as float`, `"_" as int`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestCastCollections(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak "abc" as array`, "[a, b, c]\n"},
+		{`speak "héllo" as array`, "[h, é, l, l, o]\n"},
+		{`speak { "a": 1, "b": 2 } as array`, "[[a, 1], [b, 2]]\n"},
+		{`speak [1, 2] as array`, "[1, 2]\n"},
+		{`let m = [["a", 1]] as map; speak m["a"]`, "1\n"},
+		{`speak ([] as map) == {}`, "true\n"},
+		{`decree "soft_casts"; speak 5 as array`, "err(cannot cast 5 to array)\n"},
+		{`decree "soft_casts"; speak [1, 2] as map`, "err(cannot cast array to map: element 0 is not a [key, value] pair)\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+	for _, src := range []string{`5 as array`, `"ab" as map`, `[["a"]] as map`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestLenUnicode(t *testing.T) {
+	out, _, err := evalSource(t, `speak len("héllo");`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "5\n" {
+		t.Errorf("got %q, want %q", out, "5\n")
+	}
+}
+
+func TestByteLen(t *testing.T) {
+	out, _, err := evalSource(t, `speak len("héllo"), byte_len("héllo"), byte_len("日本"), byte_len("")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "5 6 6 0\n" {
+		t.Errorf("got %q, want %q", out, "5 6 6 0\n")
+	}
+	for _, src := range []string{`byte_len([1, 2])`, `byte_len()`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestMatchExhaustion(t *testing.T) {
+	_, _, err := evalSource(t, `
+match 99 {
+  1 => speak "one",
+  2 => speak "two",
+}
+`)
+	if err == nil {
+		t.Fatal("expected doom from exhausted match")
+	}
+}
+
+func TestMatchWithoutSubject(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let n = 15; speak match { n % 2 == 0 => "even", n % 5 == 0 => "fives", n % 3 == 0 => "threes", _ => "other" }`, "fives\n"},
+		{`let n = 7; speak match { n < 0 => "neg", n > 100 => "big", _ => "other" }`, "other\n"},
+		{`let n = 0; speak match { n => "truthy", "" => "empty", [1] => "array" }`, "array\n"},
+		{`fn sign(n) { match { n < 0 => -1, n == 0 => 0, _ => 1 } }; speak sign(-4), sign(0), sign(9)`, "-1 0 1\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	// Later conditions are not evaluated once an arm is chosen.
+	out, _, err := evalSource(t, `match { true => speak "first", doom("unreachable") => speak "second" }`)
+	if err != nil || out != "first\n" {
+		t.Errorf("got %q, %v; want the first arm only", out, err)
+	}
+
+	_, _, err = evalSource(t, `match { false => 1, nil => 2 }`)
+	if doomErr, ok := err.(*DoomError); !ok || !strings.Contains(doomErr.Message, "match exhausted") {
+		t.Errorf("got %v, want match exhausted doom", err)
+	}
+}
+
+// --- Anonymous functions ---
+
+func TestAnonymousFnBasic(t *testing.T) {
+	out, _, err := evalSource(t, `
+let double = fn(x) { x * 2 }
+speak double(5)
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "10\n" {
+		t.Errorf("got %q, want %q", out, "10\n")
+	}
+}
+
+func TestAnonymousFnNoParams(t *testing.T) {
+	out, _, err := evalSource(t, `
+let greet = fn() { "hello" }
+speak greet()
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "hello\n" {
+		t.Errorf("got %q, want %q", out, "hello\n")
+	}
+}
+
+func TestAnonymousFnMultipleParams(t *testing.T) {
+	out, _, err := evalSource(t, `
 let add = fn(a, b) { a + b }
 speak add(3, 7)
 `)
@@ -994,6 +2108,8 @@ func TestValuesEqualOkErr(t *testing.T) {
 		{`speak err("x") == err("y")`, "false\n"},
 		{`speak ok(1) == err(1)`, "false\n"},
 		{`speak ok(1) != ok(2)`, "true\n"},
+		{`speak [1, [2]] == [1, [2]]`, "true\n"},
+		{`speak { "a": 1 } == { "a": 2 }`, "false\n"},
 	}
 	for _, tt := range tests {
 		out, _, err := evalSource(t, tt.source)
@@ -1162,6 +2278,111 @@ func TestFloatTruthiness(t *testing.T) {
 	}
 }
 
+// --- Variadic speak ---
+
+func TestSpeakMultipleValues(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let a = 1; speak a, "b", [2, 3] else doom("fail")`, "1 b [2, 3]\n"},
+		{`speak "solo"`, "solo\n"},
+		{`speak "x" + "y", nil`, "xy nil\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+}
+
+// --- speak ... to stream ---
+
+func TestSpeakToStream(t *testing.T) {
+	l := lexer.New(`
+speak "out"
+speak "diag", 1 to "stderr" else doom("fail")
+speak "also out" to "stdout"
+`)
+	p := parser.New(l)
+	prog := p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parse errors: %v", errs)
+	}
+	var stdout, stderr bytes.Buffer
+	ev := New()
+	ev.SetOutput(&stdout)
+	ev.SetErrOutput(&stderr)
+	if _, err := ev.Eval(prog); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := stdout.String(); got != "out\nalso out\n" {
+		t.Errorf("stdout: got %q, want %q", got, "out\nalso out\n")
+	}
+	if got := stderr.String(); got != "diag 1\n" {
+		t.Errorf("stderr: got %q, want %q", got, "diag 1\n")
+	}
+}
+
+func TestSpeakToUnknownStream(t *testing.T) {
+	_, _, err := evalSource(t, `speak "x" to "nowhere"`)
+	if err == nil {
+		t.Fatal("expected doom for unknown stream")
+	}
+}
+
+// failingWriter rejects every write, simulating a closed or full stream.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestSpeakResult(t *testing.T) {
+	out, _, err := evalSource(t, `let r = speak "x"
+speak r`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "x\nok(nil)\n" {
+		t.Errorf("got %q, want %q", out, "x\nok(nil)\n")
+	}
+}
+
+func TestSpeakWriteFailure(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let r = speak "x"; r`, "err(disk full)"},
+		{`speak "x" else "fallback"`, "fallback"},
+		{`fn f() { (speak "x")?; "unreachable" }; f()`, "err(disk full)"},
+		{`fn f() { let r = (speak "x")?; r }; match f() { err(e) => "caught " + e, _ => "no" }`, "caught disk full"},
+	}
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.source))
+		prog := p.Parse()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatalf("source %q: parse errors: %v", tt.source, errs)
+		}
+		ev := New()
+		ev.SetOutput(failingWriter{})
+		result, err := ev.Eval(prog)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if result.String() != tt.want {
+			t.Errorf("source %q: got %s, want %s", tt.source, result.String(), tt.want)
+		}
+	}
+}
+
 // --- Coward ---
 
 func TestCoward(t *testing.T) {
@@ -1304,3 +2525,513 @@ func TestAlignSingleRow(t *testing.T) {
 }
 
 func TestExampleAlign(t *testing.T) { testExampleFile(t, "align.mor") }
+
+func TestArbitraryPrecisionFactorial(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "arbitrary_precision"
+fn fact(n) {
+  if n <= 1 { return 1 }
+  return n * fact(n - 1)
+}
+speak fact(25)
+speak fact(25) / fact(24)
+speak fact(21) > fact(20)
+speak fact(30) == fact(30)
+speak -fact(21)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "15511210043330985984000000\n25\ntrue\ntrue\n-51090942171709440000\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestArbitraryPrecisionNarrowing(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "arbitrary_precision"
+let big = 9223372036854775807 + 1
+speak big
+speak (big - 1) as int
+speak big as float
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "9223372036854775808\n9223372036854775807\n9.223372036854776e+18\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	_, _, err = evalSource(t, `
+decree "arbitrary_precision"
+let big = 9223372036854775807 * 2
+big as int
+`)
+	if err == nil || !strings.Contains(err.Error(), "overflow") {
+		t.Errorf("expected overflow doom, got %v", err)
+	}
+
+	out, _, err = evalSource(t, `
+decree "arbitrary_precision"
+decree "soft_casts"
+speak (9223372036854775807 * 2) as int
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "err(") {
+		t.Errorf("expected err result from soft cast, got %q", out)
+	}
+}
+
+func TestIntOverflowDooms(t *testing.T) {
+	for _, src := range []string{
+		`9223372036854775807 + 1`,
+		`-9223372036854775807 - 2`,
+		`9223372036854775807 * 2`,
+		`let min = -9223372036854775807 - 1
+min / -1`,
+		`let min = -9223372036854775807 - 1;
+-min`,
+	} {
+		_, _, err := evalSource(t, src)
+		if err == nil || !strings.Contains(err.Error(), "integer overflow") {
+			t.Errorf("source %q: expected overflow doom, got %v", src, err)
+		}
+	}
+}
+
+func TestWrappingMath(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "wrapping_math"
+speak 9223372036854775807 + 1
+speak -9223372036854775807 - 2
+speak 9223372036854775807 * 2
+let min = -9223372036854775807 - 1
+speak min / -1
+speak -min
+speak 9223372036854775806 + 1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-9223372036854775808\n9223372036854775807\n-2\n-9223372036854775808\n-9223372036854775808\n9223372036854775807\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestSpawnAwaitInChosenOrder(t *testing.T) {
+	out, _, err := evalSource(t, `
+fn square(n) { n * n }
+let first = spawn { square(3) }
+let second = spawn { doom("boom") }
+speak first
+speak await(second)
+speak await(first)
+speak await(first)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<future>\nerr(boom)\nok(9)\nok(9)\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestSpawnRunsConcurrently(t *testing.T) {
+	// The task blocks on a lock held by the main program, so it can only
+	// finish if it runs alongside it rather than inline.
+	out, _, err := evalSource(t, `
+let gate = mutex()
+lock(gate)
+let h = spawn {
+  lock(gate)
+  unlock(gate)
+  "through"
+}
+speak "main first"
+unlock(gate)
+speak await(h)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "main first\nok(through)\n" {
+		t.Errorf("got %q, want %q", out, "main first\nok(through)\n")
+	}
+}
+
+func TestSpawnedTasksMutateSharedMap(t *testing.T) {
+	// Run under go test -race: tasks take turns holding the interpreter
+	// lock, so these writes to one map and one array never overlap.
+	out, _, err := evalSource(t, `
+decree "zero_indexed"
+let seen = {}
+let hits = [0, 0, 0]
+fn fill(id) {
+  spawn {
+    times(200, fn(i) {
+      seen[(id as str) + "-" + (i as str)] = i
+      hits[id] = hits[id] + 1
+    })
+  }
+}
+fill(0)
+fill(1)
+fill(2)
+await_all
+speak len(keys(seen))
+speak hits
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "600\n[200, 200, 200]\n" {
+		t.Errorf("got %q, want %q", out, "600\n[200, 200, 200]\n")
+	}
+}
+
+func TestSpawnedDecreesStayInTask(t *testing.T) {
+	out, _, err := evalSource(t, `
+decree "one_indexed"
+let xs = ["a", "b"]
+let h = spawn {
+  decree "zero_indexed"
+  xs[0]
+}
+speak await(h)
+speak xs[1]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "ok(a)\na\n" {
+		t.Errorf("got %q, want %q", out, "ok(a)\na\n")
+	}
+}
+
+func TestAwaitRejectsNonFuture(t *testing.T) {
+	if _, _, err := evalSource(t, `await(1)`); err == nil {
+		t.Error("expected doom")
+	}
+}
+
+func TestSelectTakesReadyChannel(t *testing.T) {
+	out, _, err := evalSource(t, `
+let slow = channel()
+let fast = channel()
+spawn { send(fast, "fast wins") }
+let got = select {
+  recv(slow) as v => "slow: " + v,
+  recv(fast) as v => "fast: " + v,
+}
+speak got
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "fast: fast wins\n" {
+		t.Errorf("got %q, want %q", out, "fast: fast wins\n")
+	}
+}
+
+func TestSelectAwaitAndDefault(t *testing.T) {
+	out, _, err := evalSource(t, `
+let idle = channel()
+select {
+  recv(idle) as v => speak "unexpected",
+  default => speak "nothing ready",
+}
+let h = spawn { 42 }
+select {
+  recv(idle) => speak "unexpected",
+  await(h) as r => speak r,
+}
+let buffered = channel(1)
+send(buffered, 7)
+speak recv(buffered)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "nothing ready\nok(42)\n7\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestSelectErrors(t *testing.T) {
+	for _, src := range []string{`select { recv(1) => 1 }`, `select { await(channel()) => 1 }`, `select { }`, `channel(-1)`, `send(1, 2)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom", src)
+		}
+	}
+}
+
+func TestChannelSizeLimit(t *testing.T) {
+	if _, _, err := evalSource(t, `channel(99999999999999)`); err == nil {
+		t.Error("expected doom for an oversized buffer")
+	}
+	if _, _, err := evalSource(t, `let c = channel(1048576); send(c, 1); speak recv(c)`); err != nil {
+		t.Errorf("unexpected error at the size limit: %v", err)
+	}
+}
+
+func TestWaitsThatCanNeverEndDoom(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`let c = channel(); recv(c)`, "recv() would wait forever"},
+		{`let c = channel(); send(c, 1)`, "send() would wait forever"},
+		{`let c = channel(1); send(c, 1); send(c, 2)`, "send() would wait forever"},
+		{`let c = channel(); select { recv(c) => 1 }`, "select would wait forever"},
+		{`let m = mutex(); lock(m); lock(m)`, "lock() would wait forever"},
+	}
+	for _, tt := range tests {
+		_, _, err := evalSource(t, tt.source)
+		doomErr, ok := err.(*DoomError)
+		if !ok || !strings.HasPrefix(doomErr.Message, tt.want) {
+			t.Errorf("source %q: got %v, want doom %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+func TestStuckTaskDooms(t *testing.T) {
+	// The task parks last, so its recv is the wait that dooms, and whoever
+	// awaits it gets the err.
+	out, _, err := evalSource(t, `
+let c = channel()
+let h = spawn { recv(c) }
+speak await(h)
+spawn { recv(c) }
+speak await_all
+let p = spawn_pool(1)
+let stuck = submit(p, fn() { recv(c) })
+speak await(submit(p, fn() { 1 }))
+speak await(stuck)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stuck := "err(recv() would wait forever: every task is waiting)"
+	// await_all also collects h, which was never collected by one.
+	want := stuck + "\n[" + stuck + ", " + stuck + "]\nok(1)\n" + stuck + "\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestStuckTaskDoomsWhenProgramEnds(t *testing.T) {
+	// Nothing will ever send, so once the main program finishes the task's
+	// recv dooms and the program still ends.
+	out, _, err := evalSource(t, `
+let c = channel()
+let m = mutex()
+spawn { recv(c) }
+spawn { lock(m); lock(m) }
+speak "done"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "done\n" {
+		t.Errorf("got %q, want %q", out, "done\n")
+	}
+}
+
+func TestAwaitAllReturnsResults(t *testing.T) {
+	out, _, err := evalSource(t, `
+spawn { 1 }
+spawn { doom("no") }
+speak await_all()
+speak await_all()
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "[ok(1), err(no)]\n[]\n" {
+		t.Errorf("got %q, want %q", out, "[ok(1), err(no)]\n[]\n")
+	}
+}
+
+func TestAwaitAllTimeout(t *testing.T) {
+	out, _, err := evalSource(t, `
+let gate = channel()
+let fast = spawn { "fast" }
+await(fast)
+spawn { recv(gate); "stuck" }
+speak await_all(20)
+send(gate, nil)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "[ok(fast), err(timeout)]\n" {
+		t.Errorf("got %q, want %q", out, "[ok(fast), err(timeout)]\n")
+	}
+
+	for _, src := range []string{`await_all(-1)`, `await_all(9223372036854775807)`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected doom for an out-of-range timeout", src)
+		}
+	}
+}
+
+func TestLazyForcedOnce(t *testing.T) {
+	out, _, err := evalSource(t, `
+let runs = 0
+let base = 20
+let answer = lazy {
+  runs = runs + 1
+  base * 2 + 2
+}
+speak runs
+speak answer
+speak force(answer)
+speak force(answer)
+speak runs
+speak force(7)
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0\n<lazy>\n42\n42\n1\n7\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestLazyCachesDoom(t *testing.T) {
+	ev := New()
+	var buf bytes.Buffer
+	ev.SetOutput(&buf)
+	if _, err := ev.EvalString(`let runs = 0; let bad = lazy { runs = runs + 1; doom("nope") }`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := ev.EvalString(`force(bad)`); err == nil || !strings.Contains(err.Error(), "nope") {
+			t.Fatalf("force #%d: expected cached doom, got %v", i+1, err)
+		}
+	}
+	if _, err := ev.EvalString(`speak runs`); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "1\n" {
+		t.Errorf("got %q, want %q", buf.String(), "1\n")
+	}
+}
+
+func TestDestructuringParams(t *testing.T) {
+	out, _, err := evalSource(t, `
+fn dist2({ "x": x, "y": y }) { x * x + y * y }
+fn head([h, ..t]) { h }
+fn tail([h, ..t]) { t }
+fn swap([a, b]) { [b, a] }
+let norm = fn({ "v": [a, b] }) { a + b }
+speak dist2({ "x": 3, "y": 4, "label": "p" })
+speak head([1, 2, 3])
+speak tail([1, 2, 3])
+speak tail([1])
+speak swap([1, 2])
+speak norm({ "v": [5, 6] })
+match [1, 2, 3] {
+  [a, ..rest] if a > 5 => speak "big",
+  [a, ..rest] => speak rest,
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "25\n1\n[2, 3]\n[]\n[2, 1]\n11\n[2, 3]\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestDestructuringParamMismatchDooms(t *testing.T) {
+	for _, src := range []string{
+		`fn head([h, ..t]) { h }
+head([])`,
+		`fn swap([a, b]) { a }
+swap([1, 2, 3])`,
+		`fn x({ "x": x }) { x }
+x({ "y": 1 })`,
+		`fn x({ "x": x }) { x }
+x(5)`,
+	} {
+		_, _, err := evalSource(t, src)
+		if err == nil || !strings.Contains(err.Error(), "does not match") {
+			t.Errorf("source %q: expected pattern mismatch doom, got %v", src, err)
+		}
+	}
+}
+
+func TestNestedMapOutputIsStable(t *testing.T) {
+	src := `
+let rows = [
+  { "name": "b", "tags": { "z": 1, "a": 2 }, "kids": [{ "q": 1, "p": 2 }] },
+  { "name": "a", "tags": { "only": 0 }, "kids": [] },
+]
+rows[0]["tags"]["z"] = 9
+rows[0]["extra"] = true
+speak rows
+`
+	want := "[{name: b, tags: {z: 9, a: 2}, kids: [{q: 1, p: 2}], extra: true}, {name: a, tags: {only: 0}, kids: []}]\n"
+	for _, prefix := range []string{"decree \"zero_indexed\"\n", "decree \"zero_indexed\"\ndecree \"deterministic_hashing\"\n"} {
+		for i := 0; i < 20; i++ {
+			out, _, err := evalSource(t, prefix+src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != want {
+				t.Fatalf("run %d with %q: got %q, want %q", i, prefix, out, want)
+			}
+		}
+	}
+}
+
+func TestDivisionOperators(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`speak 10 / 3`, "3\n"},
+		{`speak -7 / 2`, "-3\n"},
+		{`speak 10 // 3`, "3\n"},
+		{`speak -7 // 2`, "-4\n"},
+		{`speak 7 // -2`, "-4\n"},
+		{`speak -8 // 2`, "-4\n"},
+		{`speak 7.5 // 2`, "3\n"},
+		{`speak 2 + 7 // 2 * 2`, "8\n"},
+		{`decree "true_division"
+speak 10 / 3`, "3.3333333333333335\n"},
+		{`decree "true_division"
+speak 10 / 5`, "2\n"},
+		{`decree "true_division"
+speak -7 // 2`, "-4\n"},
+		{`decree "arbitrary_precision"
+speak (0 - 9223372036854775807 * 3) // 2`, "-13835058055282163711\n"},
+	}
+	for _, tt := range tests {
+		out, _, err := evalSource(t, tt.source)
+		if err != nil {
+			t.Errorf("source %q: unexpected error: %v", tt.source, err)
+			continue
+		}
+		if out != tt.want {
+			t.Errorf("source %q: got %q, want %q", tt.source, out, tt.want)
+		}
+	}
+
+	for _, src := range []string{`1 // 0`, `1.5 // 0`, `decree "true_division"
+1 / 0`} {
+		if _, _, err := evalSource(t, src); err == nil {
+			t.Errorf("source %q: expected division by zero doom", src)
+		}
+	}
+}
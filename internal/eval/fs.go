@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// requireChant dooms unless the program has run `chant name`, which is how
+// builtins with outside effects are unlocked.
+func (ev *Evaluator) requireChant(builtin, name string) error {
+	if _, ok := ev.chants.Load(name); !ok {
+		return &DoomError{Message: fmt.Sprintf("%s() requires chant %q", builtin, name)}
+	}
+	return nil
+}
+
+// readLine returns the next line from r without its line ending. ok is false
+// at end of input; a final line without a newline is still returned.
+func readLine(r *bufio.Reader) (line string, ok bool, err error) {
+	line, err = r.ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return "", false, nil
+		}
+		err = nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), true, nil
+}
+
+// builtinReadLines reads a whole file as ok(array of lines). Line endings are
+// dropped, so a trailing newline does not produce an empty last line.
+func (ev *Evaluator) builtinReadLines(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValStr {
+		return nil, true, &DoomError{Message: "read_lines() takes exactly 1 string argument"}
+	}
+	if err := ev.requireChant("read_lines", "fs"); err != nil {
+		return nil, true, err
+	}
+	f, err := os.Open(args[0].Str)
+	if err != nil {
+		return ErrVal(StrVal(err.Error())), true, nil
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	lines := []*Value{}
+	for {
+		line, ok, err := readLine(r)
+		if err != nil {
+			return ErrVal(StrVal(err.Error())), true, nil
+		}
+		if !ok {
+			break
+		}
+		lines = append(lines, StrVal(line))
+	}
+	return OkVal(ArrayVal(lines)), true, nil
+}
+
+// builtinIterLines opens a file as ok(iter) yielding one line per next(), so
+// large files never need to fit in memory. The file is closed once the
+// iterator is exhausted; a read error also ends the iteration.
+func (ev *Evaluator) builtinIterLines(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValStr {
+		return nil, true, &DoomError{Message: "iter_lines() takes exactly 1 string argument"}
+	}
+	if err := ev.requireChant("iter_lines", "fs"); err != nil {
+		return nil, true, err
+	}
+	f, err := os.Open(args[0].Str)
+	if err != nil {
+		return ErrVal(StrVal(err.Error())), true, nil
+	}
+	r := bufio.NewReader(f)
+	done := false
+	return OkVal(IterVal(&Iterator{pull: func() (*Value, bool) {
+		if done {
+			return nil, false
+		}
+		line, ok, err := readLine(r)
+		if err != nil || !ok {
+			done = true
+			f.Close()
+			return nil, false
+		}
+		return StrVal(line), true
+	}})), true, nil
+}
+
+// builtinGlob returns the paths matching a shell pattern, sorted. It needs
+// chant "fs"; a malformed pattern dooms.
+func (ev *Evaluator) builtinGlob(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValStr {
+		return nil, true, &DoomError{Message: "glob() takes exactly 1 string argument"}
+	}
+	if err := ev.requireChant("glob", "fs"); err != nil {
+		return nil, true, err
+	}
+	matches, err := filepath.Glob(args[0].Str)
+	if err != nil {
+		return nil, true, &DoomError{Message: fmt.Sprintf("glob(): %v", err)}
+	}
+	paths := make([]*Value, len(matches))
+	for i, m := range matches {
+		paths[i] = StrVal(m)
+	}
+	return ArrayVal(paths), true, nil
+}
+
+// builtinJoinPath joins its string arguments into one cleaned path.
+func (ev *Evaluator) builtinJoinPath(args []*Value) (*Value, bool, error) {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		if a.Kind != ValStr {
+			return nil, true, &DoomError{Message: "join_path() arguments must be strings"}
+		}
+		parts[i] = a.Str
+	}
+	return StrVal(filepath.Join(parts...)), true, nil
+}
+
+// builtinPathPart implements base_name, dir_name and ext, which only inspect
+// the path string and never touch the filesystem.
+func (ev *Evaluator) builtinPathPart(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValStr {
+		return nil, true, &DoomError{Message: name + "() takes exactly 1 string argument"}
+	}
+	p := args[0].Str
+	switch name {
+	case "base_name":
+		return StrVal(filepath.Base(p)), true, nil
+	case "dir_name":
+		return StrVal(filepath.Dir(p)), true, nil
+	default:
+		return StrVal(filepath.Ext(p)), true, nil
+	}
+}
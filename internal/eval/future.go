@@ -0,0 +1,201 @@
+package eval
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/joeabbey/morgoth/internal/parser"
+)
+
+// Future is the handle returned by spawn. The task runs on its own goroutine;
+// done is closed once result holds its outcome, ok(value) or err(message).
+// waiters are the tasks parked in await or select until then.
+type Future struct {
+	done    chan struct{}
+	result  *Value
+	waiters []waitReg
+}
+
+func newFuture() *Future { return &Future{done: make(chan struct{})} }
+
+func FutureVal(f *Future) *Value { return &Value{Kind: ValFuture, Future: f} }
+
+func (f *Future) finished() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// taskGroup tracks spawned tasks. wg lets the program wait for every task
+// before it finishes; pending holds the futures not yet collected by an
+// await_all. runnable counts the tasks, the main program included, that are
+// not parked or finished, and parked holds the rest in the order they
+// parked. Everything but wg is guarded by the interpreter lock.
+type taskGroup struct {
+	wg       sync.WaitGroup
+	pending  []*Future
+	runnable int
+	parked   []*waiter
+}
+
+// start runs body on a new task that resolves f. The caller holds the
+// interpreter lock; the task waits for it before evaluating anything.
+func (g *taskGroup) start(task *Evaluator, f *Future, body func() *Value) {
+	g.wg.Add(1)
+	g.runnable++
+	go func() {
+		defer g.wg.Done()
+		task.interp.Lock()
+		defer task.interp.Unlock()
+		f.result = body()
+		close(f.done)
+		g.wakeAll(f.waiters, f.result)
+		f.waiters = nil
+		g.exit()
+	}()
+}
+
+// take removes and returns the pending futures in spawn order.
+func (g *taskGroup) take() []*Future {
+	fs := g.pending
+	g.pending = nil
+	return fs
+}
+
+// await returns f's result once its task has finished, parking until then.
+// It reports false if that can never happen.
+func (ev *Evaluator) await(f *Future) (*Value, bool) {
+	if f.finished() {
+		return f.result, true
+	}
+	w := newWaiter()
+	f.waiters = append(f.waiters, waitReg{w: w})
+	if !ev.park(w) {
+		return nil, false
+	}
+	return w.val, true
+}
+
+// fork returns an evaluator for a spawned task. It shares output, the task
+// group and the interpreter lock with ev, but has its own current scope and
+// its own copies of the decrees and sigils, so a task's decree or sigil does
+// not leak into the code that spawned it.
+func (ev *Evaluator) fork(env *Env) *Evaluator {
+	child := *ev
+	child.env = env
+	child.debugger = nil
+	child.decrees = ev.decrees.Clone()
+	child.sigils = make(map[string]*SigilDef, len(ev.sigils))
+	for name, def := range ev.sigils {
+		child.sigils[name] = def
+	}
+	return &child
+}
+
+// blocking runs wait with the interpreter lock released, letting other tasks
+// evaluate meanwhile. Waits for another task go through park, which uses it;
+// sleep, retry's backoff and await_all with a timeout call it directly, and
+// the task stays runnable since it will wake up on its own.
+func (ev *Evaluator) blocking(wait func()) {
+	ev.interp.Unlock()
+	defer ev.interp.Lock()
+	wait()
+}
+
+// spec:SEC-6-1
+func (ev *Evaluator) evalSpawnExpr(expr *parser.SpawnExpr) (*Value, error) {
+	f := newFuture()
+	task := ev.fork(NewEnv(ev.env))
+	ev.tasks.pending = append(ev.tasks.pending, f)
+	ev.tasks.start(task, f, func() *Value {
+		return taskResult(task.evalBlockExpr(expr.Body))
+	})
+	return FutureVal(f), nil
+}
+
+// taskResult folds the outcome of a spawned block into ok/err. A doom or an
+// unhandled ? becomes err; a return or guard exit yields its value.
+func taskResult(val *Value, err error) *Value {
+	switch e := err.(type) {
+	case nil:
+		return OkVal(val)
+	case *ReturnSignal:
+		return OkVal(e.Value)
+	case *GuardReturnSignal:
+		return OkVal(e.Value)
+	case *PropagateError:
+		return e.Value
+	case *DoomError:
+		return ErrVal(StrVal(e.Message))
+	default:
+		return ErrVal(StrVal(err.Error()))
+	}
+}
+
+// evalAwaitAllExpr waits for every task spawned since the last await_all and
+// returns their results in spawn order. With a timeout, tasks still running
+// at the deadline are reported as err("timeout") and left to finish on
+// their own.
+func (ev *Evaluator) evalAwaitAllExpr(expr *parser.AwaitAllExpr) (*Value, error) {
+	var deadline <-chan time.Time
+	timed := expr.Timeout != nil
+	if timed {
+		ms, err := ev.evalExpr(expr.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		if ms.Kind != ValInt || ms.Int < 0 || ms.Int > maxMillis {
+			return nil, &DoomError{Message: fmt.Sprintf("await_all() timeout must be an int from 0 to %d, got %s", maxMillis, ms.String())}
+		}
+		timer := time.NewTimer(time.Duration(ms.Int) * time.Millisecond)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	futures := ev.tasks.take()
+	results := make([]*Value, len(futures))
+	if !timed {
+		for i, f := range futures {
+			res, ok := ev.await(f)
+			if !ok {
+				return nil, deadlockDoom("await_all")
+			}
+			results[i] = res
+		}
+		return ArrayVal(results), nil
+	}
+	expired := false
+	for i, f := range futures {
+		if !expired {
+			ev.blocking(func() {
+				select {
+				case <-f.done:
+				case <-deadline:
+					expired = true
+				}
+			})
+		}
+		select {
+		case <-f.done:
+			results[i] = f.result
+		default:
+			results[i] = ErrVal(StrVal("timeout"))
+		}
+	}
+	return ArrayVal(results), nil
+}
+
+func (ev *Evaluator) builtinAwait(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValFuture {
+		return nil, true, &DoomError{Message: "await() takes exactly 1 spawn handle"}
+	}
+	res, ok := ev.await(args[0].Future)
+	if !ok {
+		return nil, true, deadlockDoom("await()")
+	}
+	return res, true, nil
+}
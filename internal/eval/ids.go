@@ -0,0 +1,77 @@
+package eval
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// idSource is the random number generator behind uuid() and ulid(). It is
+// shared with spawned tasks, so every draw takes the lock.
+type idSource struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newIDSource(seed int64) *idSource {
+	return &idSource{rng: rand.New(rand.NewSource(seed))}
+}
+
+func (s *idSource) read(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rng.Read(b)
+}
+
+// SetSeed reseeds the generator behind uuid() and ulid(), so a program run
+// twice with the same seed (and, for ulid(), the same clock) produces the
+// same IDs. The generator is not suitable for secrets.
+func (ev *Evaluator) SetSeed(seed int64) {
+	ev.ids.mu.Lock()
+	defer ev.ids.mu.Unlock()
+	ev.ids.rng.Seed(seed)
+}
+
+// builtinUUID returns a random version 4 UUID in its canonical form.
+func (ev *Evaluator) builtinUUID(args []*Value) (*Value, bool, error) {
+	if len(args) != 0 {
+		return nil, true, &DoomError{Message: "uuid() takes no arguments"}
+	}
+	var b [16]byte
+	ev.ids.read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return StrVal(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])), true, nil
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// builtinULID returns a ULID: a 48-bit millisecond timestamp from the
+// evaluator's clock followed by 80 random bits, written as 26 Crockford
+// base32 characters. IDs made in later milliseconds sort after earlier ones.
+func (ev *Evaluator) builtinULID(args []*Value) (*Value, bool, error) {
+	if len(args) != 0 {
+		return nil, true, &DoomError{Message: "ulid() takes no arguments"}
+	}
+	var b [16]byte
+	ms := uint64(ev.now().UnixNano() / int64(time.Millisecond))
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	ev.ids.read(b[6:])
+	// Pad the 128 bits with two leading zeros to make 26 five-bit groups.
+	var sb strings.Builder
+	for i := 0; i < 26; i++ {
+		var v byte
+		for j := 0; j < 5; j++ {
+			v <<= 1
+			if pos := 5*i + j - 2; pos >= 0 {
+				v |= b[pos/8] >> (7 - pos%8) & 1
+			}
+		}
+		sb.WriteByte(crockford[v])
+	}
+	return StrVal(sb.String()), true, nil
+}
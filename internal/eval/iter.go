@@ -0,0 +1,112 @@
+package eval
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Iterator is the uniform iteration protocol: iter() turns arrays, maps,
+// strings and ranges into one, and next() pulls values until it reports
+// err("done"). Iterators may be shared between tasks, so pulls are
+// serialized.
+type Iterator struct {
+	mu   sync.Mutex
+	pull func() (*Value, bool)
+}
+
+func IterVal(it *Iterator) *Value { return &Value{Kind: ValIter, Iter: it} }
+
+// Next returns the next value, or false once the iterator is exhausted.
+func (it *Iterator) Next() (*Value, bool) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.pull()
+}
+
+// sliceIter yields the elements of a snapshot of vals.
+func sliceIter(vals []*Value) *Iterator {
+	i := 0
+	return &Iterator{pull: func() (*Value, bool) {
+		if i >= len(vals) {
+			return nil, false
+		}
+		i++
+		return vals[i-1], true
+	}}
+}
+
+// toIterator returns an iterator over v: array elements, map [key, value]
+// pairs in insertion order, or string characters. Iterators are returned
+// unchanged.
+func toIterator(v *Value) (*Iterator, error) {
+	switch v.Kind {
+	case ValIter:
+		return v.Iter, nil
+	case ValArray:
+		return sliceIter(append([]*Value(nil), v.Array...)), nil
+	case ValMap:
+		pairs := make([]*Value, 0, v.Map.Len())
+		for _, k := range v.Map.Keys() {
+			val, _ := v.Map.Get(k)
+			pairs = append(pairs, ArrayVal([]*Value{StrVal(keyText(k)), val}))
+		}
+		return sliceIter(pairs), nil
+	case ValStr:
+		var chars []*Value
+		for _, r := range v.Str {
+			chars = append(chars, StrVal(string(r)))
+		}
+		return sliceIter(chars), nil
+	default:
+		return nil, &DoomError{Message: fmt.Sprintf("cannot iterate over %s", v.String())}
+	}
+}
+
+func (ev *Evaluator) builtinIter(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: "iter() takes exactly 1 argument"}
+	}
+	it, err := toIterator(args[0])
+	if err != nil {
+		return nil, true, err
+	}
+	return IterVal(it), true, nil
+}
+
+func (ev *Evaluator) builtinNext(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValIter {
+		return nil, true, &DoomError{Message: "next() takes exactly 1 iterator argument"}
+	}
+	if v, ok := args[0].Iter.Next(); ok {
+		return OkVal(v), true, nil
+	}
+	return ErrVal(StrVal("done")), true, nil
+}
+
+// builtinRange returns a lazy iterator over start, start+step, ... up to but
+// not including end. step defaults to 1 and may be negative.
+func (ev *Evaluator) builtinRange(args []*Value) (*Value, bool, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, true, &DoomError{Message: "range() takes a start, an end and an optional step"}
+	}
+	for _, a := range args {
+		if a.Kind != ValInt {
+			return nil, true, &DoomError{Message: fmt.Sprintf("range() arguments must be int, got %s", a.String())}
+		}
+	}
+	cur, end, step := args[0].Int, args[1].Int, int64(1)
+	if len(args) == 3 {
+		step = args[2].Int
+	}
+	if step == 0 {
+		return nil, true, &DoomError{Message: "range() step cannot be 0"}
+	}
+	return IterVal(&Iterator{pull: func() (*Value, bool) {
+		if step > 0 && cur >= end || step < 0 && cur <= end {
+			return nil, false
+		}
+		v := cur
+		cur += step
+		return IntVal(v), true
+	}}), true, nil
+}
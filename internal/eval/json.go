@@ -0,0 +1,141 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// builtinToJSON encodes a value as compact JSON. Maps keep insertion order.
+func (ev *Evaluator) builtinToJSON(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: "to_json() takes exactly 1 argument"}
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, args[0], "", 0, map[any]bool{}); err != nil {
+		return nil, true, err
+	}
+	return StrVal(buf.String()), true, nil
+}
+
+// maxJSONIndent bounds to_json_pretty()'s indent, in spaces or characters.
+const maxJSONIndent = 10
+
+// builtinToJSONPretty encodes a value as multi-line JSON. The indent is a
+// number of spaces or a literal string such as "\t".
+func (ev *Evaluator) builtinToJSONPretty(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 {
+		return nil, true, &DoomError{Message: "to_json_pretty() takes a value and an indent"}
+	}
+	var indent string
+	switch args[1].Kind {
+	case ValInt:
+		if args[1].Int < 0 || args[1].Int > maxJSONIndent {
+			return nil, true, &DoomError{Message: fmt.Sprintf("to_json_pretty() indent must be between 0 and %d spaces", maxJSONIndent)}
+		}
+		indent = strings.Repeat(" ", int(args[1].Int))
+	case ValStr:
+		if len(args[1].Str) > maxJSONIndent {
+			return nil, true, &DoomError{Message: fmt.Sprintf("to_json_pretty() indent must be at most %d characters", maxJSONIndent)}
+		}
+		indent = args[1].Str
+	default:
+		return nil, true, &DoomError{Message: "to_json_pretty() indent must be an int or a string"}
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, args[0], indent, 0, map[any]bool{}); err != nil {
+		return nil, true, err
+	}
+	return StrVal(buf.String()), true, nil
+}
+
+// writeJSON encodes v into buf. With an empty indent the output is compact;
+// otherwise each array element and map entry goes on its own line, indented
+// once per nesting level. Values with no JSON form doom, as does an array or
+// map that contains itself. onPath holds the arrays (by first slot) and maps
+// being encoded, so a value that merely appears twice is still allowed.
+func writeJSON(buf *bytes.Buffer, v *Value, indent string, depth int, onPath map[any]bool) error {
+	newline := func(d int) {
+		if indent != "" {
+			buf.WriteByte('\n')
+			buf.WriteString(strings.Repeat(indent, d))
+		}
+	}
+	switch v.Kind {
+	case ValNil:
+		buf.WriteString("null")
+	case ValBool, ValInt, ValBigInt, ValDecimal:
+		buf.WriteString(v.String())
+	case ValFloat:
+		if math.IsNaN(v.Float) || math.IsInf(v.Float, 0) {
+			return &DoomError{Message: fmt.Sprintf("cannot encode %s as JSON", v.String())}
+		}
+		buf.WriteString(strconv.FormatFloat(v.Float, 'g', -1, 64))
+	case ValStr:
+		writeJSONString(buf, v.Str)
+	case ValArray:
+		if len(v.Array) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+		if onPath[&v.Array[0]] {
+			return &DoomError{Message: "cannot encode an array that contains itself as JSON"}
+		}
+		onPath[&v.Array[0]] = true
+		defer delete(onPath, &v.Array[0])
+		buf.WriteByte('[')
+		for i, elem := range v.Array {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(depth + 1)
+			if err := writeJSON(buf, elem, indent, depth+1, onPath); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		buf.WriteByte(']')
+	case ValMap:
+		if v.Map.Len() == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		if onPath[v.Map] {
+			return &DoomError{Message: "cannot encode a map that contains itself as JSON"}
+		}
+		onPath[v.Map] = true
+		defer delete(onPath, v.Map)
+		buf.WriteByte('{')
+		for i, k := range v.Map.Keys() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(depth + 1)
+			writeJSONString(buf, keyText(k))
+			buf.WriteByte(':')
+			if indent != "" {
+				buf.WriteByte(' ')
+			}
+			val, _ := v.Map.Get(k)
+			if err := writeJSON(buf, val, indent, depth+1, onPath); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		buf.WriteByte('}')
+	default:
+		return &DoomError{Message: fmt.Sprintf("cannot encode %s as JSON", v.Kind)}
+	}
+	return nil
+}
+
+// writeJSONString writes s as a JSON string literal without HTML escaping.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)               // strings always encode
+	buf.Truncate(buf.Len() - 1) // drop Encode's trailing newline
+}
@@ -0,0 +1,64 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/joeabbey/morgoth/internal/parser"
+)
+
+// Thunk is a deferred computation created by lazy { ... }. The body runs at
+// most once, on the first force(); later forces return the cached outcome,
+// including a cached doom. Forces happen under the interpreter lock, which
+// guards started, finished and waiters, the tasks parked in a force while
+// another task runs the body.
+type Thunk struct {
+	started  bool
+	finished bool
+	waiters  []waitReg
+	body     *parser.BlockExpr
+	env      *Env
+	result   *Value
+	err      error
+}
+
+func LazyVal(t *Thunk) *Value { return &Value{Kind: ValLazy, Thunk: t} }
+
+func (ev *Evaluator) evalLazyExpr(expr *parser.LazyExpr) (*Value, error) {
+	return LazyVal(&Thunk{body: expr.Body, env: ev.env}), nil
+}
+
+// force evaluates t in the scope it was created in, once. Forcing t while
+// its body runs, from another task or from the body itself, waits for it.
+func (ev *Evaluator) force(t *Thunk) (*Value, error) {
+	if t.started {
+		if !t.finished {
+			w := newWaiter()
+			t.waiters = append(t.waiters, waitReg{w: w})
+			if !ev.park(w) {
+				return nil, deadlockDoom("force()")
+			}
+		}
+		return t.result, t.err
+	}
+	t.started = true
+	t.result, t.err = ev.fork(NewEnv(t.env)).evalBlockExpr(t.body)
+	if rs, ok := t.err.(*ReturnSignal); ok {
+		t.result, t.err = rs.Value, nil
+	}
+	t.finished = true
+	ev.tasks.wakeAll(t.waiters, nil)
+	t.waiters = nil
+	return t.result, t.err
+}
+
+// builtinForce evaluates a lazy value; any other value is returned as is.
+func (ev *Evaluator) builtinForce(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 {
+		return nil, true, &DoomError{Message: fmt.Sprintf("force() takes exactly 1 argument, got %d", len(args))}
+	}
+	if args[0].Kind != ValLazy {
+		return args[0], true, nil
+	}
+	val, err := ev.force(args[0].Thunk)
+	return val, true, err
+}
@@ -0,0 +1,50 @@
+package eval
+
+import "fmt"
+
+// Lock backs the values returned by mutex(). held tracks whether the lock is
+// taken so that unlock() of an unlocked mutex dooms; waiters are the tasks
+// parked in lock(). Both are guarded by the interpreter lock.
+type Lock struct {
+	held    bool
+	waiters []waitReg
+}
+
+func MutexVal(l *Lock) *Value { return &Value{Kind: ValMutex, Lock: l} }
+
+func (ev *Evaluator) builtinMutex(args []*Value) (*Value, bool, error) {
+	if len(args) != 0 {
+		return nil, true, &DoomError{Message: "mutex() takes no arguments"}
+	}
+	return MutexVal(&Lock{}), true, nil
+}
+
+// builtinLockOp implements lock(m) and unlock(m). unlock hands a held lock
+// straight to the longest-waiting task, if any.
+func (ev *Evaluator) builtinLockOp(name string, args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValMutex {
+		return nil, true, &DoomError{Message: fmt.Sprintf("%s() takes exactly 1 mutex argument", name)}
+	}
+	l := args[0].Lock
+	if name == "lock" {
+		if !l.held {
+			l.held = true
+			return NilVal(), true, nil
+		}
+		w := newWaiter()
+		l.waiters = append(l.waiters, waitReg{w: w})
+		if !ev.park(w) {
+			return nil, true, deadlockDoom("lock()")
+		}
+		return NilVal(), true, nil
+	}
+	if !l.held {
+		return nil, true, &DoomError{Message: "unlock() of an unlocked mutex"}
+	}
+	if r, ok := takeLive(&l.waiters); ok {
+		ev.tasks.wake(r, nil)
+	} else {
+		l.held = false
+	}
+	return NilVal(), true, nil
+}
@@ -0,0 +1,93 @@
+package eval
+
+import "fmt"
+
+// builtinGetPath follows path, an array of map keys and array indices, into
+// nested collections. Any step that is missing, out of bounds or lands on a
+// non-collection yields nil rather than dooming. Integer steps into arrays
+// honor the indexing decree.
+func (ev *Evaluator) builtinGetPath(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[1].Kind != ValArray {
+		return nil, true, &DoomError{Message: "get_path() takes a value and a path array"}
+	}
+	cur := args[0]
+	for _, step := range args[1].Array {
+		switch cur.Kind {
+		case ValMap:
+			key, err := MapKey(step)
+			if err != nil {
+				return nil, true, &DoomError{Message: err.Error()}
+			}
+			next, ok := cur.Map.Get(key)
+			if !ok {
+				return NilVal(), true, nil
+			}
+			cur = next
+		case ValArray:
+			if step.Kind != ValInt {
+				return NilVal(), true, nil
+			}
+			idx, err := ev.resolveIndex(step.Int, len(cur.Array), "array")
+			if err != nil {
+				return NilVal(), true, nil
+			}
+			cur = cur.Array[idx]
+		default:
+			return NilVal(), true, nil
+		}
+	}
+	return cur, true, nil
+}
+
+// builtinSetPath stores value at path inside x, mutating it in place and
+// returning x. Missing or nil intermediate steps become new maps; array steps
+// must already be in bounds. An empty path has nothing to set and dooms.
+func (ev *Evaluator) builtinSetPath(args []*Value) (*Value, bool, error) {
+	if len(args) != 3 || args[1].Kind != ValArray {
+		return nil, true, &DoomError{Message: "set_path() takes a value, a path array and a value"}
+	}
+	path := args[1].Array
+	if len(path) == 0 {
+		return nil, true, &DoomError{Message: "set_path() path must not be empty"}
+	}
+	cur := args[0]
+	for i, step := range path {
+		last := i == len(path)-1
+		switch cur.Kind {
+		case ValMap:
+			key, err := MapKey(step)
+			if err != nil {
+				return nil, true, &DoomError{Message: err.Error()}
+			}
+			if last {
+				cur.Map.Set(key, args[2])
+				break
+			}
+			next, ok := cur.Map.Get(key)
+			if !ok || next.Kind == ValNil {
+				next = MapVal(NewOrderedMap())
+				cur.Map.Set(key, next)
+			}
+			cur = next
+		case ValArray:
+			if step.Kind != ValInt {
+				return nil, true, &DoomError{Message: fmt.Sprintf("set_path() step %d: array index must be int", i+1)}
+			}
+			idx, err := ev.resolveIndex(step.Int, len(cur.Array), "array")
+			if err != nil {
+				return nil, true, err
+			}
+			if last {
+				cur.Array[idx] = args[2]
+				break
+			}
+			if cur.Array[idx].Kind == ValNil {
+				cur.Array[idx] = MapVal(NewOrderedMap())
+			}
+			cur = cur.Array[idx]
+		default:
+			return nil, true, &DoomError{Message: fmt.Sprintf("set_path() step %d: cannot index into %s", i+1, cur.String())}
+		}
+	}
+	return args[0], true, nil
+}
@@ -0,0 +1,78 @@
+package eval
+
+// Pool bounds parallelism: spawn_pool(n) makes one, submit(pool, f) runs f()
+// on a task once one of the n worker slots is free, and await_pool(pool)
+// collects the results in submission order. running counts the busy slots;
+// submit parks in waiters while all n are taken, and a finishing task hands
+// its slot to the first of them. All fields are guarded by the interpreter
+// lock.
+type Pool struct {
+	size    int64
+	running int64
+	waiters []waitReg
+	pending []*Future
+}
+
+func PoolVal(p *Pool) *Value { return &Value{Kind: ValPool, Pool: p} }
+
+func (ev *Evaluator) builtinSpawnPool(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValInt || args[0].Int < 1 {
+		return nil, true, &DoomError{Message: "spawn_pool() takes a positive int worker count"}
+	}
+	return PoolVal(&Pool{size: args[0].Int}), true, nil
+}
+
+// builtinSubmit waits for a free worker slot, then runs fn() on its own task
+// and returns the task's handle, which await() also accepts. Like spawned
+// tasks, submitted ones are waited for before the program finishes.
+func (ev *Evaluator) builtinSubmit(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 || args[0].Kind != ValPool || args[1].Kind != ValFn {
+		return nil, true, &DoomError{Message: "submit() takes a pool and a function"}
+	}
+	p, fn := args[0].Pool, args[1].Fn
+	if p.running < p.size {
+		p.running++
+	} else {
+		w := newWaiter()
+		p.waiters = append(p.waiters, waitReg{w: w})
+		if !ev.park(w) {
+			return nil, true, deadlockDoom("submit()")
+		}
+	}
+
+	f := newFuture()
+	p.pending = append(p.pending, f)
+	task := ev.fork(NewEnv(ev.env))
+	ev.tasks.start(task, f, func() *Value {
+		result := taskResult(task.callFunction(fn, nil))
+		if r, ok := takeLive(&p.waiters); ok {
+			ev.tasks.wake(r, nil)
+		} else {
+			p.running--
+		}
+		return result
+	})
+	return FutureVal(f), true, nil
+}
+
+// builtinAwaitPool waits for every function submitted since the last
+// await_pool and returns their results, ok(value) or err(message), in
+// submission order.
+func (ev *Evaluator) builtinAwaitPool(args []*Value) (*Value, bool, error) {
+	if len(args) != 1 || args[0].Kind != ValPool {
+		return nil, true, &DoomError{Message: "await_pool() takes exactly 1 pool argument"}
+	}
+	p := args[0].Pool
+	futures := p.pending
+	p.pending = nil
+
+	results := make([]*Value, len(futures))
+	for i, f := range futures {
+		res, ok := ev.await(f)
+		if !ok {
+			return nil, true, deadlockDoom("await_pool()")
+		}
+		results[i] = res
+	}
+	return ArrayVal(results), true, nil
+}
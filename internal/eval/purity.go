@@ -0,0 +1,231 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/joeabbey/morgoth/internal/parser"
+)
+
+// impureBuiltins are the builtins whose calls do more than compute a result:
+// they perform I/O, block, draw random numbers, or mutate an existing value.
+var impureBuiltins = map[string]bool{
+	"read_file":   true,
+	"read_lines":  true,
+	"iter_lines":  true,
+	"glob":        true,
+	"read":        true,
+	"write":       true,
+	"malloc":      true,
+	"free":        true,
+	"sleep":       true,
+	"retry":       true,
+	"send":        true,
+	"recv":        true,
+	"lock":        true,
+	"unlock":      true,
+	"await":       true,
+	"submit":      true,
+	"await_pool":  true,
+	"force":       true,
+	"next":        true,
+	"dump":        true,
+	"move_to_end": true,
+	"set_path":    true,
+	"uuid":        true,
+	"ulid":        true,
+}
+
+// pureBuiltins are the remaining builtins. A call to a name in neither set
+// cannot be vetted, so it counts as impure.
+var pureBuiltins = map[string]bool{
+	"len": true, "byte_len": true, "join_path": true, "base_name": true,
+	"dir_name": true, "ext": true, "parse_toml": true, "is_empty": true,
+	"non_empty": true, "is_nil": true, "exists": true, "coward": true,
+	"tag": true, "type": true, "debug_env": true, "memoize": true, "once": true,
+	"compose": true, "pipe_fns": true, "partial": true, "new_array": true,
+	"keys": true, "values": true, "entries": true, "zip_map": true,
+	"from_entries": true, "map_values": true, "map_keys": true,
+	"sort_keys": true, "to_json": true, "to_json_pretty": true,
+	"escape_html": true, "escape_shell": true, "escape_json": true,
+	"parse_csv": true, "to_csv": true, "get_path": true, "take": true,
+	"drop": true, "chunk": true, "windows": true, "pairwise": true,
+	"group_by": true, "flat_map": true, "flatten": true, "unique": true,
+	"partition": true, "sort": true, "sort_by": true, "min_by": true,
+	"max_by": true, "popcount": true, "leading_zeros": true,
+	"trailing_zeros": true, "rotate_left": true, "mutex": true,
+	"channel": true, "iter": true, "range": true, "spawn_pool": true,
+	"catch": true, "unwrap": true, "expect": true, "times": true,
+	"floor_div": true, "floor_mod": true, "gcd": true, "lcm": true,
+	"is_even": true, "is_odd": true, "hex": true, "oct": true, "bin": true,
+	"commas": true, "rat": true, "decimal": true, "complex": true,
+	"real": true, "imag": true, "abs": true,
+}
+
+// checkConstPurity dooms when a const initializer has side effects. It runs
+// before the initializer is evaluated, so a rejected const never speaks.
+func (ev *Evaluator) checkConstPurity(stmt *parser.ConstStmt) error {
+	if what := ev.impurity(stmt.Value, ev.env, map[*FnValue]bool{}); what != "" {
+		return &DoomError{Message: fmt.Sprintf("const %s must be pure, but its initializer uses %s", stmt.Name, what)}
+	}
+	return nil
+}
+
+// fnImpurity checks a function's body, or each function a composed one
+// calls. Functions already in seen are not checked again.
+func (ev *Evaluator) fnImpurity(fn *FnValue, seen map[*FnValue]bool) string {
+	if seen[fn] {
+		return ""
+	}
+	seen[fn] = true
+	for _, link := range fn.Chain {
+		if what := ev.fnImpurity(link, seen); what != "" {
+			return what
+		}
+	}
+	if fn.Body == nil {
+		return ""
+	}
+	return ev.impurity(fn.Body, fn.Env, seen)
+}
+
+// impurity describes the first side-effecting construct reachable from e, or
+// returns "" when e is pure. Calls by name are followed into the bodies of the
+// user functions they resolve to in env; seen stops recursion. Function
+// literals are pure to create, so their bodies are only checked when called.
+// Any other callee, such as o.f() or fs[0](), is not vetted and counts as
+// impure.
+func (ev *Evaluator) impurity(e parser.Node, env *Env, seen map[*FnValue]bool) string {
+	check := func(nodes ...parser.Node) string {
+		for _, n := range nodes {
+			if n == nil {
+				continue
+			}
+			if what := ev.impurity(n, env, seen); what != "" {
+				return what
+			}
+		}
+		return ""
+	}
+	switch n := e.(type) {
+	case nil:
+		return ""
+	case *parser.SpeakExpr:
+		return "speak"
+	case *parser.DoomExpr:
+		return "doom"
+	case *parser.ChantExpr:
+		return "chant"
+	case *parser.SorryExpr:
+		return "sorry"
+	case *parser.SpawnExpr:
+		return "spawn"
+	case *parser.AwaitAllExpr:
+		return "await_all"
+	case *parser.SelectExpr:
+		return "select"
+	case *parser.InvokeExpr:
+		return "invoke " + n.Name
+	case *parser.DecreeStmt:
+		return n.Token.Literal
+	case *parser.AssignExpr:
+		return "assignment to " + n.Name
+	case *parser.IndexAssignExpr, *parser.DotAssignExpr:
+		return "assignment"
+	case *parser.CallExpr:
+		for _, a := range n.Args {
+			if what := check(a); what != "" {
+				return what
+			}
+		}
+		switch callee := n.Function.(type) {
+		case *parser.FnLitExpr:
+			return check(callee.Body)
+		case *parser.IdentExpr:
+			val, err := env.Get(callee.Name)
+			if err != nil {
+				if impureBuiltins[callee.Name] {
+					return callee.Name + "()"
+				}
+				if pureBuiltins[callee.Name] {
+					return ""
+				}
+			} else if val.Kind == ValFn {
+				if what := ev.fnImpurity(val.Fn, seen); what != "" {
+					return what + " (via " + callee.Name + "())"
+				}
+				return ""
+			}
+		}
+		return "call to an unknown function"
+	case *parser.BlockExpr:
+		for _, s := range n.Stmts {
+			if what := check(s); what != "" {
+				return what
+			}
+		}
+		return check(n.FinalExpr)
+	case *parser.LetStmt:
+		return check(n.Value)
+	case *parser.ConstStmt:
+		return check(n.Value)
+	case *parser.ReturnStmt:
+		return check(n.Value)
+	case *parser.ExprStmt:
+		return check(n.Expression)
+	case *parser.ArrayLitExpr:
+		for _, el := range n.Elements {
+			if what := check(el); what != "" {
+				return what
+			}
+		}
+	case *parser.MapLitExpr:
+		for _, p := range n.Pairs {
+			if what := check(p.Key, p.Value); what != "" {
+				return what
+			}
+		}
+	case *parser.AlignExpr:
+		for _, row := range n.Rows {
+			for _, cell := range row {
+				if what := check(cell); what != "" {
+					return what
+				}
+			}
+		}
+	case *parser.BinaryExpr:
+		return check(n.Left, n.Right)
+	case *parser.UnaryExpr:
+		return check(n.Right)
+	case *parser.IndexExpr:
+		return check(n.Left, n.Index)
+	case *parser.DotExpr:
+		return check(n.Left)
+	case *parser.PropagateExpr:
+		return check(n.Inner, n.Context)
+	case *parser.OkExpr:
+		return check(n.Inner)
+	case *parser.ErrExpr:
+		return check(n.Inner)
+	case *parser.AsExpr:
+		return check(n.Left)
+	case *parser.GuardExpr:
+		return check(n.Condition, n.ElseBody)
+	case *parser.ForExpr:
+		return check(n.Iterable, n.Body)
+	case *parser.IfExpr:
+		if what := check(n.Condition, n.Then); what != "" {
+			return what
+		}
+		return check(n.Else)
+	case *parser.MatchExpr:
+		if what := check(n.Subject); what != "" {
+			return what
+		}
+		for _, arm := range n.Arms {
+			if what := check(arm.Cond, arm.Body); what != "" {
+				return what
+			}
+		}
+	}
+	return ""
+}
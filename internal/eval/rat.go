@@ -0,0 +1,70 @@
+package eval
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Exact rational numbers, created with rat(n, d). Arithmetic between a
+// rational and an int (or bigint) stays exact; mixing with a float falls
+// back to float arithmetic.
+
+func RatVal(r *big.Rat) *Value { return &Value{Kind: ValRat, Rat: r} }
+
+func toRat(v *Value) *big.Rat {
+	switch v.Kind {
+	case ValRat:
+		return v.Rat
+	case ValBigInt:
+		return new(big.Rat).SetInt(v.Big)
+	default:
+		return new(big.Rat).SetInt64(v.Int)
+	}
+}
+
+// useRat reports whether an operation should be carried out exactly on
+// rationals: at least one side is a rational and neither is a float.
+func useRat(left, right *Value) bool {
+	if left.Kind != ValRat && right.Kind != ValRat {
+		return false
+	}
+	ok := func(v *Value) bool { return v.Kind == ValRat || isIntegral(v) }
+	return ok(left) && ok(right)
+}
+
+func (ev *Evaluator) evalRatArith(left, right *Value, op string) (*Value, error) {
+	l, r := toRat(left), toRat(right)
+	res := new(big.Rat)
+	switch op {
+	case "+":
+		res.Add(l, r)
+	case "-":
+		res.Sub(l, r)
+	case "*":
+		res.Mul(l, r)
+	case "/":
+		if r.Sign() == 0 {
+			return nil, &DoomError{Message: "division by zero"}
+		}
+		res.Quo(l, r)
+	default:
+		return nil, &DoomError{Message: fmt.Sprintf("cannot perform %s on rationals", op)}
+	}
+	return RatVal(res), nil
+}
+
+func (ev *Evaluator) builtinRat(args []*Value) (*Value, bool, error) {
+	if len(args) != 2 {
+		return nil, true, &DoomError{Message: "rat() takes exactly 2 arguments (numerator, denominator)"}
+	}
+	for _, a := range args {
+		if !isIntegral(a) {
+			return nil, true, &DoomError{Message: fmt.Sprintf("rat() arguments must be int, got %s", a.String())}
+		}
+	}
+	d := toBig(args[1])
+	if d.Sign() == 0 {
+		return nil, true, &DoomError{Message: "rat() denominator cannot be zero"}
+	}
+	return RatVal(new(big.Rat).SetFrac(toBig(args[0]), d)), true, nil
+}
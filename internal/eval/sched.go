@@ -0,0 +1,106 @@
+package eval
+
+// Tasks evaluate one at a time under the interpreter lock (see blocking). A
+// task that has to wait for another one (in recv, send, select, await, lock,
+// submit, or a force or once() call already running elsewhere) parks: it
+// registers a waiter with the thing it waits on, and the task that makes
+// progress possible wakes it. Both sides run under the interpreter lock, so
+// the task group always knows how many tasks can still run. When that drops
+// to zero while tasks are parked, nothing can ever wake them, and the newest
+// one is woken with a deadlock instead of hanging the interpreter.
+
+// waiter is a parked task. arm and val record which registration woke it and
+// the value handed over, if any.
+type waiter struct {
+	ready      chan struct{}
+	woken      bool
+	deadlocked bool
+	arm        int
+	val        *Value
+}
+
+func newWaiter() *waiter { return &waiter{ready: make(chan struct{})} }
+
+// waitReg registers a waiter with a channel, future, lock, pool or lazy value.
+// arm tells select which of its arms fired; val is the value a parked sender
+// is sending.
+type waitReg struct {
+	w   *waiter
+	arm int
+	val *Value
+}
+
+// takeLive removes and returns the first registration in q whose waiter has
+// not been woken yet. Registrations left behind by a select that fired on
+// another arm, or by a deadlocked waiter, are dropped on the way.
+func takeLive(q *[]waitReg) (waitReg, bool) {
+	for len(*q) > 0 {
+		r := (*q)[0]
+		*q = (*q)[1:]
+		if !r.w.woken {
+			return r, true
+		}
+	}
+	return waitReg{}, false
+}
+
+// wake resumes the waiter behind r with val.
+func (g *taskGroup) wake(r waitReg, val *Value) {
+	w := r.w
+	w.woken, w.arm, w.val = true, r.arm, val
+	g.unpark(w)
+}
+
+// wakeAll resumes every waiter still parked in q with val.
+func (g *taskGroup) wakeAll(q []waitReg, val *Value) {
+	for _, r := range q {
+		if !r.w.woken {
+			g.wake(r, val)
+		}
+	}
+}
+
+func (g *taskGroup) unpark(w *waiter) {
+	for i, p := range g.parked {
+		if p == w {
+			g.parked = append(g.parked[:i], g.parked[i+1:]...)
+			break
+		}
+	}
+	g.runnable++
+	close(w.ready)
+}
+
+// park blocks the current task until w is woken. It reports false if the
+// task was woken because every task was waiting.
+func (ev *Evaluator) park(w *waiter) bool {
+	g := ev.tasks
+	g.runnable--
+	g.parked = append(g.parked, w)
+	g.breakDeadlock()
+	ev.blocking(func() { <-w.ready })
+	return !w.deadlocked
+}
+
+// exit records that a task (or the main program) has finished.
+func (g *taskGroup) exit() {
+	g.runnable--
+	g.breakDeadlock()
+}
+
+// breakDeadlock wakes the most recently parked task, marked deadlocked, when
+// no task can run. Its doom may in turn wake others; if not, the next exit
+// or park lands here again.
+func (g *taskGroup) breakDeadlock() {
+	if g.runnable > 0 || len(g.parked) == 0 {
+		return
+	}
+	w := g.parked[len(g.parked)-1]
+	w.woken, w.deadlocked = true, true
+	g.unpark(w)
+}
+
+// deadlockDoom is the doom for a wait that can never end.
+func deadlockDoom(op string) error {
+	return &DoomError{Message: op + " would wait forever: every task is waiting"}
+}
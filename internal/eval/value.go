@@ -2,6 +2,9 @@ package eval
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
 	"strings"
 
 	"github.com/joeabbey/morgoth/internal/parser"
@@ -22,20 +25,73 @@ const (
 	ValOk
 	ValErr
 	ValPtr
+	ValBigInt
+	ValRat
+	ValDecimal
+	ValComplex
+	ValMutex
+	ValFuture
+	ValChannel
+	ValLazy
+	ValIter
+	ValPool
 )
 
+// kindNames are the type names type() reports; they match the names accepted
+// by typed match patterns.
+var kindNames = map[ValueKind]string{
+	ValInt:     "int",
+	ValFloat:   "float",
+	ValBool:    "bool",
+	ValStr:     "str",
+	ValNil:     "nil",
+	ValArray:   "array",
+	ValMap:     "map",
+	ValFn:      "fn",
+	ValOk:      "ok",
+	ValErr:     "err",
+	ValPtr:     "ptr",
+	ValBigInt:  "bigint",
+	ValRat:     "rat",
+	ValDecimal: "decimal",
+	ValComplex: "complex",
+	ValMutex:   "mutex",
+	ValFuture:  "future",
+	ValChannel: "channel",
+	ValLazy:    "lazy",
+	ValIter:    "iter",
+	ValPool:    "pool",
+}
+
+func (k ValueKind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ValueKind(%d)", int(k))
+}
+
 // Value is the universal runtime value.
 type Value struct {
-	Kind   ValueKind
-	Int    int64
-	Float  float64
-	Bool   bool
-	Str    string
-	Array  []*Value
-	Map    *OrderedMap
-	Fn     *FnValue
-	Inner  *Value // for Ok/Err wrapping
-	Coward bool   // coward-tagged values are always falsy
+	Kind    ValueKind
+	Int     int64
+	Float   float64
+	Bool    bool
+	Str     string
+	Array   []*Value
+	Map     *OrderedMap
+	Fn      *FnValue
+	Big     *big.Int   // for ValBigInt
+	Rat     *big.Rat   // for ValRat
+	Dec     *Decimal   // for ValDecimal
+	Complex complex128 // for ValComplex
+	Lock    *Lock      // for ValMutex
+	Future  *Future    // for ValFuture
+	Chan    *Channel   // for ValChannel
+	Thunk   *Thunk     // for ValLazy
+	Iter    *Iterator  // for ValIter
+	Pool    *Pool      // for ValPool
+	Inner   *Value     // for Ok/Err wrapping
+	Coward  bool       // coward-tagged values are always falsy
 }
 
 // FnValue captures a function closure.
@@ -44,18 +100,48 @@ type FnValue struct {
 	Params []string
 	Body   *parser.BlockExpr
 	Env    *Env
+	// Patterns holds destructuring patterns by parameter position (nil
+	// entries for plain names); nil when no parameter destructures.
+	Patterns []parser.Pattern
+	// Memo caches results keyed by the string form of the arguments.
+	// Only set on functions wrapped by memoize().
+	Memo map[string]*Value
+	// Once caches the single result of a function wrapped by once().
+	Once *OnceCell
+	// Chain lists, in call order, the functions a compose(), pipe_fns() or
+	// partial() function runs, each receiving the previous one's result.
+	Chain []*FnValue
+	// Bound holds the leading arguments partial() fixed; calls pass them to
+	// the first function in Chain ahead of their own.
+	Bound []*Value
 }
 
-// OrderedMap preserves insertion order for deterministic output.
+// OnceCell holds the result of a once() function after its first
+// successful call. The mutex is held during that call so concurrent callers
+// wait for it instead of running the body again.
+type OnceCell struct {
+	done  bool
+	value *Value
+	// running is set while a call is in the body; waiters are the tasks
+	// parked until it finishes. Guarded by the interpreter lock.
+	running bool
+	waiters []waitReg
+}
+
+// OrderedMap preserves insertion order for deterministic output. tag names
+// the struct type a map was made as, or is empty for a plain map.
 type OrderedMap struct {
 	keys   []string
 	values map[string]*Value
+	tag    string
 }
 
 func NewOrderedMap() *OrderedMap {
 	return &OrderedMap{values: make(map[string]*Value)}
 }
 
+// Set stores val under key. Overwriting an existing key keeps its position;
+// use MoveToEnd to reorder.
 func (m *OrderedMap) Set(key string, val *Value) {
 	if _, exists := m.values[key]; !exists {
 		m.keys = append(m.keys, key)
@@ -68,6 +154,22 @@ func (m *OrderedMap) Get(key string) (*Value, bool) {
 	return v, ok
 }
 
+// MoveToEnd moves an existing key to the end of the iteration order without
+// changing its value. It reports whether the key was present.
+func (m *OrderedMap) MoveToEnd(key string) bool {
+	if _, exists := m.values[key]; !exists {
+		return false
+	}
+	for i, k := range m.keys {
+		if k == key {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			break
+		}
+	}
+	m.keys = append(m.keys, key)
+	return true
+}
+
 func (m *OrderedMap) Keys() []string {
 	return m.keys
 }
@@ -76,6 +178,66 @@ func (m *OrderedMap) Len() int {
 	return len(m.keys)
 }
 
+// Tag returns the struct type name the map was tagged with, or "".
+func (m *OrderedMap) Tag() string {
+	return m.tag
+}
+
+// SetTag marks the map as a value of the struct type name.
+func (m *OrderedMap) SetTag(name string) {
+	m.tag = name
+}
+
+// keyTag starts every canonical map key that was not written from a string,
+// followed by the value's kind and a colon, so {1: ..} and {"1": ..} hold two
+// different keys. String keys are stored as they are, unless they themselves
+// start with keyTag.
+const keyTag = "\x00"
+
+// MapKey returns the canonical key string for a value used as a map key.
+// Floats always carry a fractional part or exponent ("1.0", "2.5", "1e+21")
+// so they read apart from ints, -0.0 folds into 0.0, and NaN is rejected
+// because it is not equal to itself and could never be looked up again.
+func MapKey(v *Value) (string, error) {
+	if v.Kind == ValStr {
+		if strings.HasPrefix(v.Str, keyTag) {
+			return keyTag + "str:" + v.Str, nil
+		}
+		return v.Str, nil
+	}
+	if v.Kind != ValFloat {
+		return keyTag + v.Kind.String() + ":" + v.String(), nil
+	}
+	f := v.Float
+	if math.IsNaN(f) {
+		return "", fmt.Errorf("NaN cannot be used as a map key")
+	}
+	if f == 0 {
+		f = 0 // fold -0.0
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eI") {
+		s += ".0"
+	}
+	return keyTag + "float:" + s, nil
+}
+
+// splitKey returns the kind a canonical map key was written from and its
+// text, which is how the key prints and what keys() returns.
+func splitKey(k string) (kind, text string) {
+	if !strings.HasPrefix(k, keyTag) {
+		return "str", k
+	}
+	kind, text, _ = strings.Cut(k[len(keyTag):], ":")
+	return kind, text
+}
+
+// keyText returns the text of a canonical map key without its kind.
+func keyText(k string) string {
+	_, text := splitKey(k)
+	return text
+}
+
 // IsTruthy implements Morgoth truthiness. spec:SEC-4-2
 func (v *Value) IsTruthy() bool {
 	if v.Coward {
@@ -92,6 +254,14 @@ func (v *Value) IsTruthy() bool {
 		return v.Str != ""
 	case ValPtr:
 		return v.Int != 0
+	case ValBigInt:
+		return v.Big.Sign() != 0
+	case ValRat:
+		return v.Rat.Sign() != 0
+	case ValDecimal:
+		return v.Dec.Unscaled.Sign() != 0
+	case ValComplex:
+		return v.Complex != 0
 	case ValNil:
 		return false
 	default:
@@ -99,7 +269,146 @@ func (v *Value) IsTruthy() bool {
 	}
 }
 
-// String returns a human-readable representation for speak output.
+// Clone returns a deep copy of v. Arrays, maps and ok/err payloads are
+// copied recursively; functions share their closure. An array or map that
+// contains itself yields a copy that contains the copy.
+func (v *Value) Clone() *Value {
+	return v.clone(map[any]*Value{})
+}
+
+// clone copies v, reusing the copies in done of arrays (by first slot) and
+// maps already being copied.
+func (v *Value) clone(done map[any]*Value) *Value {
+	c := *v
+	switch v.Kind {
+	case ValArray:
+		if len(v.Array) == 0 {
+			c.Array = []*Value{}
+			break
+		}
+		if prev, ok := done[&v.Array[0]]; ok {
+			return prev
+		}
+		done[&v.Array[0]] = &c
+		c.Array = make([]*Value, len(v.Array))
+		for i, elem := range v.Array {
+			c.Array[i] = elem.clone(done)
+		}
+	case ValMap:
+		if prev, ok := done[v.Map]; ok {
+			return prev
+		}
+		done[v.Map] = &c
+		c.Map = NewOrderedMap()
+		c.Map.tag = v.Map.tag
+		for _, k := range v.Map.Keys() {
+			val, _ := v.Map.Get(k)
+			c.Map.Set(k, val.clone(done))
+		}
+	case ValOk, ValErr:
+		c.Inner = v.Inner.clone(done)
+	}
+	return &c
+}
+
+// Equal reports whether v and other are equal under Morgoth's == rules.
+// Scalars compare by value, ok/err by their payloads, arrays element-wise,
+// and maps by struct tag, key set and per-key value regardless of insertion
+// order.
+// Functions are equal only to themselves. Values of different kinds are
+// never equal. An array or map is equal to itself even when it contains
+// itself.
+func (v *Value) Equal(other *Value) bool {
+	return v.equal(other, map[[2]any]bool{})
+}
+
+// equal compares v and other. onPath holds the pairs of arrays (by first
+// slot) and maps being compared; meeting a pair again means it is cyclic on
+// both sides, and it counts as equal so far.
+func (v *Value) equal(other *Value, onPath map[[2]any]bool) bool {
+	if v.Kind != other.Kind {
+		return false
+	}
+	switch v.Kind {
+	case ValInt, ValPtr:
+		return v.Int == other.Int
+	case ValFloat:
+		return v.Float == other.Float
+	case ValBool:
+		return v.Bool == other.Bool
+	case ValStr:
+		return v.Str == other.Str
+	case ValNil:
+		return true
+	case ValBigInt:
+		return v.Big.Cmp(other.Big) == 0
+	case ValRat:
+		return v.Rat.Cmp(other.Rat) == 0
+	case ValDecimal:
+		return v.Dec.Cmp(other.Dec) == 0
+	case ValComplex:
+		return v.Complex == other.Complex
+	case ValOk, ValErr:
+		return v.Inner.equal(other.Inner, onPath)
+	case ValArray:
+		if len(v.Array) != len(other.Array) {
+			return false
+		}
+		if len(v.Array) == 0 {
+			return true
+		}
+		pair := [2]any{&v.Array[0], &other.Array[0]}
+		if pair[0] == pair[1] || onPath[pair] {
+			return true
+		}
+		onPath[pair] = true
+		defer delete(onPath, pair)
+		for i := range v.Array {
+			if !v.Array[i].equal(other.Array[i], onPath) {
+				return false
+			}
+		}
+		return true
+	case ValMap:
+		if v.Map.Len() != other.Map.Len() || v.Map.tag != other.Map.tag {
+			return false
+		}
+		pair := [2]any{v.Map, other.Map}
+		if v.Map == other.Map || onPath[pair] {
+			return true
+		}
+		onPath[pair] = true
+		defer delete(onPath, pair)
+		for _, k := range v.Map.Keys() {
+			a, _ := v.Map.Get(k)
+			b, ok := other.Map.Get(k)
+			if !ok || !a.equal(b, onPath) {
+				return false
+			}
+		}
+		return true
+	case ValFn:
+		return v.Fn == other.Fn
+	case ValMutex:
+		return v.Lock == other.Lock
+	case ValFuture:
+		return v.Future == other.Future
+	case ValChannel:
+		return v.Chan == other.Chan
+	case ValLazy:
+		return v.Thunk == other.Thunk
+	case ValIter:
+		return v.Iter == other.Iter
+	case ValPool:
+		return v.Pool == other.Pool
+	default:
+		return false
+	}
+}
+
+// String returns a human-readable representation for speak output. Maps
+// render in insertion order at every nesting level, so the output of a
+// program is stable from run to run.
 func (v *Value) String() string {
 	switch v.Kind {
 	case ValInt:
@@ -125,7 +434,7 @@ func (v *Value) String() string {
 		parts := make([]string, 0, v.Map.Len())
 		for _, k := range v.Map.Keys() {
 			val, _ := v.Map.Get(k)
-			parts = append(parts, fmt.Sprintf("%s: %s", k, val.String()))
+			parts = append(parts, fmt.Sprintf("%s: %s", keyText(k), val.String()))
 		}
 		return "{" + strings.Join(parts, ", ") + "}"
 	case ValFn:
@@ -139,6 +448,26 @@ func (v *Value) String() string {
 		return fmt.Sprintf("err(%s)", v.Inner.String())
 	case ValPtr:
 		return fmt.Sprintf("ptr(%d)", v.Int)
+	case ValBigInt:
+		return v.Big.String()
+	case ValRat:
+		return v.Rat.String()
+	case ValDecimal:
+		return v.Dec.String()
+	case ValComplex:
+		return formatComplex(v.Complex)
+	case ValMutex:
+		return "<mutex>"
+	case ValFuture:
+		return "<future>"
+	case ValChannel:
+		return "<channel>"
+	case ValLazy:
+		return "<lazy>"
+	case ValIter:
+		return "<iter>"
+	case ValPool:
+		return "<pool>"
 	default:
 		return "<unknown>"
 	}
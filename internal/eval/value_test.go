@@ -0,0 +1,120 @@
+package eval
+
+import "testing"
+
+func mapOf(pairs ...any) *Value {
+	m := NewOrderedMap()
+	for i := 0; i < len(pairs); i += 2 {
+		m.Set(pairs[i].(string), pairs[i+1].(*Value))
+	}
+	return MapVal(m)
+}
+
+func TestValueEqual(t *testing.T) {
+	fn := FnVal(&FnValue{Name: "f"})
+	tests := []struct {
+		name string
+		a, b *Value
+		want bool
+	}{
+		{"ints", IntVal(3), IntVal(3), true},
+		{"int vs float", IntVal(1), FloatVal(1), false},
+		{"strings", StrVal("a"), StrVal("b"), false},
+		{"nils", NilVal(), NilVal(), true},
+		{"ok payloads", OkVal(IntVal(1)), OkVal(IntVal(1)), true},
+		{"ok vs err", OkVal(IntVal(1)), ErrVal(IntVal(1)), false},
+		{"arrays", ArrayVal([]*Value{IntVal(1), StrVal("x")}), ArrayVal([]*Value{IntVal(1), StrVal("x")}), true},
+		{"array lengths", ArrayVal([]*Value{IntVal(1)}), ArrayVal(nil), false},
+		{
+			"nested collections",
+			ArrayVal([]*Value{mapOf("k", ArrayVal([]*Value{IntVal(1)}))}),
+			ArrayVal([]*Value{mapOf("k", ArrayVal([]*Value{IntVal(1)}))}),
+			true,
+		},
+		{
+			"nested mismatch",
+			mapOf("k", ArrayVal([]*Value{IntVal(1)})),
+			mapOf("k", ArrayVal([]*Value{IntVal(2)})),
+			false,
+		},
+		{"map order ignored", mapOf("a", IntVal(1), "b", IntVal(2)), mapOf("b", IntVal(2), "a", IntVal(1)), true},
+		{"map missing key", mapOf("a", IntVal(1)), mapOf("b", IntVal(1)), false},
+		{"same fn", fn, fn, true},
+		{"distinct fns", FnVal(&FnValue{Name: "f"}), FnVal(&FnValue{Name: "f"}), false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Equal(tt.b); got != tt.want {
+			t.Errorf("%s: Equal = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := tt.b.Equal(tt.a); got != tt.want {
+			t.Errorf("%s: reversed Equal = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValueSelfContaining(t *testing.T) {
+	a := ArrayVal([]*Value{IntVal(1), IntVal(2)})
+	a.Array[0] = a
+	if !a.Equal(a) {
+		t.Error("self-containing array is not equal to itself")
+	}
+	b := ArrayVal([]*Value{IntVal(1), IntVal(2)})
+	b.Array[0] = b
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Error("two arrays that each contain themselves compare unequal")
+	}
+	c := a.Clone()
+	if c.Array[0] != c {
+		t.Error("clone of a self-containing array does not contain itself")
+	}
+	if &c.Array[0] == &a.Array[0] || !c.Equal(a) {
+		t.Error("clone shares storage with the original or differs from it")
+	}
+	b.Array[1] = IntVal(3)
+	if a.Equal(b) {
+		t.Error("self-containing arrays with different elements compare equal")
+	}
+
+	m := mapOf("x", IntVal(1))
+	m.Map.Set("self", m)
+	if mc := m.Clone(); !mc.Equal(m) {
+		t.Error("clone of a self-containing map differs from it")
+	}
+}
+
+func TestValueIsTruthy(t *testing.T) {
+	tests := []struct {
+		val  *Value
+		want bool
+	}{
+		{IntVal(0), false},
+		{IntVal(-1), true},
+		{StrVal(""), false},
+		{NilVal(), false},
+		{ArrayVal(nil), true},
+		{ErrVal(NilVal()), true},
+		{&Value{Kind: ValInt, Int: 1, Coward: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.val.IsTruthy(); got != tt.want {
+			t.Errorf("%s.IsTruthy() = %v, want %v", tt.val.String(), got, tt.want)
+		}
+	}
+}
+
+func TestValueStringNestedOrder(t *testing.T) {
+	inner := mapOf("z", IntVal(1), "a", IntVal(2), "m", IntVal(3))
+	v := ArrayVal([]*Value{
+		mapOf("b", inner, "a", ArrayVal([]*Value{mapOf("y", NilVal(), "x", StrVal("s"))})),
+		mapOf("k", IntVal(0)),
+	})
+	want := "[{b: {z: 1, a: 2, m: 3}, a: [{y: nil, x: s}]}, {k: 0}]"
+	for i := 0; i < 50; i++ {
+		if got := v.String(); got != want {
+			t.Fatalf("String() = %q, want %q", got, want)
+		}
+	}
+	if got := v.Clone().String(); got != want {
+		t.Errorf("Clone().String() = %q, want %q", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/joeabbey/morgoth/internal/token"
@@ -17,14 +18,28 @@ type Lexer struct {
 
 	// For semicolon insertion: track the last non-whitespace token emitted.
 	lastToken token.Token
-	// pendingSemicolon is set when we detect a newline boundary requiring
-	// semicolon insertion; it will be emitted before the next real token.
-	pendingSemicolon *token.Token
 
 	// alignMode is true when parsing inside an align { ... } block.
 	// In this mode, tabs and newlines are emitted as TAB/NEWLINE tokens
 	// instead of being skipped as whitespace, and semicolon insertion is disabled.
 	alignMode bool
+
+	// KeepComments makes the lexer emit COMMENT tokens instead of skipping
+	// comments. Intended for highlighters; the parser does not accept them.
+	KeepComments bool
+	// carriedNewline remembers a newline crossed before an emitted comment so
+	// semicolon insertion still sees it on the following token.
+	carriedNewline bool
+
+	// errors collects lexical diagnostics (e.g. unterminated literals).
+	errors []Error
+}
+
+// Error is a lexical diagnostic anchored at the token that triggered it.
+type Error struct {
+	Line int
+	Col  int
+	Msg  string
 }
 
 // New creates a new Lexer for the given input string.
@@ -49,6 +64,30 @@ func (l *Lexer) SetAlignMode(mode bool) {
 	l.alignMode = mode
 }
 
+// Errors returns the lexical diagnostics recorded so far.
+func (l *Lexer) Errors() []string {
+	msgs := make([]string, len(l.errors))
+	for i, e := range l.errors {
+		msgs[i] = e.Msg
+	}
+	return msgs
+}
+
+// Reported returns true if a diagnostic was recorded for the given token,
+// so callers can avoid piling a generic error on top of it.
+func (l *Lexer) Reported(tok token.Token) bool {
+	for _, e := range l.errors {
+		if e.Line == tok.Line && e.Col == tok.Col {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Lexer) addError(line, col int, msg string) {
+	l.errors = append(l.errors, Error{Line: line, Col: col, Msg: msg})
+}
+
 func (l *Lexer) readChar() {
 	if l.readPos >= len(l.input) {
 		l.ch = 0
@@ -60,6 +99,14 @@ func (l *Lexer) readChar() {
 	l.col++
 }
 
+// skipTo advances to input[pos] in one step, as if readChar had been called
+// once per byte. The skipped bytes must not include a newline.
+func (l *Lexer) skipTo(pos int) {
+	l.col += pos - l.pos - 1
+	l.readPos = pos
+	l.readChar()
+}
+
 func (l *Lexer) peekChar() byte {
 	if l.readPos >= len(l.input) {
 		return 0
@@ -82,23 +129,28 @@ func (l *Lexer) peekCharAt(offset int) byte {
 func (l *Lexer) skipWhitespaceAndComments() bool {
 	sawNewline := false
 	for {
-		switch {
-		case l.ch == ' ' || l.ch == '\r':
+		switch l.ch {
+		case ' ', '\r':
 			l.readChar()
-		case (l.ch == '\t') && !l.alignMode:
+		case '\t':
+			if l.alignMode {
+				// In align mode, don't skip tabs — they become TAB tokens
+				return sawNewline
+			}
 			l.readChar()
-		case l.ch == '\t' && l.alignMode:
-			// In align mode, don't skip tabs — they become TAB tokens
-			return sawNewline
-		case l.ch == '\n' && l.alignMode:
-			// In align mode, don't skip newlines — they become NEWLINE tokens
-			return sawNewline
-		case l.ch == '\n':
+		case '\n':
+			if l.alignMode {
+				// In align mode, don't skip newlines — they become NEWLINE tokens
+				return sawNewline
+			}
 			sawNewline = true
 			l.line++
 			l.col = 0
 			l.readChar()
-		case l.ch == '#':
+		case '#':
+			if l.KeepComments {
+				return sawNewline
+			}
 			if l.peekChar() == '{' {
 				l.skipBlockComment()
 			} else {
@@ -119,6 +171,7 @@ func (l *Lexer) skipLineComment() {
 
 // spec:SEC-1-3
 func (l *Lexer) skipBlockComment() {
+	startLine, startCol := l.line, l.col
 	// consume the '#{'
 	l.readChar() // skip '#'
 	l.readChar() // skip '{'
@@ -145,6 +198,9 @@ func (l *Lexer) skipBlockComment() {
 			l.readChar()
 		}
 	}
+	if depth > 0 {
+		l.addError(startLine, startCol, fmt.Sprintf("unterminated block comment started at line %d", startLine))
+	}
 }
 
 // NextToken returns the next token from the input. spec:SEC-1-2 spec:SEC-2-4
@@ -152,6 +208,9 @@ func (l *Lexer) NextToken() token.Token {
 	// In align mode, skip semicolon insertion entirely.
 	if l.alignMode {
 		l.skipWhitespaceAndComments()
+		if l.ch == '#' && l.KeepComments {
+			return l.readComment()
+		}
 
 		// Emit TAB and NEWLINE as explicit tokens in align mode.
 		if l.ch == '\t' {
@@ -173,32 +232,24 @@ func (l *Lexer) NextToken() token.Token {
 		goto tokenSwitch
 	}
 
-	// If we have a pending semicolon from newline insertion, emit it first.
-	if l.pendingSemicolon != nil {
-		tok := *l.pendingSemicolon
-		l.pendingSemicolon = nil
-		l.lastToken = tok
-		return tok
-	}
-
 	{
-	sawNewline := l.skipWhitespaceAndComments()
+	sawNewline := l.skipWhitespaceAndComments() || l.carriedNewline
+	l.carriedNewline = false
+	if l.ch == '#' && l.KeepComments {
+		l.carriedNewline = sawNewline
+		return l.readComment()
+	}
 
 	// Check for semicolon insertion:
 	// If we crossed a newline, the last token triggers semicolon insertion,
-	// and the upcoming token starts a statement (or is EOF).
+	// and the upcoming token starts a statement (or is EOF). The semicolon
+	// is emitted in place of the upcoming token, which the next call reads.
 	if sawNewline && token.SemicolonTrigger(l.lastToken.Type) {
 		// Peek at what comes next to see if it starts a statement or is EOF.
 		if l.ch == 0 || l.nextTokenStartsStatement() {
-			semi := token.Token{
-				Type:    token.SEMICOLON,
-				Literal: ";",
-				Line:    l.line,
-				Col:     l.col,
-			}
-			l.pendingSemicolon = &semi
-			// Recurse to emit the semicolon.
-			return l.NextToken()
+			tok := l.makeToken(token.SEMICOLON, ";")
+			l.lastToken = tok
+			return tok
 		}
 	}
 	}
@@ -208,8 +259,8 @@ tokenSwitch:
 	tok.Line = l.line
 	tok.Col = l.col
 
-	switch {
-	case l.ch == 0:
+	switch l.ch {
+	case 0:
 		// Check for trailing semicolon insertion at EOF.
 		if token.SemicolonTrigger(l.lastToken.Type) {
 			tok.Type = token.SEMICOLON
@@ -223,75 +274,80 @@ tokenSwitch:
 		l.lastToken = tok
 		return tok
 
-	case l.ch == '+':
+	case '+':
 		tok = l.makeToken(token.PLUS, "+")
 		l.readChar()
 
-	case l.ch == '-':
+	case '-':
 		tok = l.makeToken(token.MINUS, "-")
 		l.readChar()
 
-	case l.ch == '*':
+	case '*':
 		tok = l.makeToken(token.STAR, "*")
 		l.readChar()
 
-	case l.ch == '/':
-		tok = l.makeToken(token.SLASH, "/")
+	case '/':
+		if l.peekChar() == '/' {
+			tok = l.makeToken(token.FLOOR_DIV, "//")
+			l.readChar()
+		} else {
+			tok = l.makeToken(token.SLASH, "/")
+		}
 		l.readChar()
 
-	case l.ch == '%':
+	case '%':
 		tok = l.makeToken(token.PERCENT, "%")
 		l.readChar()
 
-	case l.ch == '&':
+	case '&':
 		tok = l.makeToken(token.AMP, "&")
 		l.readChar()
 
-	case l.ch == '(':
+	case '(':
 		tok = l.makeToken(token.LPAREN, "(")
 		l.readChar()
 
-	case l.ch == ')':
+	case ')':
 		tok = l.makeToken(token.RPAREN, ")")
 		l.readChar()
 
-	case l.ch == '[':
+	case '[':
 		tok = l.makeToken(token.LBRACKET, "[")
 		l.readChar()
 
-	case l.ch == ']':
+	case ']':
 		tok = l.makeToken(token.RBRACKET, "]")
 		l.readChar()
 
-	case l.ch == '{':
+	case '{':
 		tok = l.makeToken(token.LBRACE, "{")
 		l.readChar()
 
-	case l.ch == '}':
+	case '}':
 		tok = l.makeToken(token.RBRACE, "}")
 		l.readChar()
 
-	case l.ch == ',':
+	case ',':
 		tok = l.makeToken(token.COMMA, ",")
 		l.readChar()
 
-	case l.ch == ';':
+	case ';':
 		tok = l.makeToken(token.SEMICOLON, ";")
 		l.readChar()
 
-	case l.ch == ':':
+	case ':':
 		tok = l.makeToken(token.COLON, ":")
 		l.readChar()
 
-	case l.ch == '.':
+	case '.':
 		tok = l.makeToken(token.DOT, ".")
 		l.readChar()
 
-	case l.ch == '?':
+	case '?':
 		tok = l.makeToken(token.QUESTION, "?")
 		l.readChar()
 
-	case l.ch == '=':
+	case '=':
 		if l.peekChar() == '=' && l.peekCharAt(1) == '=' {
 			// ===
 			tok = l.makeToken(token.STRICT_EQ, "===")
@@ -312,7 +368,7 @@ tokenSwitch:
 			l.readChar()
 		}
 
-	case l.ch == '!':
+	case '!':
 		if l.peekChar() == '=' {
 			tok = l.makeToken(token.NEQ, "!=")
 			l.readChar()
@@ -322,7 +378,7 @@ tokenSwitch:
 			l.readChar()
 		}
 
-	case l.ch == '<':
+	case '<':
 		if l.peekChar() == '=' {
 			tok = l.makeToken(token.LTE, "<=")
 			l.readChar()
@@ -332,7 +388,7 @@ tokenSwitch:
 			l.readChar()
 		}
 
-	case l.ch == '>':
+	case '>':
 		if l.peekChar() == '=' {
 			tok = l.makeToken(token.GTE, ">=")
 			l.readChar()
@@ -342,25 +398,33 @@ tokenSwitch:
 			l.readChar()
 		}
 
-	case l.ch == '"':
+	case '"':
 		var ok bool
-		tok.Literal, ok = l.readString()
+		what := "string literal"
+		if l.peekChar() == '"' && l.peekCharAt(1) == '"' {
+			what = "here-doc"
+			tok.Literal, ok = l.readHereDoc()
+		} else {
+			tok.Literal, ok = l.readString()
+		}
 		if ok {
 			tok.Type = token.STRING
 		} else {
 			tok.Type = token.ILLEGAL
+			l.addError(tok.Line, tok.Col, fmt.Sprintf("unterminated %s starting at line %d", what, tok.Line))
 		}
 
-	case isDigit(l.ch):
-		tok.Type, tok.Literal = l.readNumber()
-
-	case isLetter(l.ch):
-		tok.Literal = l.readIdentifier()
-		tok.Type = token.LookupIdent(tok.Literal)
-
 	default:
-		tok = l.makeToken(token.ILLEGAL, string(l.ch))
-		l.readChar()
+		switch {
+		case isDigit(l.ch):
+			tok.Type, tok.Literal = l.readNumber()
+		case isLetter(l.ch):
+			tok.Literal = l.readIdentifier()
+			tok.Type = token.LookupIdent(tok.Literal)
+		default:
+			tok = l.makeToken(token.ILLEGAL, string(l.ch))
+			l.readChar()
+		}
 	}
 
 	l.lastToken = tok
@@ -369,7 +433,9 @@ tokenSwitch:
 
 // Tokenize returns all tokens from the input until EOF (inclusive).
 func (l *Lexer) Tokenize() []token.Token {
-	var tokens []token.Token
+	// Dense source averages about one token per three bytes; sizing the
+	// slice up front avoids repeatedly copying it as it grows.
+	tokens := make([]token.Token, 0, len(l.input)/3+1)
 	for {
 		tok := l.NextToken()
 		tokens = append(tokens, tok)
@@ -380,6 +446,21 @@ func (l *Lexer) Tokenize() []token.Token {
 	return tokens
 }
 
+// readComment consumes a line or block comment and returns it as a COMMENT
+// token. lastToken is left untouched so comments stay invisible to
+// semicolon insertion.
+func (l *Lexer) readComment() token.Token {
+	tok := l.makeToken(token.COMMENT, "")
+	start := l.pos
+	if l.peekChar() == '{' {
+		l.skipBlockComment()
+	} else {
+		l.skipLineComment()
+	}
+	tok.Literal = l.input[start:l.pos]
+	return tok
+}
+
 func (l *Lexer) makeToken(tt token.TokenType, literal string) token.Token {
 	return token.Token{
 		Type:    tt,
@@ -396,22 +477,7 @@ func (l *Lexer) readString() (string, bool) {
 	for l.ch != '"' && l.ch != 0 {
 		if l.ch == '\\' {
 			l.readChar()
-			switch l.ch {
-			case 'n':
-				sb.WriteByte('\n')
-			case 't':
-				sb.WriteByte('\t')
-			case '0':
-				sb.WriteByte(0)
-			case '"':
-				sb.WriteByte('"')
-			case '\\':
-				sb.WriteByte('\\')
-			default:
-				// Unknown escape: include as-is.
-				sb.WriteByte('\\')
-				sb.WriteByte(l.ch)
-			}
+			writeEscape(&sb, l.ch)
 		} else {
 			if l.ch == '\n' {
 				l.line++
@@ -429,6 +495,112 @@ func (l *Lexer) readString() (string, bool) {
 	return sb.String(), false
 }
 
+// writeEscape writes the character denoted by the escape sequence \ch.
+func writeEscape(sb *strings.Builder, ch byte) {
+	switch ch {
+	case 'n':
+		sb.WriteByte('\n')
+	case 't':
+		sb.WriteByte('\t')
+	case '0':
+		sb.WriteByte(0)
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	default:
+		// Unknown escape: include as-is.
+		sb.WriteByte('\\')
+		sb.WriteByte(ch)
+	}
+}
+
+// readHereDoc reads a triple-quoted string. A newline right after the
+// opening """ is dropped, and an opening """- followed by a newline also
+// strips the indentation common to every non-blank line, so the text can be
+// indented along with the surrounding code. Dedenting happens before escapes
+// are processed, so an escaped \t never counts as indentation.
+// spec:SEC-3-2
+func (l *Lexer) readHereDoc() (string, bool) {
+	l.readChar()
+	l.readChar()
+	l.readChar() // skip opening """
+	dedent := false
+	if l.ch == '-' && (l.peekChar() == '\n' || l.peekChar() == '\r' && l.peekCharAt(1) == '\n') {
+		dedent = true
+		l.readChar()
+	}
+	if l.ch == '\r' && l.peekChar() == '\n' {
+		l.readChar()
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.col = 0
+		l.readChar()
+	}
+	start := l.pos
+	for !(l.ch == '"' && l.peekChar() == '"' && l.peekCharAt(1) == '"') {
+		if l.ch == 0 {
+			return l.input[start:l.pos], false
+		}
+		if l.ch == '\\' {
+			l.readChar()
+		}
+		if l.ch == '\n' {
+			l.line++
+			l.col = 0
+		}
+		l.readChar()
+	}
+	raw := l.input[start:l.pos]
+	l.readChar()
+	l.readChar()
+	l.readChar() // skip closing """
+	if dedent {
+		raw = dedentText(raw)
+	}
+	var sb strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '\\' && i+1 < len(raw) {
+			i++
+			writeEscape(&sb, raw[i])
+			continue
+		}
+		sb.WriteByte(raw[i])
+	}
+	return sb.String(), true
+}
+
+// dedentText removes the longest run of leading spaces and tabs shared by
+// every non-blank line. Blank lines, including whitespace-only ones such as
+// the indentation before a closing """, become empty.
+func dedentText(text string) string {
+	lines := strings.Split(text, "\n")
+	prefix := ""
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[len(prefix):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 // spec:SEC-3-2
 func (l *Lexer) readNumber() (token.TokenType, string) {
 	start := l.pos
@@ -465,10 +637,12 @@ func (l *Lexer) readNumber() (token.TokenType, string) {
 
 func (l *Lexer) readIdentifier() string {
 	start := l.pos
-	for isLetter(l.ch) || isDigit(l.ch) {
-		l.readChar()
+	end := start
+	for end < len(l.input) && (isLetter(l.input[end]) || isDigit(l.input[end])) {
+		end++
 	}
-	return l.input[start:l.pos]
+	l.skipTo(end)
+	return l.input[start:end]
 }
 
 // nextTokenStartsStatement peeks ahead to see if the next non-whitespace
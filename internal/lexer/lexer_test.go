@@ -1,8 +1,11 @@
 package lexer
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/joeabbey/morgoth/internal/token"
@@ -119,6 +122,76 @@ func TestStringLiterals(t *testing.T) {
 	}
 }
 
+func TestUnterminatedString(t *testing.T) {
+	l := New("let x = 1\nlet s = \"no closing quote\n")
+	tokens := l.Tokenize()
+	foundIllegal := false
+	for _, tok := range tokens {
+		if tok.Type == token.ILLEGAL {
+			foundIllegal = true
+			if !l.Reported(tok) {
+				t.Errorf("ILLEGAL token at line %d col %d has no diagnostic", tok.Line, tok.Col)
+			}
+		}
+	}
+	if !foundIllegal {
+		t.Fatalf("expected ILLEGAL token, got %v", tokenTypes(tokens))
+	}
+	errs := l.Errors()
+	want := "unterminated string literal starting at line 2"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("got errors %q, want [%q]", errs, want)
+	}
+}
+
+func TestHereDocs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"""one line"""`, "one line"},
+		{"\"\"\"\nfirst\n  second\nthird\"\"\"", "first\n  second\nthird"},
+		{"\"\"\"a \"quoted\" word\\n\"\"\"", "a \"quoted\" word\n"},
+		{"\"\"\"-\n    fn main() {\n      speak 1\n    }\n    \"\"\"", "fn main() {\n  speak 1\n}\n"},
+		{"\"\"\"-\n    a\n\n      b\n  \"\"\"", "a\n\n  b\n"},
+		{"\"\"\"-\r\n\t\tx\r\n\t\ty\"\"\"", "x\r\ny"},
+		{"\"\"\"\n    kept\n    \"\"\"", "    kept\n    "},
+		{"\"\"\"-\n    \\tescaped tab\n    \"\"\"", "\tescaped tab\n"},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.STRING {
+			t.Errorf("input %q: expected STRING, got %s", tt.input, tok.Type)
+		}
+		if tok.Literal != tt.expected {
+			t.Errorf("input %q: expected literal %q, got %q", tt.input, tt.expected, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.SEMICOLON {
+			t.Errorf("input %q: expected the here-doc to end the input, got %s", tt.input, next.Type)
+		}
+	}
+}
+
+func TestHereDocLineTracking(t *testing.T) {
+	l := New("let s = \"\"\"\nline one\nline two\n\"\"\"\nlet y = 1")
+	tokens := l.Tokenize()
+	last := tokens[len(tokens)-3] // before the inserted ; and EOF
+	if last.Literal != "1" || last.Line != 5 {
+		t.Errorf("got %q at line %d, want \"1\" at line 5", last.Literal, last.Line)
+	}
+}
+
+func TestUnterminatedHereDoc(t *testing.T) {
+	l := New("let s = \"\"\"\nnever closed\n\"\"")
+	l.Tokenize()
+	errs := l.Errors()
+	want := "unterminated here-doc starting at line 1"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("got errors %q, want [%q]", errs, want)
+	}
+}
+
 func TestLineComments(t *testing.T) {
 	input := `let x = 5 # this is a comment
 let y = 10`
@@ -294,6 +367,15 @@ func TestOperatorLongestMatch(t *testing.T) {
 	if tokens3[1].Type != token.ARROW {
 		t.Errorf("expected ARROW, got %s", tokens3[1].Type)
 	}
+
+	// // is floor division, a single token
+	tokens4 := New(`a // b / c`).Tokenize()
+	if tokens4[1].Type != token.FLOOR_DIV || tokens4[1].Literal != "//" {
+		t.Errorf("expected FLOOR_DIV, got %s (literal=%q)", tokens4[1].Type, tokens4[1].Literal)
+	}
+	if tokens4[3].Type != token.SLASH {
+		t.Errorf("expected SLASH, got %s", tokens4[3].Type)
+	}
 }
 
 func TestPositionTracking(t *testing.T) {
@@ -465,6 +547,49 @@ func TestBlockCommentNestingCap(t *testing.T) {
 	}
 }
 
+func TestUnterminatedBlockComment(t *testing.T) {
+	l := New("let x = 1\n#{ never closed\nlet y = 2\n")
+	tokens := l.Tokenize()
+	for _, tok := range tokens {
+		if tok.Type == token.IDENT && tok.Literal == "y" {
+			t.Errorf("expected rest of file to be swallowed by the comment")
+		}
+	}
+	errs := l.Errors()
+	want := "unterminated block comment started at line 2"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("got errors %q, want [%q]", errs, want)
+	}
+}
+
+func TestKeepComments(t *testing.T) {
+	input := "let x = 5 # five\n#{ block\ncomment }#\nlet y = x"
+	l := New(input)
+	l.KeepComments = true
+	tokens := l.Tokenize()
+
+	want := []string{
+		"LET", "IDENT", "ASSIGN", "INT", "COMMENT", "COMMENT", "SEMICOLON",
+		"LET", "IDENT", "ASSIGN", "IDENT", "SEMICOLON", "EOF",
+	}
+	got := tokenTypes(tokens)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("token[%d]: got %v, want %v", i, got, want)
+		}
+	}
+
+	if tokens[4].Literal != "# five" || tokens[4].Line != 1 || tokens[4].Col != 11 {
+		t.Errorf("line comment: got %q at %d:%d", tokens[4].Literal, tokens[4].Line, tokens[4].Col)
+	}
+	if tokens[5].Literal != "#{ block\ncomment }#" || tokens[5].Line != 2 || tokens[5].Col != 1 {
+		t.Errorf("block comment: got %q at %d:%d", tokens[5].Literal, tokens[5].Line, tokens[5].Col)
+	}
+}
+
 func TestCRLFLineEndings(t *testing.T) {
 	// Same as TestSemicolonInsertion but with \r\n line endings.
 	input := "let x = 5\r\nlet y = 10\r\n"
@@ -552,3 +677,74 @@ func tokenTypes(tokens []token.Token) []string {
 	}
 	return out
 }
+
+// bigSourceChunk exercises every lexing path: keywords, identifiers,
+// numbers with separators, strings with escapes, here-docs, both comment
+// forms, every operator, CRLF endings and semicolon insertion. Each %d is
+// replaced with the zero-padded chunk number.
+const bigSourceChunk = "let total_%d = 1_000 + 0xFF_FF * 3.141_5 // 2 % 7\r\n" +
+	"# line comment %d\n" +
+	"#{ block #{ nested }# comment }#\n" +
+	"fn f%d(a, b) {\n" +
+	"\tif a === b and a != 2 or !b { return a <= b }\n" +
+	"\tconst s = \"tab\\there \\\"quoted\\\" %d\"\n" +
+	"\tlet doc = \"\"\"\n  here %d\n  \"\"\"\n" +
+	"\tmatch a { ok(x) => x?, _ => [a, b][0] }\n" +
+	"\tlet m = { \"k\": &a, k2: a >= b; }\n" +
+	"\tguard a > 0 else doom(\"neg\")\n" +
+	"}\n" +
+	"speak f%d(1, 2).len == 3 => @\n\n"
+
+func bigSource(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteString(strings.ReplaceAll(bigSourceChunk, "%d", fmt.Sprintf("%04d", i)))
+	}
+	return sb.String()
+}
+
+// TestTokenizeLargeSource pins the token stream for a large generated
+// source: the digest was recorded before the lexer's hot path was
+// optimized, so any change in types, literals or positions shows up here.
+func TestTokenizeLargeSource(t *testing.T) {
+	const n = 2000
+	const want = "7f9947ed4a01ebf9725af682fff503f948c232d7bfc6736aed6681cb8b8f4919"
+	tokens := New(bigSource(n)).Tokenize()
+	h := sha256.New()
+	for _, tok := range tokens {
+		fmt.Fprintf(h, "%s %q %d:%d\n", tok.Type, tok.Literal, tok.Line, tok.Col)
+	}
+	if got := fmt.Sprintf("%x", h.Sum(nil)); got != want {
+		t.Errorf("token stream digest = %s, want %s", got, want)
+	}
+
+	// Each chunk lexes the same as the first one, shifted down by the
+	// chunk's line count, no matter how far into the input it sits.
+	chunk := New(bigSource(1)).Tokenize()
+	per := len(chunk) - 1 // drop EOF
+	lines := strings.Count(bigSource(1), "\n")
+	if len(tokens) != n*per+1 {
+		t.Fatalf("got %d tokens, want %d", len(tokens), n*per+1)
+	}
+	for i, tok := range tokens[:n*per] {
+		ref := chunk[i%per]
+		if ref.Type == token.IDENT || ref.Type == token.INT || ref.Type == token.STRING || ref.Type == token.COMMENT {
+			// Literals embed the chunk number; positions and types must still line up.
+			ref.Literal = tok.Literal
+		}
+		ref.Line += i / per * lines
+		if tok != ref {
+			t.Fatalf("token %d = %+v, want %+v", i, tok, ref)
+		}
+	}
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	src := bigSource(2000)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		New(src).Tokenize()
+	}
+}
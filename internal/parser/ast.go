@@ -43,6 +43,31 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// StmtToken returns the first token of a top-level item or block statement,
+// whose Line and Col locate it in the source. ok is false for other nodes.
+func StmtToken(n Node) (tok token.Token, ok bool) {
+	switch n := n.(type) {
+	case *FnDecl:
+		return n.Token, true
+	case *ExternDecl:
+		return n.Token, true
+	case *SigilDecl:
+		return n.Token, true
+	case *LetStmt:
+		return n.Token, true
+	case *ConstStmt:
+		return n.Token, true
+	case *ReturnStmt:
+		return n.Token, true
+	case *DecreeStmt:
+		return n.Token, true
+	case *ExprStmt:
+		return n.Token, true
+	default:
+		return token.Token{}, false
+	}
+}
+
 // --- Declarations ---
 
 // FnDecl represents a function declaration: fn name(params) { body }
@@ -58,8 +83,9 @@ func (d *FnDecl) itemNode()            {}
 
 // Param is a function parameter.
 type Param struct {
-	Name string
-	Type string // optional type annotation
+	Name    string
+	Type    string  // optional type annotation
+	Pattern Pattern // destructuring pattern; Name is "_" when set
 }
 
 // ExternDecl represents: extern fn name(params);
@@ -108,10 +134,11 @@ func (s *ReturnStmt) TokenLiteral() string { return s.Token.Literal }
 func (s *ReturnStmt) stmtNode()            {}
 func (s *ReturnStmt) itemNode()            {}
 
-// DecreeStmt represents: decree "string";
+// DecreeStmt represents: decree "string"; or undecree "string";
 type DecreeStmt struct {
-	Token token.Token
-	Value string
+	Token  token.Token
+	Value  string
+	Revoke bool // undecree: reset the flag to its default
 }
 
 func (s *DecreeStmt) TokenLiteral() string { return s.Token.Literal }
@@ -290,10 +317,12 @@ type DotExpr struct {
 func (e *DotExpr) TokenLiteral() string { return e.Token.Literal }
 func (e *DotExpr) exprNode()            {}
 
-// PropagateExpr represents expr? (error propagation).
+// PropagateExpr represents expr? (error propagation), or expr? else context,
+// which prefixes the propagated error with the context message.
 type PropagateExpr struct {
-	Token token.Token // the QUESTION
-	Inner Expr
+	Token   token.Token // the QUESTION
+	Inner   Expr
+	Context Expr // nil without else
 }
 
 func (e *PropagateExpr) TokenLiteral() string { return e.Token.Literal }
@@ -310,13 +339,27 @@ type IfExpr struct {
 func (e *IfExpr) TokenLiteral() string { return e.Token.Literal }
 func (e *IfExpr) exprNode()            {}
 
-// MatchArm is a single arm in a match expression.
+// ForExpr represents: for x in collection { ... }
+type ForExpr struct {
+	Token    token.Token // the FOR token
+	Var      string
+	Iterable Expr
+	Body     *BlockExpr
+}
+
+func (e *ForExpr) TokenLiteral() string { return e.Token.Literal }
+func (e *ForExpr) exprNode()            {}
+
+// MatchArm is a single arm in a match expression. Arms of a subject-less
+// match carry a Cond instead of a Pattern, except for a trailing `_`.
 type MatchArm struct {
 	Pattern Pattern
+	Cond    Expr
 	Body    Expr
 }
 
-// MatchExpr represents: match subject { arms... }
+// MatchExpr represents: match subject { arms... }, or match { cond => ... }
+// with a nil Subject.
 type MatchExpr struct {
 	Token   token.Token // the MATCH token
 	Subject Expr
@@ -374,11 +417,12 @@ type AsExpr struct {
 func (e *AsExpr) TokenLiteral() string { return e.Token.Literal }
 func (e *AsExpr) exprNode()            {}
 
-// SpeakExpr represents: speak expr [else expr]
+// SpeakExpr represents: speak expr {, expr} [to stream] [else expr]
 type SpeakExpr struct {
 	Token    token.Token // the SPEAK token
-	Value    Expr
-	ElseBody Expr // optional
+	Values   []Expr      // printed space-separated on one line
+	Stream   Expr        // optional target stream name ("stdout" or "stderr")
+	ElseBody Expr        // optional
 }
 
 func (e *SpeakExpr) TokenLiteral() string { return e.Token.Literal }
@@ -393,10 +437,11 @@ type SorryExpr struct {
 func (e *SorryExpr) TokenLiteral() string { return e.Token.Literal }
 func (e *SorryExpr) exprNode()            {}
 
-// DoomExpr represents: doom(expr)
+// DoomExpr represents: doom(expr) or doom(expr, payload)
 type DoomExpr struct {
 	Token   token.Token
 	Message Expr
+	Payload Expr // optional structured data: doom(msg, payload)
 }
 
 func (e *DoomExpr) TokenLiteral() string { return e.Token.Literal }
@@ -439,14 +484,44 @@ type SpawnExpr struct {
 func (e *SpawnExpr) TokenLiteral() string { return e.Token.Literal }
 func (e *SpawnExpr) exprNode()            {}
 
-// AwaitAllExpr represents: await_all()
+// LazyExpr represents: lazy { body }
+type LazyExpr struct {
+	Token token.Token // the LAZY token
+	Body  *BlockExpr
+}
+
+func (e *LazyExpr) TokenLiteral() string { return e.Token.Literal }
+func (e *LazyExpr) exprNode()            {}
+
+// AwaitAllExpr represents: await_all() or await_all(timeout_ms)
 type AwaitAllExpr struct {
-	Token token.Token // the AWAIT_ALL token
+	Token   token.Token // the AWAIT_ALL token
+	Timeout Expr        // nil when no timeout is given
 }
 
 func (e *AwaitAllExpr) TokenLiteral() string { return e.Token.Literal }
 func (e *AwaitAllExpr) exprNode()            {}
 
+// SelectArm is one arm of a select expression: recv(ch) [as name] => body,
+// await(handle) [as name] => body, or default => body. Op is "recv",
+// "await" or "default"; Source is nil for default.
+type SelectArm struct {
+	Token  token.Token
+	Op     string
+	Source Expr
+	Name   string
+	Body   Expr
+}
+
+// SelectExpr represents: select { arms... }
+type SelectExpr struct {
+	Token token.Token // the SELECT token
+	Arms  []SelectArm
+}
+
+func (e *SelectExpr) TokenLiteral() string { return e.Token.Literal }
+func (e *SelectExpr) exprNode()            {}
+
 // SigilDecl represents a sigil macro declaration: sigil name(params) { body }
 type SigilDecl struct {
 	Token  token.Token // the SIGIL token
@@ -506,6 +581,39 @@ type TypedPattern struct {
 func (p *TypedPattern) TokenLiteral() string { return p.Token.Literal }
 func (p *TypedPattern) patternNode()          {}
 
+// ResultPattern matches an ok or err value and matches its payload against
+// Inner: ok(v), err([code, msg]). Inner is nil for a bare ok() or err().
+type ResultPattern struct {
+	Token token.Token // the ok or err token
+	Inner Pattern
+}
+
+func (p *ResultPattern) TokenLiteral() string { return p.Token.Literal }
+func (p *ResultPattern) patternNode()          {}
+
+// ArrayPattern matches an array element-wise: [a, b] or [head, ..tail].
+// Rest names the binding for the remaining elements when HasRest is set.
+type ArrayPattern struct {
+	Token   token.Token // the [ token
+	Elems   []Pattern
+	Rest    string
+	HasRest bool
+}
+
+func (p *ArrayPattern) TokenLiteral() string { return p.Token.Literal }
+func (p *ArrayPattern) patternNode()          {}
+
+// MapPattern matches a map containing the given keys: { "x": x, "y": y }.
+// Extra keys in the subject are ignored.
+type MapPattern struct {
+	Token  token.Token // the { token
+	Keys   []string
+	Values []Pattern
+}
+
+func (p *MapPattern) TokenLiteral() string { return p.Token.Literal }
+func (p *MapPattern) patternNode()          {}
+
 // GuardedPattern adds a guard condition to a pattern: pattern if expr
 type GuardedPattern struct {
 	Token token.Token
@@ -57,6 +57,7 @@ func TestNodeTokenLiterals(t *testing.T) {
 		{"IfExpr", &IfExpr{Token: token.Token{Literal: "if"}}, "if"},
 		{"MatchExpr", &MatchExpr{Token: token.Token{Literal: "match"}}, "match"},
 		{"GuardExpr", &GuardExpr{Token: token.Token{Literal: "guard"}}, "guard"},
+		{"ForExpr", &ForExpr{Token: token.Token{Literal: "for"}}, "for"},
 		{"BlockExpr", &BlockExpr{Token: token.Token{Literal: "{"}}, "{"},
 		{"OkExpr", &OkExpr{Token: token.Token{Literal: "ok"}}, "ok"},
 		{"ErrExpr", &ErrExpr{Token: token.Token{Literal: "err"}}, "err"},
@@ -68,6 +69,8 @@ func TestNodeTokenLiterals(t *testing.T) {
 		{"FnLitExpr", &FnLitExpr{Token: token.Token{Literal: "fn"}}, "fn"},
 		{"SpawnExpr", &SpawnExpr{Token: token.Token{Literal: "spawn"}}, "spawn"},
 		{"AwaitAllExpr", &AwaitAllExpr{Token: token.Token{Literal: "await_all"}}, "await_all"},
+		{"SelectExpr", &SelectExpr{Token: token.Token{Literal: "select"}}, "select"},
+		{"LazyExpr", &LazyExpr{Token: token.Token{Literal: "lazy"}}, "lazy"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -88,6 +91,7 @@ func TestPatternTokenLiterals(t *testing.T) {
 		{"LiteralPattern", &LiteralPattern{Token: token.Token{Literal: "42"}}, "42"},
 		{"IdentPattern", &IdentPattern{Token: token.Token{Literal: "x"}}, "x"},
 		{"TypedPattern", &TypedPattern{Token: token.Token{Literal: "n"}}, "n"},
+		{"ResultPattern", &ResultPattern{Token: token.Token{Literal: "ok"}}, "ok"},
 		{"GuardedPattern", &GuardedPattern{Token: token.Token{Literal: "if"}}, "if"},
 	}
 	for _, tt := range tests {
@@ -135,6 +139,7 @@ var (
 	_ Expr = (*IfExpr)(nil)
 	_ Expr = (*MatchExpr)(nil)
 	_ Expr = (*GuardExpr)(nil)
+	_ Expr = (*ForExpr)(nil)
 	_ Expr = (*BlockExpr)(nil)
 	_ Expr = (*OkExpr)(nil)
 	_ Expr = (*ErrExpr)(nil)
@@ -146,10 +151,30 @@ var (
 	_ Expr = (*FnLitExpr)(nil)
 	_ Expr = (*SpawnExpr)(nil)
 	_ Expr = (*AwaitAllExpr)(nil)
+	_ Expr = (*SelectExpr)(nil)
+	_ Expr = (*LazyExpr)(nil)
 
 	_ Pattern = (*WildcardPattern)(nil)
 	_ Pattern = (*LiteralPattern)(nil)
 	_ Pattern = (*IdentPattern)(nil)
 	_ Pattern = (*TypedPattern)(nil)
+	_ Pattern = (*ResultPattern)(nil)
 	_ Pattern = (*GuardedPattern)(nil)
 )
+
+func TestStmtToken(t *testing.T) {
+	prog := parse(t, "let x = 1\n  speak x\nfn f() { 2 }")
+	wantLines := []int{1, 2, 3}
+	for i, item := range prog.Items {
+		tok, ok := StmtToken(item)
+		if !ok {
+			t.Fatalf("item %d (%T): expected a statement token", i, item)
+		}
+		if tok.Line != wantLines[i] {
+			t.Errorf("item %d: line %d, want %d", i, tok.Line, wantLines[i])
+		}
+	}
+	if _, ok := StmtToken(&IntLitExpr{}); ok {
+		t.Error("expected no statement token for an expression")
+	}
+}
@@ -0,0 +1,39 @@
+package parser
+
+// SymbolKind classifies a top-level declaration in an outline.
+type SymbolKind string
+
+const (
+	SymbolFn     SymbolKind = "fn"
+	SymbolConst  SymbolKind = "const"
+	SymbolExtern SymbolKind = "extern"
+	SymbolSigil  SymbolKind = "sigil"
+)
+
+// Symbol is a single entry in a document outline.
+type Symbol struct {
+	Name   string
+	Kind   SymbolKind
+	Params []Param // nil for consts
+	Line   int
+	Col    int
+}
+
+// Outline lists the top-level declarations of a program in source order,
+// positioned at each declaration's leading keyword.
+func Outline(program *Program) []Symbol {
+	var symbols []Symbol
+	for _, item := range program.Items {
+		switch n := item.(type) {
+		case *FnDecl:
+			symbols = append(symbols, Symbol{Name: n.Name, Kind: SymbolFn, Params: n.Params, Line: n.Token.Line, Col: n.Token.Col})
+		case *ConstStmt:
+			symbols = append(symbols, Symbol{Name: n.Name, Kind: SymbolConst, Line: n.Token.Line, Col: n.Token.Col})
+		case *ExternDecl:
+			symbols = append(symbols, Symbol{Name: n.Name, Kind: SymbolExtern, Params: n.Params, Line: n.Token.Line, Col: n.Token.Col})
+		case *SigilDecl:
+			symbols = append(symbols, Symbol{Name: n.Name, Kind: SymbolSigil, Params: n.Params, Line: n.Token.Line, Col: n.Token.Col})
+		}
+	}
+	return symbols
+}
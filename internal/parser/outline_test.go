@@ -0,0 +1,51 @@
+package parser
+
+import "testing"
+
+func TestOutline(t *testing.T) {
+	prog := parse(t, `const limit = 10
+let scratch = 0
+
+fn add(a: int, b) {
+  a + b
+}
+
+extern fn puts(s: str);
+sigil shout(msg) { speak msg }
+`)
+	symbols := Outline(prog)
+	want := []struct {
+		name   string
+		kind   SymbolKind
+		params []string
+		line   int
+		col    int
+	}{
+		{"limit", SymbolConst, nil, 1, 1},
+		{"add", SymbolFn, []string{"a", "b"}, 4, 1},
+		{"puts", SymbolExtern, []string{"s"}, 8, 1},
+		{"shout", SymbolSigil, []string{"msg"}, 9, 1},
+	}
+	if len(symbols) != len(want) {
+		t.Fatalf("expected %d symbols, got %d: %+v", len(want), len(symbols), symbols)
+	}
+	for i, w := range want {
+		got := symbols[i]
+		if got.Name != w.name || got.Kind != w.kind || got.Line != w.line || got.Col != w.col {
+			t.Errorf("symbol %d: got %s %s at %d:%d, want %s %s at %d:%d",
+				i, got.Kind, got.Name, got.Line, got.Col, w.kind, w.name, w.line, w.col)
+		}
+		if len(got.Params) != len(w.params) {
+			t.Errorf("symbol %d: got %d params, want %d", i, len(got.Params), len(w.params))
+			continue
+		}
+		for j, p := range w.params {
+			if got.Params[j].Name != p {
+				t.Errorf("symbol %d param %d: got %s, want %s", i, j, got.Params[j].Name, p)
+			}
+		}
+	}
+	if symbols[1].Params[0].Type != "int" {
+		t.Errorf("expected param a to keep type int, got %q", symbols[1].Params[0].Type)
+	}
+}
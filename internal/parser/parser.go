@@ -29,7 +29,17 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 	errors    []string
+	warnings  []string
 	buffered  []token.Token // tokens buffered by peekAhead, consumed before lexer
+	bufPos    int           // index of the next unconsumed token in buffered
+
+	// commaEnds is true while parsing inside a comma-separated construct
+	// (call args, array/map literals, match arms), where a comma ends the
+	// current element rather than continuing a speak argument list.
+	commaEnds bool
+	// elseEnds is true while parsing a guard condition or speak arguments,
+	// whose own else clause must not be read as the context of expr? else.
+	elseEnds bool
 }
 
 // New creates a new Parser for the given lexer.
@@ -40,20 +50,55 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
-// Errors returns the list of parse errors.
+// Errors returns the list of lexical and parse errors.
 func (p *Parser) Errors() []string {
-	return p.errors
+	lexErrs := p.l.Errors()
+	if len(lexErrs) == 0 {
+		return p.errors
+	}
+	return append(lexErrs, p.errors...)
 }
 
 func (p *Parser) addError(msg string) {
 	p.errors = append(p.errors, fmt.Sprintf("line %d col %d: %s", p.curToken.Line, p.curToken.Col, msg))
 }
 
+// errorExpected reports that the current token is none of the tokens valid
+// here, e.g. `expected one of "=>", "if"; got INT ("2")`. Entries are token
+// spellings, quoted in the message; "identifier" stands for any name.
+func (p *Parser) errorExpected(want ...string) {
+	quoted := make([]string, len(want))
+	for i, w := range want {
+		if w == "identifier" {
+			quoted[i] = w
+		} else {
+			quoted[i] = strconv.Quote(w)
+		}
+	}
+	p.addError(fmt.Sprintf("expected one of %s; got %s (%q)", strings.Join(quoted, ", "), p.curToken.Type, p.curToken.Literal))
+}
+
+// Warnings returns non-fatal diagnostics: the program parsed, but something
+// in it is likely unintended. They are formatted like errors.
+func (p *Parser) Warnings() []string {
+	return p.warnings
+}
+
+func (p *Parser) addWarning(tok token.Token, msg string) {
+	p.warnings = append(p.warnings, fmt.Sprintf("line %d col %d: %s", tok.Line, tok.Col, msg))
+}
+
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	if len(p.buffered) > 0 {
-		p.peekToken = p.buffered[0]
-		p.buffered = p.buffered[1:]
+	if p.bufPos < len(p.buffered) {
+		p.peekToken = p.buffered[p.bufPos]
+		p.bufPos++
+		if p.bufPos == len(p.buffered) {
+			// Drained: rewind so the backing array is reused rather than
+			// sliced forward and regrown on the next lookahead.
+			p.buffered = p.buffered[:0]
+			p.bufPos = 0
+		}
 	} else {
 		p.peekToken = p.l.NextToken()
 	}
@@ -68,8 +113,8 @@ func (p *Parser) peekAhead(n int) token.Token {
 	if n == 1 {
 		return p.peekToken
 	}
-	// n >= 2: need buffered[n-2]
-	idx := n - 2
+	// n >= 2: need the (n-2)th unconsumed buffered token
+	idx := p.bufPos + n - 2
 	for len(p.buffered) <= idx {
 		p.buffered = append(p.buffered, p.l.NextToken())
 	}
@@ -98,10 +143,15 @@ func (p *Parser) Parse() *Program {
 			p.nextToken()
 			continue
 		}
+		before := p.curToken
 		item := p.parseItem()
 		if item != nil {
 			prog.Items = append(prog.Items, item)
-		} else {
+		}
+		// An item that fails on its first token, such as a stray } or
+		// `fn 5`, leaves curToken in place; skip it so we always make
+		// progress instead of looping forever.
+		if item == nil || p.curToken == before {
 			p.nextToken()
 		}
 	}
@@ -120,15 +170,36 @@ func (p *Parser) parseItem() Item {
 		return p.parseConstStmt()
 	case token.RETURN:
 		return p.parseReturnStmt()
-	case token.DECREE:
+	case token.DECREE, token.UNDECREE:
 		return p.parseDecreeStmt()
 	case token.SIGIL:
 		return p.parseSigilDecl()
+	case token.IDENT:
+		if p.curToken.Literal == "use" && p.peekIs(token.STRING) {
+			return p.parseUseStmt()
+		}
+		return p.parseExprStmt()
 	default:
 		return p.parseExprStmt()
 	}
 }
 
+// parseUseStmt expands the top-level header `use "name"` into the statement
+// `chant "name"`, so it needs no evaluator support. use is not a keyword:
+// it only means this when a string follows it at the start of an item.
+func (p *Parser) parseUseStmt() *ExprStmt {
+	tok := p.curToken
+	p.nextToken() // move past use
+	chant := tok
+	chant.Type, chant.Literal = token.CHANT, "chant"
+	name := &StringLitExpr{Token: p.curToken, Value: p.curToken.Literal}
+	p.nextToken() // move past string
+	if p.curIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return &ExprStmt{Token: tok, Expression: &ChantExpr{Token: chant, Name: name}}
+}
+
 // --- Declarations ---
 
 // spec:SEC-2-2
@@ -141,10 +212,8 @@ func (p *Parser) parseFnDecl() *FnDecl {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	decl.Params = p.parseParamList()
-	// curToken should be RPAREN
-	if !p.curIs(token.RPAREN) {
-		p.addError(fmt.Sprintf("expected ), got %s", p.curToken.Type))
+	var ok bool
+	if decl.Params, ok = p.parseParamList(); !ok {
 		return nil
 	}
 	p.nextToken() // move past )
@@ -161,9 +230,8 @@ func (p *Parser) parseFnLitExpr() Expr {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	lit.Params = p.parseParamList()
-	if !p.curIs(token.RPAREN) {
-		p.addError(fmt.Sprintf("expected ), got %s", p.curToken.Type))
+	var ok bool
+	if lit.Params, ok = p.parseParamList(); !ok {
 		return nil
 	}
 	p.nextToken() // move past )
@@ -188,9 +256,8 @@ func (p *Parser) parseExternDecl() *ExternDecl {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	decl.Params = p.parseParamList()
-	if !p.curIs(token.RPAREN) {
-		p.addError(fmt.Sprintf("expected ), got %s", p.curToken.Type))
+	var ok bool
+	if decl.Params, ok = p.parseParamList(); !ok {
 		return nil
 	}
 	p.nextToken() // move past )
@@ -201,33 +268,46 @@ func (p *Parser) parseExternDecl() *ExternDecl {
 }
 
 // parseParamList parses parameter list. Called with curToken on (.
-// Returns with curToken on ).
-func (p *Parser) parseParamList() []Param {
-	var params []Param
+// Returns with curToken on ), or reports an error listing what could have
+// come next and returns ok false.
+func (p *Parser) parseParamList() (params []Param, ok bool) {
 	p.nextToken() // move past (
 	if p.curIs(token.RPAREN) {
-		return params
+		return params, true
 	}
 	for {
-		if !p.curIs(token.IDENT) {
-			p.addError(fmt.Sprintf("expected parameter name, got %s", p.curToken.Type))
-			return params
-		}
-		param := Param{Name: p.curToken.Literal}
-		if p.peekIs(token.COLON) {
-			p.nextToken() // move to :
-			p.nextToken() // move to type name
-			param.Type = p.curToken.Literal
+		untyped := false
+		switch {
+		case p.curIs(token.LBRACKET) || p.curIs(token.LBRACE):
+			// Destructuring parameter; parsePattern leaves us on , or ).
+			params = append(params, Param{Name: "_", Pattern: p.parsePattern()})
+		case p.curIs(token.IDENT):
+			param := Param{Name: p.curToken.Literal}
+			if p.peekIs(token.COLON) {
+				p.nextToken() // move to :
+				p.nextToken() // move to type name
+				param.Type = p.curToken.Literal
+			}
+			params = append(params, param)
+			untyped = param.Type == ""
+			p.nextToken() // move past name or type
+		default:
+			p.errorExpected("identifier", "[", "{")
+			return params, false
 		}
-		params = append(params, param)
-		if !p.peekIs(token.COMMA) {
-			break
+		switch {
+		case p.curIs(token.RPAREN):
+			return params, true
+		case p.curIs(token.COMMA):
+			p.nextToken() // move past comma to next param
+		case untyped:
+			p.errorExpected(",", ")", ":")
+			return params, false
+		default:
+			p.errorExpected(",", ")")
+			return params, false
 		}
-		p.nextToken() // move to comma
-		p.nextToken() // move past comma to next param
 	}
-	p.nextToken() // advance to ) or next token
-	return params
 }
 
 // --- Statements ---
@@ -240,7 +320,7 @@ func (p *Parser) parseStmt() Stmt {
 		return p.parseConstStmt()
 	case token.RETURN:
 		return p.parseReturnStmt()
-	case token.DECREE:
+	case token.DECREE, token.UNDECREE:
 		return p.parseDecreeStmt()
 	default:
 		return p.parseExprStmt()
@@ -267,6 +347,9 @@ func (p *Parser) parseLetStmt() *LetStmt {
 	}
 	p.nextToken() // move past =
 	stmt.Value = p.parseExpression(precLowest)
+	if stmt.Value == nil {
+		p.skipStmt()
+	}
 	if p.curIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -293,6 +376,9 @@ func (p *Parser) parseConstStmt() *ConstStmt {
 	}
 	p.nextToken() // move past =
 	stmt.Value = p.parseExpression(precLowest)
+	if stmt.Value == nil {
+		p.skipStmt()
+	}
 	if p.curIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -303,14 +389,27 @@ func (p *Parser) parseReturnStmt() *ReturnStmt {
 	stmt := &ReturnStmt{Token: p.curToken}
 	p.nextToken() // move past return
 	stmt.Value = p.parseExpression(precLowest)
+	if stmt.Value == nil {
+		p.skipStmt()
+	}
 	if p.curIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
+// skipStmt moves past the rest of a statement whose value failed to parse,
+// up to a ; or } or the end of the line, so the leftover tokens are not
+// parsed and reported again as statements of their own.
+func (p *Parser) skipStmt() {
+	line := p.curToken.Line
+	for p.curToken.Line == line && !p.curIs(token.SEMICOLON) && !p.curIs(token.RBRACE) && !p.curIs(token.EOF) {
+		p.nextToken()
+	}
+}
+
 func (p *Parser) parseDecreeStmt() *DecreeStmt {
-	stmt := &DecreeStmt{Token: p.curToken}
+	stmt := &DecreeStmt{Token: p.curToken, Revoke: p.curIs(token.UNDECREE)}
 	if !p.expectPeek(token.STRING) {
 		return nil
 	}
@@ -359,29 +458,38 @@ func (p *Parser) curPrecedence() int {
 	return tokenPrecedence(p.curToken.Type)
 }
 
-func tokenPrecedence(t token.TokenType) int {
-	switch t {
-	case token.ASSIGN:
-		return precAssign
-	case token.OR:
-		return precOr
-	case token.AND:
-		return precAnd
-	case token.EQ, token.STRICT_EQ, token.NEQ:
-		return precEquality
-	case token.LT, token.GT, token.LTE, token.GTE:
-		return precComparison
-	case token.PLUS, token.MINUS:
-		return precSum
-	case token.STAR, token.SLASH, token.PERCENT:
-		return precProduct
-	case token.LPAREN, token.LBRACKET, token.DOT, token.QUESTION, token.AS:
-		return precPostfix
-	default:
-		return 0
+// precedences maps each infix or postfix operator token to its binding power.
+// Tokens absent from the table bind at precLowest, ending the Pratt loop.
+// Adding an operator is a registerPrecedence call here plus its case in
+// parseInfixExpr.
+var precedences = map[token.TokenType]int{}
+
+func init() {
+	registerPrecedence(precAssign, token.ASSIGN)
+	registerPrecedence(precOr, token.OR)
+	registerPrecedence(precAnd, token.AND)
+	registerPrecedence(precEquality, token.EQ, token.STRICT_EQ, token.NEQ)
+	registerPrecedence(precComparison, token.LT, token.GT, token.LTE, token.GTE)
+	registerPrecedence(precSum, token.PLUS, token.MINUS)
+	registerPrecedence(precProduct, token.STAR, token.SLASH, token.FLOOR_DIV, token.PERCENT)
+	registerPrecedence(precPostfix, token.LPAREN, token.LBRACKET, token.DOT, token.QUESTION, token.AS)
+}
+
+// registerPrecedence assigns prec to each of types. Registering a token twice
+// is a programming error and panics, so precedence clashes surface at startup.
+func registerPrecedence(prec int, types ...token.TokenType) {
+	for _, t := range types {
+		if old, ok := precedences[t]; ok {
+			panic(fmt.Sprintf("precedence for %s registered twice (%d and %d)", t, old, prec))
+		}
+		precedences[t] = prec
 	}
 }
 
+func tokenPrecedence(t token.TokenType) int {
+	return precedences[t]
+}
+
 // spec:SEC-3
 func (p *Parser) parsePrefixExpr() Expr {
 	switch p.curToken.Type {
@@ -411,6 +519,8 @@ func (p *Parser) parsePrefixExpr() Expr {
 		return p.parseMatchExpr()
 	case token.GUARD:
 		return p.parseGuardExpr()
+	case token.FOR:
+		return p.parseForExpr()
 	case token.OK:
 		return p.parseOkExpr()
 	case token.ERR:
@@ -429,10 +539,20 @@ func (p *Parser) parsePrefixExpr() Expr {
 		return p.parseSpawnExpr()
 	case token.AWAIT_ALL:
 		return p.parseAwaitAllExpr()
+	case token.SELECT:
+		return p.parseSelectExpr()
+	case token.LAZY:
+		return p.parseLazyExpr()
 	case token.INVOKE:
 		return p.parseInvokeExpr()
 	case token.ALIGN:
 		return p.parseAlignExpr()
+	case token.ILLEGAL:
+		if !p.l.Reported(p.curToken) {
+			p.addError(fmt.Sprintf("unexpected token %s (%q)", p.curToken.Type, p.curToken.Literal))
+		}
+		p.nextToken() // skip the malformed token so parsing can make progress
+		return nil
 	default:
 		p.addError(fmt.Sprintf("unexpected token %s (%q)", p.curToken.Type, p.curToken.Literal))
 		return nil
@@ -441,7 +561,7 @@ func (p *Parser) parsePrefixExpr() Expr {
 
 func (p *Parser) parseInfixExpr(left Expr) Expr {
 	switch p.curToken.Type {
-	case token.PLUS, token.MINUS, token.STAR, token.SLASH, token.PERCENT,
+	case token.PLUS, token.MINUS, token.STAR, token.SLASH, token.FLOOR_DIV, token.PERCENT,
 		token.EQ, token.STRICT_EQ, token.NEQ,
 		token.LT, token.GT, token.LTE, token.GTE,
 		token.AND, token.OR:
@@ -556,6 +676,10 @@ func (p *Parser) parsePropagateExpr(left Expr) Expr {
 		Inner: left,
 	}
 	p.nextToken() // move past ?
+	if p.curIs(token.ELSE) && !p.elseEnds {
+		p.nextToken() // move past else
+		expr.Context = p.parseExpression(precLowest)
+	}
 	return expr
 }
 
@@ -638,6 +762,10 @@ func (p *Parser) parseUnaryExpr() Expr {
 }
 
 func (p *Parser) parseGroupedExpr() Expr {
+	saved, savedElse := p.commaEnds, p.elseEnds
+	p.commaEnds, p.elseEnds = false, false
+	defer func() { p.commaEnds, p.elseEnds = saved, savedElse }()
+
 	p.nextToken() // skip (
 	expr := p.parseExpression(precLowest)
 	if !p.curIs(token.RPAREN) {
@@ -658,6 +786,10 @@ func (p *Parser) parseArrayLitExpr() Expr {
 // curToken is on the opening delimiter (e.g., ( or [).
 // Returns with curToken on the token AFTER the closing delimiter.
 func (p *Parser) parseExprList(end token.TokenType) []Expr {
+	saved, savedElse := p.commaEnds, p.elseEnds
+	p.commaEnds, p.elseEnds = true, false
+	defer func() { p.commaEnds, p.elseEnds = saved, savedElse }()
+
 	var list []Expr
 	p.nextToken() // move past opening delimiter
 	if p.curIs(end) {
@@ -666,14 +798,24 @@ func (p *Parser) parseExprList(end token.TokenType) []Expr {
 	}
 	list = append(list, p.parseExpression(precLowest))
 	for p.curIs(token.COMMA) {
+		comma := p.curToken
 		p.nextToken() // skip comma
 		if p.curIs(end) {
-			break // trailing comma
+			// A trailing comma suits a list split over lines; on one line
+			// it usually means an element was left out.
+			if p.curToken.Line == comma.Line {
+				p.addWarning(comma, fmt.Sprintf("trailing comma before %s on the same line", p.curToken.Literal))
+			}
+			break
 		}
 		list = append(list, p.parseExpression(precLowest))
 	}
 	if !p.curIs(end) {
-		p.addError(fmt.Sprintf("expected %s, got %s", end, p.curToken.Type))
+		closer := ")"
+		if end == token.RBRACKET {
+			closer = "]"
+		}
+		p.errorExpected(",", closer)
 		return list
 	}
 	p.nextToken() // move past closing delimiter
@@ -690,9 +832,12 @@ func (p *Parser) parseBlockOrMap() Expr {
 }
 
 // isMapLiteral peeks ahead to decide if { starts a map literal.
-// Map: { STRING : ... } or { IDENT/OK/ERR : ... } or { INT/FLOAT/BOOL/NIL : ... }
+// Map: {} or { STRING : ... } or { IDENT/OK/ERR : ... } or { INT/FLOAT/BOOL/NIL : ... }
+// An empty {} is only a map in expression position; function bodies, if/else
+// branches and other places that require a block call parseBlockExpr directly
+// and never get here.
 func (p *Parser) isMapLiteral() bool {
-	if p.peekIs(token.STRING) {
+	if p.peekIs(token.STRING) || p.peekIs(token.RBRACE) {
 		return true
 	}
 	// For all other key types, check if two tokens ahead is COLON.
@@ -700,11 +845,36 @@ func (p *Parser) isMapLiteral() bool {
 	case token.IDENT, token.OK, token.ERR,
 		token.INT, token.FLOAT, token.TRUE, token.FALSE, token.NIL:
 		return p.peekAhead(2).Type == token.COLON
+	case token.MINUS:
+		// Negative number key: { -1: "a" }
+		next := p.peekAhead(2).Type
+		return (next == token.INT || next == token.FLOAT) && p.peekAhead(3).Type == token.COLON
+	case token.LPAREN:
+		// Computed key: { (1 + 1): "b" }. Skip to the matching ) and
+		// check for the colon after it.
+		depth := 0
+		for n := 1; ; n++ {
+			switch p.peekAhead(n).Type {
+			case token.LPAREN:
+				depth++
+			case token.RPAREN:
+				depth--
+				if depth == 0 {
+					return p.peekAhead(n+1).Type == token.COLON
+				}
+			case token.EOF:
+				return false
+			}
+		}
 	}
 	return false
 }
 
 func (p *Parser) parseMapLitExpr() Expr {
+	saved, savedElse := p.commaEnds, p.elseEnds
+	p.commaEnds, p.elseEnds = true, false
+	defer func() { p.commaEnds, p.elseEnds = saved, savedElse }()
+
 	expr := &MapLitExpr{Token: p.curToken}
 	p.nextToken() // move past {
 	for !p.curIs(token.RBRACE) && !p.curIs(token.EOF) {
@@ -738,11 +908,15 @@ func (p *Parser) parseBlockExpr() *BlockExpr {
 		p.addError(fmt.Sprintf("expected {, got %s (%q)", p.curToken.Type, p.curToken.Literal))
 		return nil
 	}
+	saved, savedElse := p.commaEnds, p.elseEnds
+	p.commaEnds, p.elseEnds = false, false
+	defer func() { p.commaEnds, p.elseEnds = saved, savedElse }()
+
 	block := &BlockExpr{Token: p.curToken}
 	p.nextToken() // move past {
 
 	for !p.curIs(token.RBRACE) && !p.curIs(token.EOF) {
-		if p.curIs(token.LET) || p.curIs(token.CONST) || p.curIs(token.RETURN) || p.curIs(token.DECREE) {
+		if p.curIs(token.LET) || p.curIs(token.CONST) || p.curIs(token.RETURN) || p.curIs(token.DECREE) || p.curIs(token.UNDECREE) {
 			stmt := p.parseStmt()
 			if stmt != nil {
 				block.Stmts = append(block.Stmts, stmt)
@@ -807,6 +981,9 @@ func (p *Parser) parseIfExpr() Expr {
 func (p *Parser) parseMatchExpr() Expr {
 	expr := &MatchExpr{Token: p.curToken}
 	p.nextToken() // move past match
+	if p.curIs(token.LBRACE) {
+		return p.parseCondMatch(expr)
+	}
 	expr.Subject = p.parseExpression(precLowest)
 
 	if !p.curIs(token.LBRACE) {
@@ -825,17 +1002,61 @@ func (p *Parser) parseMatchExpr() Expr {
 	return expr
 }
 
+// parseCondMatch parses the arms of a subject-less match, where each arm is
+// a condition rather than a pattern: match { x < 0 => "neg", _ => "other" }.
+func (p *Parser) parseCondMatch(expr *MatchExpr) Expr {
+	p.nextToken() // move past {
+	for !p.curIs(token.RBRACE) && !p.curIs(token.EOF) {
+		arm := MatchArm{}
+		if p.curIs(token.IDENT) && p.curToken.Literal == "_" && p.peekIs(token.ARROW) {
+			arm.Pattern = &WildcardPattern{Token: p.curToken}
+			p.nextToken()
+		} else {
+			arm.Cond = p.parseExpression(precLowest)
+		}
+		if !p.curIs(token.ARROW) {
+			p.addError(fmt.Sprintf("expected =>, got %s (%q)", p.curToken.Type, p.curToken.Literal))
+			return nil
+		}
+		p.nextToken() // move past =>
+
+		saved := p.commaEnds
+		p.commaEnds = true
+		arm.Body = p.parseExpression(precLowest)
+		p.commaEnds = saved
+
+		if p.curIs(token.COMMA) || p.curIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		expr.Arms = append(expr.Arms, arm)
+	}
+	if p.curIs(token.RBRACE) {
+		p.nextToken() // move past }
+	}
+	return expr
+}
+
 func (p *Parser) parseMatchArm() MatchArm {
 	arm := MatchArm{}
 	arm.Pattern = p.parsePattern()
 
 	if !p.curIs(token.ARROW) {
-		p.addError(fmt.Sprintf("expected =>, got %s (%q)", p.curToken.Type, p.curToken.Literal))
+		switch arm.Pattern.(type) {
+		case *GuardedPattern:
+			p.errorExpected("=>")
+		case *IdentPattern:
+			p.errorExpected("=>", "if", ":")
+		default:
+			p.errorExpected("=>", "if")
+		}
 		return arm
 	}
 	p.nextToken() // move past =>
 
+	saved := p.commaEnds
+	p.commaEnds = true
 	arm.Body = p.parseExpression(precLowest)
+	p.commaEnds = saved
 
 	if p.curIs(token.COMMA) || p.curIs(token.SEMICOLON) {
 		p.nextToken()
@@ -844,6 +1065,13 @@ func (p *Parser) parseMatchArm() MatchArm {
 }
 
 func (p *Parser) parsePattern() Pattern {
+	if p.curIs(token.LBRACKET) {
+		return p.maybeGuardedPattern(p.parseArrayPattern())
+	}
+	if p.curIs(token.LBRACE) {
+		return p.maybeGuardedPattern(p.parseMapPattern())
+	}
+
 	// _ is wildcard
 	if p.curIs(token.IDENT) && p.curToken.Literal == "_" {
 		pat := &WildcardPattern{Token: p.curToken}
@@ -851,21 +1079,20 @@ func (p *Parser) parsePattern() Pattern {
 		return p.maybeGuardedPattern(pat)
 	}
 
-	// ok(v) / err(e) destructuring patterns in match arms
+	// ok(v) / err(e) destructuring patterns in match arms; the payload may
+	// be any pattern, so ok([a, b]) destructures an ok-wrapped array.
 	if (p.curIs(token.OK) || p.curIs(token.ERR)) && p.peekIs(token.LPAREN) {
-		name := p.curToken.Literal
-		tok := p.curToken
+		pat := &ResultPattern{Token: p.curToken}
 		p.nextToken() // skip ok/err
 		p.nextToken() // skip (
-		inner := ""
-		if p.curIs(token.IDENT) {
-			inner = p.curToken.Literal
-			p.nextToken()
+		if !p.curIs(token.RPAREN) {
+			pat.Inner = p.parsePattern()
 		}
-		if p.curIs(token.RPAREN) {
-			p.nextToken() // skip )
+		if !p.curIs(token.RPAREN) {
+			p.addError(fmt.Sprintf("expected ) in %s pattern, got %s", pat.Token.Literal, p.curToken.Type))
+			return nil
 		}
-		pat := &IdentPattern{Token: tok, Name: name + "(" + inner + ")"}
+		p.nextToken() // skip )
 		return p.maybeGuardedPattern(pat)
 	}
 
@@ -905,6 +1132,69 @@ func (p *Parser) parsePattern() Pattern {
 	return &WildcardPattern{Token: p.curToken}
 }
 
+// parseArrayPattern parses [p1, p2, ..rest]. The rest binding, if any, must
+// come last.
+func (p *Parser) parseArrayPattern() Pattern {
+	pat := &ArrayPattern{Token: p.curToken}
+	p.nextToken() // move past [
+	for !p.curIs(token.RBRACKET) && !p.curIs(token.EOF) {
+		if p.curIs(token.DOT) && p.peekIs(token.DOT) {
+			p.nextToken() // move to second .
+			if !p.expectPeek(token.IDENT) {
+				return pat
+			}
+			pat.Rest = p.curToken.Literal
+			pat.HasRest = true
+			p.nextToken() // move past rest name
+			if !p.curIs(token.RBRACKET) {
+				p.addError(fmt.Sprintf("rest pattern ..%s must be last, got %s", pat.Rest, p.curToken.Type))
+				return pat
+			}
+			break
+		}
+		pat.Elems = append(pat.Elems, p.parsePattern())
+		if p.curIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.curIs(token.RBRACKET) {
+			p.errorExpected(",", "]")
+			return pat
+		}
+	}
+	if p.curIs(token.RBRACKET) {
+		p.nextToken() // move past ]
+	}
+	return pat
+}
+
+// parseMapPattern parses { "key": pattern, ... }.
+func (p *Parser) parseMapPattern() Pattern {
+	pat := &MapPattern{Token: p.curToken}
+	p.nextToken() // move past {
+	for !p.curIs(token.RBRACE) && !p.curIs(token.EOF) {
+		if !p.curIs(token.STRING) {
+			p.addError(fmt.Sprintf("expected string key in map pattern, got %s", p.curToken.Type))
+			return pat
+		}
+		key := p.curToken.Literal
+		if !p.expectPeek(token.COLON) {
+			return pat
+		}
+		p.nextToken() // move past :
+		pat.Keys = append(pat.Keys, key)
+		pat.Values = append(pat.Values, p.parsePattern())
+		if p.curIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.curIs(token.RBRACE) {
+			p.errorExpected(",", "}")
+			return pat
+		}
+	}
+	if p.curIs(token.RBRACE) {
+		p.nextToken() // move past }
+	}
+	return pat
+}
+
 func (p *Parser) maybeGuardedPattern(inner Pattern) Pattern {
 	if p.curIs(token.IF) {
 		tok := p.curToken
@@ -919,7 +1209,10 @@ func (p *Parser) maybeGuardedPattern(inner Pattern) Pattern {
 func (p *Parser) parseGuardExpr() Expr {
 	expr := &GuardExpr{Token: p.curToken}
 	p.nextToken() // move past guard
+	savedElse := p.elseEnds
+	p.elseEnds = true
 	expr.Condition = p.parseExpression(precLowest)
+	p.elseEnds = savedElse
 	if !p.curIs(token.ELSE) {
 		p.addError(fmt.Sprintf("expected else after guard condition, got %s", p.curToken.Type))
 		return nil
@@ -929,6 +1222,29 @@ func (p *Parser) parseGuardExpr() Expr {
 	return expr
 }
 
+// parseForExpr parses for x in collection { ... }. "in" is not a keyword, so
+// it is matched by spelling.
+func (p *Parser) parseForExpr() Expr {
+	expr := &ForExpr{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	expr.Var = p.curToken.Literal
+	p.nextToken() // move past the loop variable
+	if !p.curIs(token.IDENT) || p.curToken.Literal != "in" {
+		p.addError(fmt.Sprintf("expected in after for variable, got %s (%q)", p.curToken.Type, p.curToken.Literal))
+		return nil
+	}
+	p.nextToken() // move past in
+	expr.Iterable = p.parseExpression(precLowest)
+	body := p.parseBlockExpr()
+	if body == nil {
+		return nil
+	}
+	expr.Body = body
+	return expr
+}
+
 func (p *Parser) parseOkExpr() Expr {
 	tok := p.curToken
 	if !p.peekIs(token.LPAREN) {
@@ -968,13 +1284,26 @@ func (p *Parser) parseErrExpr() Expr {
 func (p *Parser) parseSpeakExpr() Expr {
 	tok := p.curToken
 	p.nextToken() // move past speak
-	value := p.parseExpression(precLowest)
+	savedElse := p.elseEnds
+	p.elseEnds = true
+	values := []Expr{p.parseExpression(precLowest)}
+	for !p.commaEnds && p.curIs(token.COMMA) {
+		p.nextToken() // skip comma
+		values = append(values, p.parseExpression(precLowest))
+	}
+	// "to" is contextual, not a keyword, so it stays usable as an identifier.
+	var stream Expr
+	if p.curIs(token.IDENT) && p.curToken.Literal == "to" {
+		p.nextToken() // move past to
+		stream = p.parseExpression(precLowest)
+	}
+	p.elseEnds = savedElse
 	var elseBody Expr
 	if p.curIs(token.ELSE) {
 		p.nextToken() // move past else
 		elseBody = p.parseExpression(precLowest)
 	}
-	return &SpeakExpr{Token: tok, Value: value, ElseBody: elseBody}
+	return &SpeakExpr{Token: tok, Values: values, Stream: stream, ElseBody: elseBody}
 }
 
 func (p *Parser) parseSorryExpr() Expr {
@@ -1002,12 +1331,17 @@ func (p *Parser) parseDoomExpr() Expr {
 	}
 	p.nextToken() // move past (
 	msg := p.parseExpression(precLowest)
+	var payload Expr
+	if p.curIs(token.COMMA) {
+		p.nextToken() // move past ,
+		payload = p.parseExpression(precLowest)
+	}
 	if !p.curIs(token.RPAREN) {
 		p.addError(fmt.Sprintf("expected ) in doom(), got %s", p.curToken.Type))
 		return nil
 	}
 	p.nextToken() // move past )
-	return &DoomExpr{Token: tok, Message: msg}
+	return &DoomExpr{Token: tok, Message: msg, Payload: payload}
 }
 
 func (p *Parser) parseChantExpr() Expr {
@@ -1028,18 +1362,100 @@ func (p *Parser) parseSpawnExpr() Expr {
 	return &SpawnExpr{Token: tok, Body: body}
 }
 
-func (p *Parser) parseAwaitAllExpr() Expr {
+func (p *Parser) parseLazyExpr() Expr {
 	tok := p.curToken
-	if p.peekIs(token.LPAREN) {
-		p.nextToken() // move to (
-		if !p.expectPeek(token.RPAREN) {
+	p.nextToken() // move past lazy
+	body := p.parseBlockExpr()
+	if body == nil {
+		return nil
+	}
+	return &LazyExpr{Token: tok, Body: body}
+}
+
+func (p *Parser) parseAwaitAllExpr() Expr {
+	expr := &AwaitAllExpr{Token: p.curToken}
+	if !p.peekIs(token.LPAREN) {
+		p.nextToken() // move past await_all keyword
+		return expr
+	}
+	p.nextToken() // move to (
+	p.nextToken() // move past (
+	if !p.curIs(token.RPAREN) {
+		expr.Timeout = p.parseExpression(precLowest)
+		if !p.curIs(token.RPAREN) {
+			p.addError(fmt.Sprintf("expected ) after await_all timeout, got %s", p.curToken.Type))
+			return nil
+		}
+	}
+	p.nextToken() // move past )
+	return expr
+}
+
+func (p *Parser) parseSelectExpr() Expr {
+	expr := &SelectExpr{Token: p.curToken}
+	p.nextToken() // move past select
+	if !p.curIs(token.LBRACE) {
+		p.addError(fmt.Sprintf("expected { after select, got %s", p.curToken.Type))
+		return nil
+	}
+	p.nextToken() // move past {
+
+	for !p.curIs(token.RBRACE) && !p.curIs(token.EOF) {
+		arm, ok := p.parseSelectArm()
+		if !ok {
 			return nil
 		}
+		expr.Arms = append(expr.Arms, arm)
+	}
+	if p.curIs(token.RBRACE) {
+		p.nextToken() // move past }
+	}
+	return expr
+}
+
+func (p *Parser) parseSelectArm() (SelectArm, bool) {
+	arm := SelectArm{Token: p.curToken, Op: p.curToken.Literal}
+	switch {
+	case p.curIs(token.IDENT) && arm.Op == "default":
+		p.nextToken() // move past default
+	case p.curIs(token.IDENT) && (arm.Op == "recv" || arm.Op == "await"):
+		if !p.expectPeek(token.LPAREN) {
+			return arm, false
+		}
+		p.nextToken() // move past (
+		arm.Source = p.parseExpression(precLowest)
+		if !p.curIs(token.RPAREN) {
+			p.addError(fmt.Sprintf("expected ) after %s source, got %s", arm.Op, p.curToken.Type))
+			return arm, false
+		}
 		p.nextToken() // move past )
-	} else {
-		p.nextToken() // move past await_all keyword
+		if p.curIs(token.AS) {
+			if !p.expectPeek(token.IDENT) {
+				return arm, false
+			}
+			arm.Name = p.curToken.Literal
+			p.nextToken() // move past name
+		}
+	default:
+		p.addError(fmt.Sprintf("expected recv(...), await(...) or default in select, got %s (%q)", p.curToken.Type, p.curToken.Literal))
+		return arm, false
 	}
-	return &AwaitAllExpr{Token: tok}
+
+	if !p.curIs(token.ARROW) {
+		p.addError(fmt.Sprintf("expected =>, got %s (%q)", p.curToken.Type, p.curToken.Literal))
+		return arm, false
+	}
+	p.nextToken() // move past =>
+
+	saved := p.commaEnds
+	p.commaEnds = true
+	arm.Body = p.parseExpression(precLowest)
+	p.commaEnds = saved
+
+	if p.curIs(token.COMMA) || p.curIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return arm, true
 }
 
 // spec:SEC-6-4
@@ -1052,9 +1468,8 @@ func (p *Parser) parseSigilDecl() *SigilDecl {
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
-	decl.Params = p.parseParamList()
-	if !p.curIs(token.RPAREN) {
-		p.addError(fmt.Sprintf("expected ), got %s", p.curToken.Type))
+	var ok bool
+	if decl.Params, ok = p.parseParamList(); !ok {
 		return nil
 	}
 	p.nextToken() // move past )
@@ -1159,7 +1574,8 @@ func (p *Parser) parseAlignExpr() Expr {
 	node.Rows = rows
 	// Re-fetch peekToken: it was obtained in align mode and may be a
 	// TAB/NEWLINE token that doesn't exist in normal mode.
-	p.buffered = nil
+	p.buffered = p.buffered[:0]
+	p.bufPos = 0
 	p.peekToken = p.l.NextToken()
 	p.nextToken() // move past }
 	return node
@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/joeabbey/morgoth/internal/lexer"
+	"github.com/joeabbey/morgoth/internal/token"
 )
 
 func parse(t *testing.T, input string) *Program {
@@ -100,6 +103,42 @@ func TestDecreeStmt(t *testing.T) {
 	}
 }
 
+func TestUndecreeStmt(t *testing.T) {
+	prog := parse(t, "undecree \"zero_indexed\"\n{ undecree \"soft_casts\"; 1 }")
+	stmt, ok := prog.Items[0].(*DecreeStmt)
+	if !ok {
+		t.Fatalf("expected *DecreeStmt, got %T", prog.Items[0])
+	}
+	if !stmt.Revoke || stmt.Value != "zero_indexed" {
+		t.Errorf("got revoke=%v value=%s, want an undecree of zero_indexed", stmt.Revoke, stmt.Value)
+	}
+	block := prog.Items[1].(*ExprStmt).Expression.(*BlockExpr)
+	if inner, ok := block.Stmts[0].(*DecreeStmt); !ok || !inner.Revoke {
+		t.Errorf("expected undecree inside the block, got %T", block.Stmts[0])
+	}
+}
+
+func TestUseExpandsToChant(t *testing.T) {
+	prog := parse(t, "use \"stdio\"\nfn use() { 1 }\nuse()")
+	if len(prog.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(prog.Items))
+	}
+	c, ok := prog.Items[0].(*ExprStmt).Expression.(*ChantExpr)
+	if !ok {
+		t.Fatalf("expected *ChantExpr, got %T", prog.Items[0].(*ExprStmt).Expression)
+	}
+	if name := c.Name.(*StringLitExpr).Value; name != "stdio" || c.Token.Type != token.CHANT {
+		t.Errorf("got chant %q with token %s, want chant \"stdio\"", name, c.Token.Type)
+	}
+	// use is still an ordinary name when no string follows it.
+	if _, ok := prog.Items[1].(*FnDecl); !ok {
+		t.Errorf("expected *FnDecl, got %T", prog.Items[1])
+	}
+	if _, ok := prog.Items[2].(*ExprStmt).Expression.(*CallExpr); !ok {
+		t.Errorf("expected a call to use, got %T", prog.Items[2].(*ExprStmt).Expression)
+	}
+}
+
 // --- Expression precedence tests ---
 
 func TestBinaryPrecedence(t *testing.T) {
@@ -226,6 +265,30 @@ func TestMatchExpr(t *testing.T) {
 	}
 }
 
+func TestMatchWithoutSubject(t *testing.T) {
+	prog := parse(t, `match {
+		x < 0 => "negative",
+		x == 0 => "zero",
+		_ => "positive",
+	};`)
+	m, ok := prog.Items[0].(*ExprStmt).Expression.(*MatchExpr)
+	if !ok {
+		t.Fatalf("expected *MatchExpr, got %T", prog.Items[0].(*ExprStmt).Expression)
+	}
+	if m.Subject != nil {
+		t.Errorf("expected no subject, got %T", m.Subject)
+	}
+	if len(m.Arms) != 3 {
+		t.Fatalf("expected 3 arms, got %d", len(m.Arms))
+	}
+	if _, ok := m.Arms[0].Cond.(*BinaryExpr); !ok || m.Arms[0].Pattern != nil {
+		t.Errorf("expected a condition arm, got cond %T pattern %T", m.Arms[0].Cond, m.Arms[0].Pattern)
+	}
+	if _, ok := m.Arms[2].Pattern.(*WildcardPattern); !ok || m.Arms[2].Cond != nil {
+		t.Errorf("expected a wildcard arm, got cond %T pattern %T", m.Arms[2].Cond, m.Arms[2].Pattern)
+	}
+}
+
 func TestMatchTypedPattern(t *testing.T) {
 	input := `match x {
 		n: int => n,
@@ -262,6 +325,32 @@ func TestMatchGuardedPattern(t *testing.T) {
 	}
 }
 
+func TestMatchResultPattern(t *testing.T) {
+	prog := parse(t, `match x {
+		ok([a, b]) => a + b,
+		err(e) => e,
+		ok() => 0,
+	};`)
+	m := prog.Items[0].(*ExprStmt).Expression.(*MatchExpr)
+	rp, ok := m.Arms[0].Pattern.(*ResultPattern)
+	if !ok {
+		t.Fatalf("expected *ResultPattern, got %T", m.Arms[0].Pattern)
+	}
+	if rp.Token.Literal != "ok" {
+		t.Errorf("expected ok pattern, got %s", rp.Token.Literal)
+	}
+	if ap, ok := rp.Inner.(*ArrayPattern); !ok || len(ap.Elems) != 2 {
+		t.Errorf("expected a 2-element ArrayPattern inside ok, got %T", rp.Inner)
+	}
+	ep := m.Arms[1].Pattern.(*ResultPattern)
+	if ip, ok := ep.Inner.(*IdentPattern); ep.Token.Literal != "err" || !ok || ip.Name != "e" {
+		t.Errorf("expected err(e), got %s(%T)", ep.Token.Literal, ep.Inner)
+	}
+	if bare := m.Arms[2].Pattern.(*ResultPattern); bare.Inner != nil {
+		t.Errorf("expected no inner pattern for ok(), got %T", bare.Inner)
+	}
+}
+
 func TestGuardExpr(t *testing.T) {
 	input := `guard x >= 2 else doom("too small");`
 	prog := parse(t, input)
@@ -278,6 +367,41 @@ func TestGuardExpr(t *testing.T) {
 	}
 }
 
+func TestForExpr(t *testing.T) {
+	tests := []struct {
+		input    string
+		iterable string // Go type of the iterable expression
+	}{
+		{`for x in [1, 2, 3] { speak x };`, "*parser.ArrayLitExpr"},
+		{`for c in "abc" { speak c };`, "*parser.StringLitExpr"},
+		{`for k in { "a": 1 } { speak k };`, "*parser.MapLitExpr"},
+		{`for x in xs { };`, "*parser.IdentExpr"},
+	}
+	for _, tt := range tests {
+		prog := parse(t, tt.input)
+		es := prog.Items[0].(*ExprStmt)
+		f, ok := es.Expression.(*ForExpr)
+		if !ok {
+			t.Fatalf("%q: expected *ForExpr, got %T", tt.input, es.Expression)
+		}
+		if f.Var == "" {
+			t.Errorf("%q: expected loop variable", tt.input)
+		}
+		if got := fmt.Sprintf("%T", f.Iterable); got != tt.iterable {
+			t.Errorf("%q: iterable is %s, want %s", tt.input, got, tt.iterable)
+		}
+		if f.Body == nil {
+			t.Errorf("%q: expected loop body", tt.input)
+		}
+	}
+
+	for _, input := range []string{`for in xs { }`, `for x of xs { }`, `for x in xs speak x`} {
+		if _, errs := parseExpectErrors(input); len(errs) == 0 {
+			t.Errorf("%q: expected parse errors", input)
+		}
+	}
+}
+
 func TestOkErrExpr(t *testing.T) {
 	prog := parse(t, `ok(42);`)
 	es := prog.Items[0].(*ExprStmt)
@@ -326,6 +450,38 @@ func TestSpeakWithoutElse(t *testing.T) {
 	}
 }
 
+func TestSpeakMultipleValues(t *testing.T) {
+	prog := parse(t, `speak a, "b", 1 + 2 else doom("fail");`)
+	sp := prog.Items[0].(*ExprStmt).Expression.(*SpeakExpr)
+	if len(sp.Values) != 3 {
+		t.Fatalf("expected 3 values, got %d", len(sp.Values))
+	}
+	if _, ok := sp.Values[2].(*BinaryExpr); !ok {
+		t.Errorf("expected third value *BinaryExpr, got %T", sp.Values[2])
+	}
+	if sp.ElseBody == nil {
+		t.Fatal("expected else body")
+	}
+}
+
+func TestSpeakInMatchArmStopsAtComma(t *testing.T) {
+	prog := parse(t, `match x {
+  1 => speak "one", 
+  _ => { speak "other", x },
+}`)
+	m := prog.Items[0].(*ExprStmt).Expression.(*MatchExpr)
+	if len(m.Arms) != 2 {
+		t.Fatalf("expected 2 arms, got %d", len(m.Arms))
+	}
+	if n := len(m.Arms[0].Body.(*SpeakExpr).Values); n != 1 {
+		t.Errorf("arm 0: expected 1 speak value, got %d", n)
+	}
+	block := m.Arms[1].Body.(*BlockExpr)
+	if n := len(block.FinalExpr.(*SpeakExpr).Values); n != 2 {
+		t.Errorf("arm 1: expected 2 speak values inside block, got %d", n)
+	}
+}
+
 func TestSorryExpr(t *testing.T) {
 	prog := parse(t, `sorry(y);`)
 	es := prog.Items[0].(*ExprStmt)
@@ -351,6 +507,22 @@ func TestDoomExpr(t *testing.T) {
 	}
 }
 
+func TestDoomExprPayload(t *testing.T) {
+	prog := parse(t, `doom("error", { "code": 7 });`)
+	d := prog.Items[0].(*ExprStmt).Expression.(*DoomExpr)
+	if d.Message.(*StringLitExpr).Value != "error" {
+		t.Errorf("expected error, got %s", d.Message)
+	}
+	if _, ok := d.Payload.(*MapLitExpr); !ok {
+		t.Errorf("expected map payload, got %T", d.Payload)
+	}
+
+	plain := parse(t, `doom("error");`).Items[0].(*ExprStmt).Expression.(*DoomExpr)
+	if plain.Payload != nil {
+		t.Errorf("expected no payload, got %T", plain.Payload)
+	}
+}
+
 func TestChantExpr(t *testing.T) {
 	prog := parse(t, `chant "stdio";`)
 	es := prog.Items[0].(*ExprStmt)
@@ -377,6 +549,28 @@ func TestPropagateExpr(t *testing.T) {
 	}
 }
 
+func TestPropagateContext(t *testing.T) {
+	prop := parse(t, `x? else "reading x";`).Items[0].(*ExprStmt).Expression.(*PropagateExpr)
+	if ctx, ok := prop.Context.(*StringLitExpr); !ok || ctx.Value != "reading x" {
+		t.Errorf("expected context \"reading x\", got %T", prop.Context)
+	}
+
+	// guard and speak keep their own else clauses.
+	guard := parse(t, `guard x? else 1;`).Items[0].(*ExprStmt).Expression.(*GuardExpr)
+	if inner := guard.Condition.(*PropagateExpr); inner.Context != nil || guard.ElseBody == nil {
+		t.Errorf("guard else was taken as propagation context")
+	}
+	speak := parse(t, `speak x? else 1;`).Items[0].(*ExprStmt).Expression.(*SpeakExpr)
+	if inner := speak.Values[0].(*PropagateExpr); inner.Context != nil || speak.ElseBody == nil {
+		t.Errorf("speak else was taken as propagation context")
+	}
+	// Parentheses restore the context form inside them.
+	guard = parse(t, `guard (x? else "ctx") else 1;`).Items[0].(*ExprStmt).Expression.(*GuardExpr)
+	if inner := guard.Condition.(*PropagateExpr); inner.Context == nil {
+		t.Errorf("expected context inside parentheses")
+	}
+}
+
 func TestAsExpr(t *testing.T) {
 	prog := parse(t, `s as int;`)
 	es := prog.Items[0].(*ExprStmt)
@@ -524,6 +718,169 @@ func TestMapVsBlockDisambiguation(t *testing.T) {
 	}
 }
 
+func TestEmptyBracesByContext(t *testing.T) {
+	// In expression position {} is an empty map.
+	prog := parse(t, `let m = {};`)
+	let := prog.Items[0].(*LetStmt)
+	m, ok := let.Value.(*MapLitExpr)
+	if !ok {
+		t.Fatalf("expected *MapLitExpr, got %T", let.Value)
+	}
+	if len(m.Pairs) != 0 {
+		t.Errorf("expected no pairs, got %d", len(m.Pairs))
+	}
+
+	// Where a block is required, {} is an empty block.
+	prog = parse(t, `if c {} else { };`)
+	ifExpr := prog.Items[0].(*ExprStmt).Expression.(*IfExpr)
+	if len(ifExpr.Then.Stmts) != 0 || ifExpr.Then.FinalExpr != nil {
+		t.Errorf("expected empty then block, got %+v", ifExpr.Then)
+	}
+	if _, ok := ifExpr.Else.(*BlockExpr); !ok {
+		t.Errorf("expected else block, got %T", ifExpr.Else)
+	}
+
+	prog = parse(t, `fn f() {}`)
+	if fn := prog.Items[0].(*FnDecl); fn.Body == nil || fn.Body.FinalExpr != nil {
+		t.Errorf("expected empty function body")
+	}
+}
+
+// manyDisambiguations generates n pairs of let-bound map literals and blocks,
+// each of which forces a two-token lookahead in isMapLiteral.
+func manyDisambiguations(n int) string {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "let m%d = { k%d: %d }\n", i, i, i)
+		fmt.Fprintf(&sb, "let b%d = { x%d }\n", i, i)
+	}
+	return sb.String()
+}
+
+func TestManyMapBlockDisambiguations(t *testing.T) {
+	const n = 500
+	prog := parse(t, manyDisambiguations(n))
+	if len(prog.Items) != 2*n {
+		t.Fatalf("expected %d items, got %d", 2*n, len(prog.Items))
+	}
+	for i := 0; i < n; i++ {
+		m := prog.Items[2*i].(*LetStmt)
+		lit, ok := m.Value.(*MapLitExpr)
+		if !ok {
+			t.Fatalf("item %d: expected *MapLitExpr, got %T", 2*i, m.Value)
+		}
+		if len(lit.Pairs) != 1 {
+			t.Fatalf("item %d: expected 1 pair, got %d", 2*i, len(lit.Pairs))
+		}
+		if key := lit.Pairs[0].Key.(*IdentExpr).Name; key != fmt.Sprintf("k%d", i) {
+			t.Errorf("item %d: got key %s", 2*i, key)
+		}
+		if val := lit.Pairs[0].Value.(*IntLitExpr).Value; val != int64(i) {
+			t.Errorf("item %d: got value %d", 2*i, val)
+		}
+
+		b := prog.Items[2*i+1].(*LetStmt)
+		block, ok := b.Value.(*BlockExpr)
+		if !ok {
+			t.Fatalf("item %d: expected *BlockExpr, got %T", 2*i+1, b.Value)
+		}
+		if name := block.FinalExpr.(*IdentExpr).Name; name != fmt.Sprintf("x%d", i) {
+			t.Errorf("item %d: got block expr %s", 2*i+1, name)
+		}
+	}
+}
+
+func BenchmarkParseLargeProgram(b *testing.B) {
+	src := manyDisambiguations(5000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := New(lexer.New(src))
+		p.Parse()
+		if errs := p.Errors(); len(errs) > 0 {
+			b.Fatalf("parse errors: %v", errs[0])
+		}
+	}
+}
+
+func TestUnterminatedStringError(t *testing.T) {
+	_, errs := parseExpectErrors("speak \"hello\n")
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	want := "unterminated string literal starting at line 1"
+	if errs[0] != want {
+		t.Errorf("got %q, want %q", errs[0], want)
+	}
+}
+
+func TestUnterminatedBlockCommentError(t *testing.T) {
+	_, errs := parseExpectErrors("let x = 1\n#{ oops\nspeak x\n")
+	want := "unterminated block comment started at line 2"
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("got errors %q, want [%q]", errs, want)
+	}
+}
+
+func TestTrailingCommaWarning(t *testing.T) {
+	p := New(lexer.New("speak f(1, 2,)\nlet xs = [\n  1,\n  2,\n]\n"))
+	p.Parse()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("unexpected parse errors: %v", errs)
+	}
+	want := []string{"line 1 col 13: trailing comma before ) on the same line"}
+	if got := p.Warnings(); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("got warnings %q, want %q", got, want)
+	}
+}
+
+func TestExpectedOneOfErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"match x { 1 2 => \"a\" }", `line 1 col 13: expected one of "=>", "if"; got INT ("2")`},
+		{"match x { n 5 => n }", `line 1 col 13: expected one of "=>", "if", ":"; got INT ("5")`},
+		{"match x { n if n > 0 5 => n }", `line 1 col 22: expected one of "=>"; got INT ("5")`},
+		{"fn f(a b) { a }", `line 1 col 8: expected one of ",", ")", ":"; got IDENT ("b")`},
+		{"fn f(a: int b) { a }", `line 1 col 13: expected one of ",", ")"; got IDENT ("b")`},
+		{"fn f(a, 1) { a }", `line 1 col 9: expected one of identifier, "[", "{"; got INT ("1")`},
+		{"let g = fn(a; b) { a }", `line 1 col 13: expected one of ",", ")", ":"; got SEMICOLON (";")`},
+		{"f(1 2)", `line 1 col 5: expected one of ",", ")"; got INT ("2")`},
+		{"match x { [a b] => a }", `line 1 col 14: expected one of ",", "]"; got IDENT ("b")`},
+	}
+	for _, tt := range tests {
+		_, errs := parseExpectErrors(tt.source)
+		if len(errs) == 0 || errs[0] != tt.want {
+			t.Errorf("source %q: got errors %q, want first %q", tt.source, errs, tt.want)
+		}
+	}
+}
+
+func TestParseErrorsMakeProgress(t *testing.T) {
+	for _, src := range []string{"let = 5", "speak )", "const", "}", "fn 5", ")", "let x = 1 }"} {
+		_, errs := parseExpectErrors(src)
+		if len(errs) == 0 {
+			t.Errorf("source %q: expected parse errors", src)
+		}
+	}
+}
+
+func TestBadStmtValueReportedOnce(t *testing.T) {
+	tests := []string{
+		"let x = 9223372036854775808",
+		"const x = 9223372036854775808; speak 1",
+		"fn f() { return 9223372036854775808 }",
+		"fn f() {\n  let y = )\n  y\n}",
+	}
+	for _, src := range tests {
+		_, errs := parseExpectErrors(src)
+		if len(errs) != 1 {
+			t.Errorf("source %q: got %d errors %v, want exactly 1", src, len(errs), errs)
+		}
+	}
+}
+
 // --- Example file tests ---
 
 func TestExampleFiles(t *testing.T) {
@@ -609,10 +966,25 @@ func TestSpawnExpr(t *testing.T) {
 func TestAwaitAllExpr(t *testing.T) {
 	prog := parse(t, `await_all();`)
 	es := prog.Items[0].(*ExprStmt)
-	_, ok := es.Expression.(*AwaitAllExpr)
+	aa, ok := es.Expression.(*AwaitAllExpr)
 	if !ok {
 		t.Fatalf("expected *AwaitAllExpr, got %T", es.Expression)
 	}
+	if aa.Timeout != nil {
+		t.Errorf("expected no timeout, got %T", aa.Timeout)
+	}
+}
+
+func TestAwaitAllExprTimeout(t *testing.T) {
+	prog := parse(t, `await_all(100 * 2);`)
+	es := prog.Items[0].(*ExprStmt)
+	aa, ok := es.Expression.(*AwaitAllExpr)
+	if !ok {
+		t.Fatalf("expected *AwaitAllExpr, got %T", es.Expression)
+	}
+	if _, ok := aa.Timeout.(*BinaryExpr); !ok {
+		t.Errorf("expected *BinaryExpr timeout, got %T", aa.Timeout)
+	}
 }
 
 func TestResultMorParsed(t *testing.T) {
@@ -692,6 +1064,31 @@ func TestParseMapBoolKeys(t *testing.T) {
 	}
 }
 
+func TestParseMapNegativeAndComputedKeys(t *testing.T) {
+	prog := parse(t, `{ -1: "a", -2.5: "b", (1 + 1): "c", ((k)): "d" };`)
+	m, ok := prog.Items[0].(*ExprStmt).Expression.(*MapLitExpr)
+	if !ok {
+		t.Fatalf("expected *MapLitExpr, got %T", prog.Items[0].(*ExprStmt).Expression)
+	}
+	if len(m.Pairs) != 4 {
+		t.Fatalf("expected 4 pairs, got %d", len(m.Pairs))
+	}
+	if _, ok := m.Pairs[0].Key.(*UnaryExpr); !ok {
+		t.Errorf("expected a unary key, got %T", m.Pairs[0].Key)
+	}
+	if _, ok := m.Pairs[2].Key.(*BinaryExpr); !ok {
+		t.Errorf("expected a binary key, got %T", m.Pairs[2].Key)
+	}
+
+	// Without a colon after the number or the parentheses these stay blocks.
+	for _, src := range []string{`{ -1 };`, `{ -x };`, `{ (1 + 2) * 3 };`, `{ (1 + 2) };`} {
+		prog := parse(t, src)
+		if _, ok := prog.Items[0].(*ExprStmt).Expression.(*BlockExpr); !ok {
+			t.Errorf("%s: expected block, got %T", src, prog.Items[0].(*ExprStmt).Expression)
+		}
+	}
+}
+
 func TestParseFnLitExpr(t *testing.T) {
 	prog := parse(t, `let f = fn(x) { x + 1 };`)
 	if len(prog.Items) != 1 {
@@ -854,3 +1251,107 @@ func TestAlignExprFollowedByCode(t *testing.T) {
 		t.Fatalf("expected *ExprStmt after align, got %T", prog.Items[1])
 	}
 }
+
+func TestSelectExpr(t *testing.T) {
+	prog := parse(t, "select {\n  recv(a) as v => v,\n  await(h) => 1,\n  default => 2,\n}")
+	es := prog.Items[0].(*ExprStmt)
+	se, ok := es.Expression.(*SelectExpr)
+	if !ok {
+		t.Fatalf("expected *SelectExpr, got %T", es.Expression)
+	}
+	if len(se.Arms) != 3 {
+		t.Fatalf("expected 3 arms, got %d", len(se.Arms))
+	}
+	want := []struct{ op, name string }{{"recv", "v"}, {"await", ""}, {"default", ""}}
+	for i, w := range want {
+		arm := se.Arms[i]
+		if arm.Op != w.op || arm.Name != w.name {
+			t.Errorf("arm %d: got op %q name %q, want op %q name %q", i, arm.Op, arm.Name, w.op, w.name)
+		}
+		if (arm.Source == nil) != (w.op == "default") {
+			t.Errorf("arm %d: unexpected source %v", i, arm.Source)
+		}
+	}
+}
+
+func TestSelectExprBadArm(t *testing.T) {
+	if _, errs := parseExpectErrors("select { send(a) => 1 }"); len(errs) == 0 {
+		t.Error("expected parse error for unknown select arm")
+	}
+}
+
+func TestDestructuringParams(t *testing.T) {
+	prog := parse(t, `fn f({ "x": x, "y": y }, [h, ..t], n) { n }`)
+	fd, ok := prog.Items[0].(*FnDecl)
+	if !ok {
+		t.Fatalf("expected *FnDecl, got %T", prog.Items[0])
+	}
+	if len(fd.Params) != 3 {
+		t.Fatalf("expected 3 params, got %d", len(fd.Params))
+	}
+	mp, ok := fd.Params[0].Pattern.(*MapPattern)
+	if !ok {
+		t.Fatalf("param 0: expected *MapPattern, got %T", fd.Params[0].Pattern)
+	}
+	if len(mp.Keys) != 2 || mp.Keys[0] != "x" || mp.Keys[1] != "y" {
+		t.Errorf("map pattern keys = %v", mp.Keys)
+	}
+	ap, ok := fd.Params[1].Pattern.(*ArrayPattern)
+	if !ok {
+		t.Fatalf("param 1: expected *ArrayPattern, got %T", fd.Params[1].Pattern)
+	}
+	if len(ap.Elems) != 1 || !ap.HasRest || ap.Rest != "t" {
+		t.Errorf("array pattern = %d elems, rest %q (%v)", len(ap.Elems), ap.Rest, ap.HasRest)
+	}
+	if fd.Params[2].Name != "n" || fd.Params[2].Pattern != nil {
+		t.Errorf("param 2 = %+v, want plain n", fd.Params[2])
+	}
+}
+
+func TestArrayPatternRestMustBeLast(t *testing.T) {
+	if _, errs := parseExpectErrors(`fn f([..t, h]) { h }`); len(errs) == 0 {
+		t.Error("expected parse error for rest pattern before other elements")
+	}
+}
+
+// TestPrecedenceTable pins the data-driven precedence table to the values the
+// parser used when precedence was a switch statement.
+func TestPrecedenceTable(t *testing.T) {
+	want := map[token.TokenType]int{
+		token.ASSIGN:    precAssign,
+		token.OR:        precOr,
+		token.AND:       precAnd,
+		token.EQ:        precEquality,
+		token.STRICT_EQ: precEquality,
+		token.NEQ:       precEquality,
+		token.LT:        precComparison,
+		token.GT:        precComparison,
+		token.LTE:       precComparison,
+		token.GTE:       precComparison,
+		token.PLUS:      precSum,
+		token.MINUS:     precSum,
+		token.STAR:      precProduct,
+		token.SLASH:     precProduct,
+		token.FLOOR_DIV: precProduct,
+		token.PERCENT:   precProduct,
+		token.LPAREN:    precPostfix,
+		token.LBRACKET:  precPostfix,
+		token.DOT:       precPostfix,
+		token.QUESTION:  precPostfix,
+		token.AS:        precPostfix,
+	}
+	for tt := token.INT; tt <= token.ILLEGAL; tt++ {
+		if got := tokenPrecedence(tt); got != want[tt] {
+			t.Errorf("tokenPrecedence(%s) = %d, want %d", tt, got, want[tt])
+		}
+	}
+}
+
+func TestRegisterPrecedenceTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic when registering a token twice")
+		}
+	}()
+	registerPrecedence(precSum, token.PLUS)
+}
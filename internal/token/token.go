@@ -33,7 +33,10 @@ const (
 	EXTERN
 	SPAWN
 	AWAIT_ALL
+	SELECT
+	LAZY
 	DECREE
+	UNDECREE
 	CHANT
 	SORRY
 	SPEAK
@@ -43,12 +46,14 @@ const (
 	ALIGN
 	SIGIL
 	INVOKE
+	FOR
 
 	// Operators
 	PLUS      // +
 	MINUS     // -
 	STAR      // *
 	SLASH     // /
+	FLOOR_DIV // //
 	PERCENT   // %
 	ASSIGN    // =
 	EQ        // ==
@@ -79,6 +84,7 @@ const (
 	EOF
 	TAB
 	NEWLINE
+	COMMENT // only emitted when the lexer keeps comments
 	ILLEGAL
 )
 
@@ -105,7 +111,10 @@ var tokenNames = map[TokenType]string{
 	EXTERN:    "EXTERN",
 	SPAWN:     "SPAWN",
 	AWAIT_ALL: "AWAIT_ALL",
+	SELECT:    "SELECT",
+	LAZY:      "LAZY",
 	DECREE:    "DECREE",
+	UNDECREE:  "UNDECREE",
 	CHANT:     "CHANT",
 	SORRY:     "SORRY",
 	SPEAK:     "SPEAK",
@@ -115,10 +124,12 @@ var tokenNames = map[TokenType]string{
 	ALIGN:     "ALIGN",
 	SIGIL:     "SIGIL",
 	INVOKE:    "INVOKE",
+	FOR:       "FOR",
 	PLUS:      "PLUS",
 	MINUS:     "MINUS",
 	STAR:      "STAR",
 	SLASH:     "SLASH",
+	FLOOR_DIV: "FLOOR_DIV",
 	PERCENT:   "PERCENT",
 	ASSIGN:    "ASSIGN",
 	EQ:        "EQ",
@@ -145,6 +156,7 @@ var tokenNames = map[TokenType]string{
 	EOF:       "EOF",
 	TAB:       "TAB",
 	NEWLINE:   "NEWLINE",
+	COMMENT:   "COMMENT",
 	ILLEGAL:   "ILLEGAL",
 }
 
@@ -183,7 +195,10 @@ var keywords = map[string]TokenType{
 	"extern":    EXTERN,
 	"spawn":     SPAWN,
 	"await_all": AWAIT_ALL,
+	"select":    SELECT,
+	"lazy":      LAZY,
 	"decree":    DECREE,
+	"undecree":  UNDECREE,
 	"chant":     CHANT,
 	"sorry":     SORRY,
 	"speak":     SPEAK,
@@ -193,6 +208,7 @@ var keywords = map[string]TokenType{
 	"align":     ALIGN,
 	"sigil":     SIGIL,
 	"invoke":    INVOKE,
+	"for":       FOR,
 }
 
 // LookupIdent returns the TokenType for a given identifier string.
@@ -218,24 +234,90 @@ func SemicolonTrigger(t TokenType) bool {
 // StartsStatement returns true if this token type is one of the keywords
 // that can begin a new statement (used for semicolon insertion). spec:SEC-2-4
 var statementStarters = map[TokenType]bool{
-	LET:    true,
-	CONST:  true,
-	FN:     true,
-	MATCH:  true,
-	IF:     true,
-	GUARD:  true,
-	RETURN: true,
-	DECREE: true,
-	SPAWN:  true,
-	SPEAK:  true,
-	DOOM:   true,
-	SORRY:  true,
-	CHANT:  true,
-	ALIGN:  true,
-	SIGIL:  true,
-	INVOKE: true,
+	LET:      true,
+	CONST:    true,
+	FN:       true,
+	MATCH:    true,
+	IF:       true,
+	GUARD:    true,
+	RETURN:   true,
+	DECREE:   true,
+	UNDECREE: true,
+	SPAWN:    true,
+	SELECT:   true,
+	SPEAK:    true,
+	DOOM:     true,
+	SORRY:    true,
+	CHANT:    true,
+	ALIGN:    true,
+	SIGIL:    true,
+	INVOKE:   true,
+	FOR:      true,
 }
 
 func StartsStatement(t TokenType) bool {
 	return statementStarters[t]
 }
+
+// TokenCategory groups token types for syntax highlighting.
+type TokenCategory int
+
+const (
+	CategoryOther TokenCategory = iota
+	CategoryKeyword
+	CategoryOperator
+	CategoryLiteral
+	CategoryIdentifier
+	CategoryPunctuation
+	CategoryComment
+)
+
+var categoryNames = map[TokenCategory]string{
+	CategoryOther:       "other",
+	CategoryKeyword:     "keyword",
+	CategoryOperator:    "operator",
+	CategoryLiteral:     "literal",
+	CategoryIdentifier:  "identifier",
+	CategoryPunctuation: "punctuation",
+	CategoryComment:     "comment",
+}
+
+func (c TokenCategory) String() string {
+	if name, ok := categoryNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("TokenCategory(%d)", int(c))
+}
+
+// keywordTypes holds every token type a keyword lexes to, so a new keyword
+// is categorized as soon as it is added to keywords.
+var keywordTypes = func() map[TokenType]bool {
+	types := make(map[TokenType]bool, len(keywords))
+	for _, t := range keywords {
+		types[t] = true
+	}
+	return types
+}()
+
+// Category classifies a token type for editors and highlighters.
+// true, false and nil are keywords to the lexer but literals to a reader.
+func Category(t TokenType) TokenCategory {
+	switch t {
+	case INT, FLOAT, STRING, TRUE, FALSE, NIL:
+		return CategoryLiteral
+	case IDENT:
+		return CategoryIdentifier
+	case PLUS, MINUS, STAR, SLASH, FLOOR_DIV, PERCENT, ASSIGN, EQ, STRICT_EQ, NEQ,
+		LT, GT, LTE, GTE, BANG, AMP, ARROW, QUESTION:
+		return CategoryOperator
+	case LPAREN, RPAREN, LBRACKET, RBRACKET, LBRACE, RBRACE,
+		COMMA, SEMICOLON, COLON, DOT:
+		return CategoryPunctuation
+	case COMMENT:
+		return CategoryComment
+	}
+	if keywordTypes[t] {
+		return CategoryKeyword
+	}
+	return CategoryOther
+}
@@ -55,6 +55,7 @@ func TestLookupIdentKeywords(t *testing.T) {
 		{"spawn", SPAWN},
 		{"await_all", AWAIT_ALL},
 		{"decree", DECREE},
+		{"undecree", UNDECREE},
 		{"chant", CHANT},
 		{"sorry", SORRY},
 		{"speak", SPEAK},
@@ -63,6 +64,7 @@ func TestLookupIdentKeywords(t *testing.T) {
 		{"as", AS},
 		{"ref", REF},
 		{"extern", EXTERN},
+		{"for", FOR},
 	}
 	for _, tt := range tests {
 		got := LookupIdent(tt.ident)
@@ -138,7 +140,7 @@ func TestAlignToken(t *testing.T) {
 }
 
 func TestStartsStatement(t *testing.T) {
-	starters := []TokenType{LET, CONST, FN, MATCH, IF, GUARD, RETURN, DECREE, SPAWN, SPEAK, DOOM, SORRY, CHANT, SIGIL, INVOKE, ALIGN}
+	starters := []TokenType{LET, CONST, FN, MATCH, IF, GUARD, RETURN, DECREE, SPAWN, SPEAK, DOOM, SORRY, CHANT, SIGIL, INVOKE, ALIGN, FOR}
 	for _, tt := range starters {
 		if !StartsStatement(tt) {
 			t.Errorf("StartsStatement(%v) = false, want true", tt)
@@ -152,3 +154,45 @@ func TestStartsStatement(t *testing.T) {
 		}
 	}
 }
+
+func TestCategory(t *testing.T) {
+	tests := []struct {
+		tt   TokenType
+		want TokenCategory
+	}{
+		{LET, CategoryKeyword},
+		{INVOKE, CategoryKeyword},
+		{FOR, CategoryKeyword},
+		{AWAIT_ALL, CategoryKeyword},
+		{SPEAK, CategoryKeyword},
+		{OK, CategoryKeyword},
+		{PLUS, CategoryOperator},
+		{STRICT_EQ, CategoryOperator},
+		{ARROW, CategoryOperator},
+		{INT, CategoryLiteral},
+		{STRING, CategoryLiteral},
+		{TRUE, CategoryLiteral},
+		{NIL, CategoryLiteral},
+		{IDENT, CategoryIdentifier},
+		{LBRACE, CategoryPunctuation},
+		{SEMICOLON, CategoryPunctuation},
+		{COMMENT, CategoryComment},
+		{EOF, CategoryOther},
+		{ILLEGAL, CategoryOther},
+	}
+	for _, tt := range tests {
+		if got := Category(tt.tt); got != tt.want {
+			t.Errorf("Category(%v) = %v, want %v", tt.tt, got, tt.want)
+		}
+	}
+
+	for word, tt := range keywords {
+		want := CategoryKeyword
+		if tt == TRUE || tt == FALSE || tt == NIL {
+			want = CategoryLiteral
+		}
+		if got := Category(tt); got != want {
+			t.Errorf("Category(%v) for keyword %q = %v, want %v", tt, word, got, want)
+		}
+	}
+}